@@ -14,6 +14,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/units"
 )
 
 /**
@@ -108,9 +110,8 @@ func main() {
 	// 获取 Gas 价格
 	gasPrice, err := client.SuggestGasPrice(ctx)
 	if err == nil {
-		// 将 wei 转换为 gwei（1 gwei = 10^9 wei）
-		gasPriceGwei := float64(gasPrice.Uint64()) / 1_000_000_000
-		fmt.Printf("✓ 建议 Gas 价格: %.2f gwei\n", gasPriceGwei)
+		// 将 wei 转换为 gwei（1 gwei = 10^9 wei），精确换算，不经过 float64
+		fmt.Printf("✓ 建议 Gas 价格: %s gwei\n", units.FormatGwei(gasPrice))
 	}
 
 	//查询 10000000 区块
@@ -2,16 +2,27 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/units"
 )
 
 // 使用示例：
@@ -27,6 +38,12 @@ import (
 //
 //	# 批量查询，自定义请求间隔（毫秒）
 //	go run main.go -range-start 100 -range-end 105 -rate-limit 500
+//
+//	# 查询地址在区块范围内的交易记录（不依赖 Etherscan 等索引服务，逐块扫描）
+//	go run main.go history -address 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb -from 100 -to 200
+//
+//	# 交易记录输出为 CSV
+//	go run main.go history -address 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb -from 100 -to 200 -format csv > history.csv
 
 // 查询最新区块、指定区块以及批量查询区块范围的信息。
 func main() {
@@ -35,6 +52,12 @@ func main() {
 	if rpcURL == "" {
 		log.Fatal("ETH_RPC_URL is not set")
 	}
+
+	// history 子命令：扫描区块范围，列出地址的交易记录
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		runHistory(rpcURL, os.Args[2:])
+		return
+	}
 	//context.WithTimeout 创建一个新的上下文，会在指定时间后自动取消,30 秒超时：防止网络连接问题导致程序无限等待
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel() // defer 确保函数退出时取消上下文，释放资源
@@ -85,7 +108,15 @@ func main() {
 			log.Fatal("range-start must be <= range-end")
 		}
 		rateLimit := time.Duration(*rateLimitFlag) * time.Millisecond
-		fetchBlockRange(ctx, client, *rangeStartFlag, *rangeEndFlag, rateLimit)
+		fmt.Printf("\n=== Fetching Block Range [%d, %d] ===\n", *rangeStartFlag, *rangeEndFlag)
+		fmt.Printf("Rate Limit: %v per request\n\n", rateLimit)
+		stats := fetchBlockRange(ctx, client, *rangeStartFlag, *rangeEndFlag, rateLimit, func(block *types.Block) {
+			printBlockInfo(fmt.Sprintf("Block %d", block.NumberU64()), block)
+		})
+		fmt.Printf("\n=== Summary ===\n")
+		fmt.Printf("Success: %d blocks\n", stats.success)
+		fmt.Printf("Skipped: %d blocks\n", stats.skipped)
+		fmt.Printf("Total: %d blocks\n", *rangeEndFlag-*rangeStartFlag+1)
 	}
 
 }
@@ -114,45 +145,51 @@ func fetchBlockWithRetry(ctx context.Context, client *ethclient.Client, blockNum
 	return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, lastErr)
 }
 
-// fetchBlockRange 批量查询区块范围，带频率控制
-func fetchBlockRange(ctx context.Context, client *ethclient.Client, start, end uint64, rateLimit time.Duration) {
-	fmt.Printf("\n=== Fetching Block Range [%d, %d] ===\n", start, end)
-	fmt.Printf("Rate Limit: %v per request\n\n", rateLimit)
+// rangeStats 区块范围查询的统计结果
+type rangeStats struct {
+	success   int
+	skipped   int
+	cancelled bool // 上下文取消，未查询到 end
+}
 
-	successCount := 0
-	skipCount := 0
-	ticker := time.NewTicker(rateLimit)
-	defer ticker.Stop()
+// fetchBlockRange 批量查询区块范围，带频率控制；每个成功获取的区块交给 handle 处理
+// rateLimit <= 0 时不限速
+func fetchBlockRange(ctx context.Context, client *ethclient.Client, start, end uint64, rateLimit time.Duration, handle func(block *types.Block)) rangeStats {
+	var stats rangeStats
+	var tick <-chan time.Time
+	if rateLimit > 0 {
+		ticker := time.NewTicker(rateLimit)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 
 	for num := start; num <= end; num++ {
 		// 等待速率限制
-		<-ticker.C
+		if tick != nil {
+			<-tick
+		}
 
 		blockNumber := big.NewInt(0).SetUint64(num)
 		block, err := fetchBlockWithRetry(ctx, client, blockNumber, 2)
 
 		if err != nil {
 			log.Printf("[ERROR] Block %d: %v", num, err)
-			skipCount++
-			continue
+			stats.skipped++
+		} else {
+			stats.success++
+			handle(block)
 		}
 
-		successCount++
-		printBlockInfo(fmt.Sprintf("Block %d", num), block)
-
 		// 检查上下文是否已取消
 		select {
 		case <-ctx.Done():
 			log.Printf("[INFO] Context cancelled, stopping at block %d", num)
-			return
+			stats.cancelled = true
+			return stats
 		default:
 		}
 	}
-
-	fmt.Printf("\n=== Summary ===\n")
-	fmt.Printf("Success: %d blocks\n", successCount)
-	fmt.Printf("Skipped: %d blocks\n", skipCount)
-	fmt.Printf("Total: %d blocks\n", end-start+1)
+	return stats
 }
 
 // 打印详细的区块信息
@@ -260,3 +297,353 @@ func printBlockInfo(title string, block *types.Block) {
 	fmt.Println()
 
 }
+
+// erc20TransferTopic Transfer(address,address,uint256) 事件签名哈希
+var erc20TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// logChunkSize 每次 eth_getLogs 查询的区块数，多数节点服务商限制单次查询的区块范围
+const logChunkSize = 2000
+
+// historyRow 交易记录中的一行：一笔普通交易，或者交易中的一条 ERC-20 Transfer 事件
+type historyRow struct {
+	block        uint64
+	time         time.Time
+	txHash       common.Hash
+	txIndex      uint
+	logIndex     int      // ERC-20 Transfer 的日志序号，普通交易为 -1
+	direction    string   // OUT / IN / SELF
+	counterparty string   // 对方地址，创建合约时为 "contract creation"
+	asset        string   // "ETH" 或代币合约地址
+	value        string   // 已按精度格式化
+	fee          *big.Int // 每笔交易只在第一行显示手续费，同一笔交易的其他行为 nil
+	status       *uint64  // 回执状态，回执查询失败时为 nil（显示 unknown）
+}
+
+// historyScanner 扫描区块范围，收集与地址相关的交易和 ERC-20 转账
+type historyScanner struct {
+	client   *ethclient.Client
+	address  common.Address
+	signer   types.Signer
+	rows     []historyRow
+	receipts map[common.Hash]*types.Receipt
+	feeTx    map[common.Hash]bool   // 已经有一行带上手续费的交易
+	decimals map[common.Address]int // 代币精度，-1 表示查询失败
+	blockTs  map[uint64]time.Time
+	logErrs  int // 查询失败的 eth_getLogs 请求数，这些区块范围内的 ERC-20 转账缺失
+}
+
+// runHistory history 子命令：列出地址在 [from, to] 区块范围内作为发送方或接收方的交易，以及 ERC-20 转账
+// 普通交易通过逐块扫描获得（fetchBlockRange），ERC-20 转账通过按 topic 过滤的 eth_getLogs 获得；
+// 合约内部产生的 ETH 转账（internal transaction）不在交易和日志中，需要 trace 接口，这里不包含
+func runHistory(rpcURL string, args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	addrFlag := fs.String("address", "", "address to list transactions for")
+	fromFlag := fs.Uint64("from", 0, "start block number")
+	toFlag := fs.Uint64("to", 0, "end block number (0 means latest)")
+	formatFlag := fs.String("format", "table", "output format: table or csv")
+	rateLimitFlag := fs.Int("rate-limit", 50, "rate limit in milliseconds between block requests")
+	fs.Parse(args)
+
+	if !common.IsHexAddress(*addrFlag) {
+		log.Fatalf("missing or invalid -address: %q", *addrFlag)
+	}
+	if *formatFlag != "table" && *formatFlag != "csv" {
+		log.Fatalf("unknown format: %s (use: table or csv)", *formatFlag)
+	}
+
+	// 扫描大范围区块耗时较长，不设置总超时，Ctrl+C 时输出已收集到的记录
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("failed to connect to Ethereum node: %v", err)
+	}
+	defer client.Close()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("failed to get chain id: %v", err)
+	}
+	end := *toFlag
+	if end == 0 {
+		end, err = client.BlockNumber(ctx)
+		if err != nil {
+			log.Fatalf("failed to get latest block number: %v", err)
+		}
+	}
+	if *fromFlag > end {
+		log.Fatal("-from must be <= -to")
+	}
+
+	h := &historyScanner{
+		client:   client,
+		address:  common.HexToAddress(*addrFlag),
+		signer:   types.LatestSignerForChainID(chainID),
+		receipts: make(map[common.Hash]*types.Receipt),
+		feeTx:    make(map[common.Hash]bool),
+		decimals: make(map[common.Address]int),
+		blockTs:  make(map[uint64]time.Time),
+	}
+
+	// 进度和警告输出到 stderr，stdout 只输出结果，便于重定向为 CSV 文件
+	log.Printf("[INFO] scanning blocks [%d, %d] for %s", *fromFlag, end, h.address.Hex())
+	rateLimit := time.Duration(*rateLimitFlag) * time.Millisecond
+	stats := fetchBlockRange(ctx, client, *fromFlag, end, rateLimit, func(block *types.Block) {
+		h.scanBlock(ctx, block)
+	})
+	if !stats.cancelled {
+		h.scanTokenTransfers(ctx, *fromFlag, end)
+	}
+	if stats.skipped > 0 || stats.cancelled || h.logErrs > 0 {
+		log.Printf("[WARN] history is incomplete: %d block(s) skipped, %d Transfer log request(s) failed, cancelled: %v",
+			stats.skipped, h.logErrs, stats.cancelled)
+	}
+
+	// 按区块、交易、日志顺序输出；同一笔交易的普通记录排在它的 Transfer 事件之前
+	sort.Slice(h.rows, func(i, j int) bool {
+		a, b := h.rows[i], h.rows[j]
+		if a.block != b.block {
+			return a.block < b.block
+		}
+		if a.txIndex != b.txIndex {
+			return a.txIndex < b.txIndex
+		}
+		return a.logIndex < b.logIndex
+	})
+
+	if *formatFlag == "csv" {
+		err = h.writeCSV(os.Stdout)
+	} else {
+		err = h.writeTable(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("failed to write history: %v", err)
+	}
+}
+
+// scanBlock 收集区块中以该地址为发送方或接收方的交易
+func (h *historyScanner) scanBlock(ctx context.Context, block *types.Block) {
+	h.blockTs[block.NumberU64()] = time.Unix(int64(block.Time()), 0)
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(h.signer, tx)
+		if err != nil {
+			// L2 的系统交易等无法恢复发送方的交易类型
+			log.Printf("[WARN] block %d tx %s: cannot recover sender: %v", block.NumberU64(), tx.Hash().Hex(), err)
+			continue
+		}
+		to := tx.To()
+		isFrom := from == h.address
+		isTo := to != nil && *to == h.address
+		if !isFrom && !isTo {
+			continue
+		}
+
+		row := historyRow{
+			block:    block.NumberU64(),
+			time:     h.blockTs[block.NumberU64()],
+			txHash:   tx.Hash(),
+			txIndex:  uint(i),
+			logIndex: -1,
+			asset:    "ETH",
+			value:    units.FormatEther(tx.Value()),
+		}
+		switch {
+		case isFrom && isTo:
+			row.direction, row.counterparty = "SELF", h.address.Hex()
+		case isFrom && to == nil:
+			row.direction, row.counterparty = "OUT", "contract creation"
+		case isFrom:
+			row.direction, row.counterparty = "OUT", to.Hex()
+		default:
+			row.direction, row.counterparty = "IN", from.Hex()
+		}
+		if receipt := h.receipt(ctx, tx.Hash()); receipt != nil {
+			row.fee, row.status = h.txFee(tx.Hash(), receipt), &receipt.Status
+		}
+		h.rows = append(h.rows, row)
+	}
+}
+
+// scanTokenTransfers 通过 eth_getLogs 查询该地址转出和转入的 ERC-20 Transfer 事件
+// topic 过滤无法表达 "from 或 to"，分别按 topics[1]（from）和 topics[2]（to）查询两次
+func (h *historyScanner) scanTokenTransfers(ctx context.Context, start, end uint64) {
+	addrTopic := common.BytesToHash(h.address.Bytes())
+	queries := [][][]common.Hash{
+		{{erc20TransferTopic}, {addrTopic}},
+		{{erc20TransferTopic}, nil, {addrTopic}},
+	}
+	// 自己转给自己的事件两次查询都会返回，按 (交易, 日志序号) 去重
+	seen := make(map[string]bool)
+
+	for chunkStart := start; chunkStart <= end; chunkStart += logChunkSize {
+		chunkEnd := min(chunkStart+logChunkSize-1, end)
+		for _, topics := range queries {
+			logs, err := h.client.FilterLogs(ctx, ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(chunkStart),
+				ToBlock:   new(big.Int).SetUint64(chunkEnd),
+				Topics:    topics,
+			})
+			if err != nil {
+				log.Printf("[ERROR] failed to get Transfer logs for blocks [%d, %d]: %v", chunkStart, chunkEnd, err)
+				h.logErrs++
+				continue
+			}
+			for _, lg := range logs {
+				key := fmt.Sprintf("%s-%d", lg.TxHash.Hex(), lg.Index)
+				// ERC-721 的 Transfer 签名相同，但 tokenId 也是 indexed（4 个 topic），这里只处理 ERC-20
+				if len(lg.Topics) != 3 || seen[key] {
+					continue
+				}
+				seen[key] = true
+				h.addTokenTransfer(ctx, lg)
+			}
+		}
+	}
+}
+
+// addTokenTransfer 把一条 ERC-20 Transfer 事件加入交易记录
+func (h *historyScanner) addTokenTransfer(ctx context.Context, lg types.Log) {
+	from := common.BytesToAddress(lg.Topics[1].Bytes())
+	to := common.BytesToAddress(lg.Topics[2].Bytes())
+	amount := new(big.Int).SetBytes(lg.Data)
+	success := types.ReceiptStatusSuccessful
+
+	row := historyRow{
+		block:    lg.BlockNumber,
+		time:     h.blockTs[lg.BlockNumber],
+		txHash:   lg.TxHash,
+		txIndex:  lg.TxIndex,
+		logIndex: int(lg.Index),
+		asset:    lg.Address.Hex(),
+		value:    amount.String(),
+		// 能查到日志说明交易执行成功（回滚的交易不会留下日志）
+		status: &success,
+	}
+	switch {
+	case from == h.address && to == h.address:
+		row.direction, row.counterparty = "SELF", h.address.Hex()
+	case from == h.address:
+		row.direction, row.counterparty = "OUT", to.Hex()
+	default:
+		row.direction, row.counterparty = "IN", from.Hex()
+	}
+	if decimals := h.tokenDecimals(ctx, lg.Address); decimals >= 0 {
+		row.value = units.Format(amount, decimals)
+	}
+	// 同一笔交易的普通记录（先扫描）或前一条 Transfer 已经带上手续费时不再重复显示，避免按列求和时重复计算
+	if receipt := h.receipt(ctx, lg.TxHash); receipt != nil {
+		row.fee = h.txFee(lg.TxHash, receipt)
+	}
+	h.rows = append(h.rows, row)
+}
+
+// receipt 查询并缓存交易回执，同一笔交易既有普通记录又有 Transfer 事件时只查询一次
+func (h *historyScanner) receipt(ctx context.Context, txHash common.Hash) *types.Receipt {
+	if r, ok := h.receipts[txHash]; ok {
+		return r
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	r, err := h.client.TransactionReceipt(reqCtx, txHash)
+	if err != nil {
+		log.Printf("[WARN] failed to get receipt for %s: %v", txHash.Hex(), err)
+		r = nil
+	}
+	h.receipts[txHash] = r
+	return r
+}
+
+// txFee 返回交易的手续费，同一笔交易只在第一次调用时返回，之后返回 nil
+func (h *historyScanner) txFee(txHash common.Hash, receipt *types.Receipt) *big.Int {
+	if h.feeTx[txHash] {
+		return nil
+	}
+	fee := receiptFee(receipt)
+	if fee != nil {
+		h.feeTx[txHash] = true
+	}
+	return fee
+}
+
+// tokenDecimals 查询并缓存代币精度，不支持 decimals() 的合约返回 -1（显示原始数量）
+func (h *historyScanner) tokenDecimals(ctx context.Context, token common.Address) int {
+	if d, ok := h.decimals[token]; ok {
+		return d
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	// decimals() 的函数选择器
+	out, err := h.client.CallContract(reqCtx, ethereum.CallMsg{To: &token, Data: crypto.Keccak256([]byte("decimals()"))[:4]}, nil)
+	d := -1
+	if err == nil && len(out) == 32 {
+		if v := new(big.Int).SetBytes(out); v.IsUint64() && v.Uint64() <= 255 {
+			d = int(v.Uint64())
+		}
+	}
+	h.decimals[token] = d
+	return d
+}
+
+// receiptFee 计算交易手续费：gasUsed * effectiveGasPrice，加上 blob 交易的 blob 费用
+// 手续费由交易发送方支付；接收记录中显示的是对方为这笔交易支付的手续费
+func receiptFee(receipt *types.Receipt) *big.Int {
+	if receipt.EffectiveGasPrice == nil {
+		return nil
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+	if receipt.BlobGasPrice != nil {
+		fee.Add(fee, new(big.Int).Mul(new(big.Int).SetUint64(receipt.BlobGasUsed), receipt.BlobGasPrice))
+	}
+	return fee
+}
+
+// historyHeader 表格和 CSV 的列名
+var historyHeader = []string{"block", "time", "tx_hash", "direction", "counterparty", "asset", "value", "fee_eth", "status"}
+
+// fields 将一行记录转换为列值
+func (r historyRow) fields() []string {
+	fee := "-"
+	if r.fee != nil {
+		fee = units.FormatEther(r.fee)
+	}
+	// 回执查询失败时状态未知，不能当作失败
+	status := "unknown"
+	if r.status != nil {
+		status = "success"
+		if *r.status == types.ReceiptStatusFailed {
+			status = "failed"
+		}
+	}
+	ts := ""
+	if !r.time.IsZero() {
+		ts = r.time.UTC().Format(time.RFC3339)
+	}
+	return []string{strconv.FormatUint(r.block, 10), ts, r.txHash.Hex(), r.direction, r.counterparty, r.asset, r.value, fee, status}
+}
+
+// writeTable 以对齐的表格输出交易记录
+func (h *historyScanner) writeTable(w io.Writer) error {
+	fmt.Fprintf(w, "History of %s (%d records)\n\n", h.address.Hex(), len(h.rows))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(historyHeader, "\t")))
+	for _, r := range h.rows {
+		fmt.Fprintln(tw, strings.Join(r.fields(), "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeCSV 以 CSV 输出交易记录
+func (h *historyScanner) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(historyHeader); err != nil {
+		return err
+	}
+	for _, r := range h.rows {
+		if err := cw.Write(r.fields()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
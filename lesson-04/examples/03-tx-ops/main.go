@@ -2,62 +2,145 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/abifile"
+	"ethutil/policy"
+	"ethutil/prompt"
+	"ethutil/revert"
+	"ethutil/selectors"
+	"ethutil/signer"
+	"ethutil/tracer"
+	"ethutil/txsim"
+	"ethutil/txwait"
+	"ethutil/units"
 )
 
-// 支持两种操作模式：
-// 1. 查询交易：--tx <hash> - 按哈希查询交易与回执，解析关键字段
-// 2. 发送交易：--send --to <address> --amount <eth> - 发起 ETH 转账交易
+// 支持四种操作模式：
+//  1. 查询交易：--tx <hash> - 按哈希查询交易与回执，解析关键字段
+//     失败交易（Status 0）会在父区块上重放，还原回滚原因；--abi 指定合约 ABI 文件后可解码自定义错误
+//  2. 发送交易：--send --to <address> --amount <eth> - 发起 ETH 转账交易
+//     --amount 默认单位为 ETH，支持 1.5ether、30gwei、100wei 等后缀，按十进制精确换算，小数位超出单位精度时报错
+//     签名前会先在 pending 状态上预执行（dry run），展示回滚原因与余额变化预览，用户确认后才广播
+//     加上 --yes 可跳过确认提示（脚本场景）
+//     签名方式：--keystore <file> [--password-file <file>]、--mnemonic-file <file> [--hd-path ...]、--clef <url>，
+//     都未指定时兼容读取 SENDER_PRIVATE_KEY 环境变量
+//     --policy <file>（或 TX_POLICY_FILE）指定交易策略：链 ID 白名单、金额/手续费上限、收款地址名单等，
+//     违反策略时输出 JSON 格式的拒绝原因，交易不会被签名
+//     广播后等待确认：--wait-for <N|safe|finalized>（默认 1 个确认），--wait-timeout 最长等待时间，
+//     等待期间检测链重组；ETH_RPC_URL 为 ws:// 时通过新区块订阅代替轮询
+//  3. 调用树追踪：--trace --tx <hash> - 通过 debug_traceTransaction(callTracer) 展示交易内部的嵌套调用
+//     需要节点开放 debug 命名空间；--abi 用于解码函数名，--json <file> 导出原始调用树（- 表示输出到终端）
+//  4. 解码调用数据：--tx 查询时自动解码 input；--decode 0x... 离线解码任意 calldata
+//     内置常用合约（ERC-20/721/1155、Uniswap、Multicall3、Safe）的函数签名，--abi 中的函数会加入选择器数据库；
+//     选择器碰撞时列出其它候选签名，multicall(bytes[])、aggregate3、execTransaction、multiSend 中的嵌套调用递归解码
+//
 // go run main.go --send --to 0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC --amount 3
+// go run main.go --send --to 0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC --amount 1500gwei
+// go run main.go --trace --tx 0x... --abi build/Counter_sol_Counter.abi --json trace.json
+// go run main.go --decode 0xa9059cbb00000000000000000000000028c6c06298d514db089934071355e5743bf21d600000000000000000000000000000000000000000000000000000000000000005
 // 使用本地私链的话，默认当前账户是第一个
 func main() {
 	//获取启动命令行中参数名称: `-tx` 的值
 	txHashHex := flag.String("tx", "", "transaction hash (for query mode)")
 	//判断启动命令行中参数名称 --send，有返回true，否则返回false
 	sendMode := flag.Bool("send", false, "enable send transaction mode")
+	traceMode := flag.Bool("trace", false, "trace the internal call tree of --tx via debug_traceTransaction")
+	jsonOut := flag.String("json", "", "export the call tree as JSON to this file (- for stdout, trace mode only)")
 	toAddrHex := flag.String("to", "", "recipient address (required for send mode)")
-	amountEth := flag.Float64("amount", 0, "amount in ETH (required for send mode)")
+	amount := flag.String("amount", "", "amount to send, in ETH by default; accepts unit suffixes like 1.5ether, 30gwei, 100wei (required for send mode)")
+	assumeYes := flag.Bool("yes", false, "skip the confirmation prompt after pre-flight simulation")
+	abiFiles := flag.String("abi", "", "comma separated ABI files used to decode calldata and custom revert errors")
+	decodeHex := flag.String("decode", "", "decode raw calldata (0x hex) offline with the selector database")
+	// 签名器参数：--clef / --keystore / --mnemonic-file 等，未指定时兼容 SENDER_PRIVATE_KEY
+	signerCfg := signer.RegisterFlags(flag.CommandLine, "SENDER_PRIVATE_KEY")
+	policyFile := flag.String("policy", "", "transaction policy file (defaults to $TX_POLICY_FILE)")
+	waitFor := flag.String("wait-for", "1", "wait until the transaction has N confirmations, or is covered by the safe / finalized block")
+	waitTimeout := flag.Duration("wait-timeout", 5*time.Minute, "maximum time to wait for the transaction (send mode)")
 	flag.Parse()
 
+	// 加载 ABI 中定义的自定义错误，用于解码回滚原因
+	abis, err := abifile.LoadList(*abiFiles)
+	if err != nil {
+		log.Fatalf("failed to load ABI: %v", err)
+	}
+	decoder := revert.NewDecoder(abis...)
+	// 函数选择器数据库：内置常用签名 + --abi 中的函数（ABI 中的定义优先）
+	selectorDB := selectors.Bundled()
+	for _, a := range abis {
+		selectorDB.AddABI(a)
+	}
+
 	// 判断操作模式
-	if *sendMode {
+	if *decodeHex != "" {
+		// 离线解码 calldata，不需要连接节点
+		data, err := hexutil.Decode(strings.TrimSpace(*decodeHex))
+		if err != nil {
+			log.Fatalf("invalid --decode hex: %v", err)
+		}
+		printCalldata(data, selectorDB)
+	} else if *traceMode {
+		// 调用树追踪模式
+		if *txHashHex == "" {
+			log.Fatal("trace mode requires --tx flag")
+		}
+		traceTransaction(*txHashHex, selectorDB, decoder, *jsonOut)
+	} else if *sendMode {
 		// 发送交易模式
-		if *toAddrHex == "" || *amountEth <= 0 {
+		if *toAddrHex == "" || *amount == "" {
 			log.Fatal("send mode requires --to and --amount flags")
 		}
-		sendTransaction(*toAddrHex, *amountEth)
+		// 金额按十进制字符串精确换算为 wei，不经过 float64
+		valueWei, err := units.ParseEther(*amount)
+		if err != nil {
+			log.Fatalf("invalid --amount: %v", err)
+		}
+		if valueWei.Sign() <= 0 {
+			log.Fatal("--amount must be greater than 0")
+		}
+		pol, err := policy.Load(*policyFile)
+		if err != nil {
+			log.Fatalf("failed to load policy: %v", err)
+		}
+		target, err := txwait.ParseTarget(*waitFor)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sendTransaction(signerCfg, pol, *toAddrHex, valueWei, *assumeYes, decoder, target, *waitTimeout)
 	} else {
 		// 查询交易模式
 		if *txHashHex == "" {
 			log.Fatal("query mode requires --tx flag, or use --send for send mode")
 		}
-		queryTransaction(*txHashHex)
+		queryTransaction(*txHashHex, decoder, selectorDB)
 	}
 }
 
 // 发送交易
-func sendTransaction(toAddrHex string, amountEth float64) {
+func sendTransaction(signerCfg *signer.Config, pol *policy.Policy, toAddrHex string, valueWei *big.Int, assumeYes bool, decoder *revert.Decoder, target txwait.Target, waitTimeout time.Duration) {
 	//获取地址
 	rpcURL := os.Getenv("ETH_RPC_URL")
 	if rpcURL == "" {
 		log.Fatal("ETH_RPC_URL is not set")
 	}
-	//获取私钥
-	privKeyHex := os.Getenv("SENDER_PRIVATE_KEY")
-	fmt.Printf("私钥原始值: %s\n", privKeyHex)
-	if privKeyHex == "" {
-		log.Fatal("SENDER_PRIVATE_KEY is not set (required for send mode)")
+	// 打开签名器（clef / keystore / 助记词 / SENDER_PRIVATE_KEY），私钥材料不会被打印
+	// 放在创建超时上下文之前：keystore 可能需要交互输入密码
+	txSigner, err := signerCfg.Open()
+	if err != nil {
+		log.Fatalf("failed to open signer: %v", err)
 	}
 	//context.WithTimeout 创建一个新的上下文，会在指定时间后自动取消,30 秒超时：防止网络连接问题导致程序无限等待
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -69,21 +152,9 @@ func sendTransaction(toAddrHex string, amountEth float64) {
 	}
 	defer client.Close() // defer 确保函数退出时关闭连接
 
-	// 解析私钥， trim0x 移除十六进制字符串前缀 "0x"
-	privKey, err := crypto.HexToECDSA(trim0x(privKeyHex))
-	if err != nil {
-		log.Fatalf("invalid private key: %v", err)
-	}
-
-	// 获取发送方地址
-	publicKey := privKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("error casting public key to ECDSA")
-	}
 	//获取转账账户
-	fromAddr := crypto.PubkeyToAddress(*publicKeyECDSA)
-	fmt.Printf("获取转账账户fromAddr: %s\n", fromAddr)
+	fromAddr := txSigner.Address()
+	fmt.Printf("获取转账账户fromAddr: %s (signer: %s)\n", fromAddr, txSigner.Kind())
 
 	//获取转账接收账户
 	toAddr := common.HexToAddress(toAddrHex)
@@ -132,14 +203,6 @@ func sendTransaction(toAddrHex string, amountEth float64) {
 	// 估算 Gas Limit（普通转账固定为 21000）
 	gasLimit := uint64(21000)
 
-	// 转换 ETH 金额为 Wei
-	// amountEth * 1e18
-	amountWei := new(big.Float).Mul(
-		big.NewFloat(amountEth),
-		big.NewFloat(1e18),
-	)
-	valueWei, _ := amountWei.Int(nil)
-
 	// 检查余额是否足够
 	balance, err := client.BalanceAt(ctx, fromAddr, nil)
 	fmt.Printf("转账账户余额balance: %s\n", balance)
@@ -157,6 +220,50 @@ func sendTransaction(toAddrHex string, amountEth float64) {
 		log.Fatalf("insufficient balance: have %s wei, need %s wei", balance.String(), totalCost.String())
 	}
 
+	// 交易策略检查：在预执行和签名之前拦截不符合策略的交易
+	policyTx := &policy.Tx{
+		Kind:      policy.KindETHTransfer,
+		ChainID:   chainID,
+		From:      fromAddr,
+		To:        &toAddr,
+		Value:     valueWei,
+		GasLimit:  gasLimit,
+		GasFeeCap: gasFeeCap,
+		GasTipCap: gasTipCap,
+	}
+	decision, err := pol.Check(policyTx)
+	if err != nil {
+		policy.PrintRejection(os.Stdout, err)
+		log.Fatalf("transaction rejected by policy: %v", err)
+	}
+
+	// 预执行（dry run）：在 pending 状态上模拟这笔转账
+	// 接收方可能是合约（receive/fallback 中可能 revert），提前发现可以避免白白浪费 Gas
+	sim, err := txsim.Simulate(ctx, client, ethereum.CallMsg{
+		From:  fromAddr,
+		To:    &toAddr,
+		Value: valueWei,
+	}, decoder)
+	if err != nil {
+		log.Fatalf("failed to simulate transaction: %v", err)
+	}
+	sim.Print(os.Stdout)
+	if sim.Revert != nil {
+		log.Fatalf("simulation reverted, transaction not sent: %s", sim.Revert)
+	}
+	// 策略要求大额确认时，--yes 不能跳过确认
+	ok, err := decision.Confirm(assumeYes, "Sign and broadcast this transaction?", prompt.Confirm)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if !ok {
+		fmt.Println("Aborted, transaction not sent.")
+		return
+	}
+	// 等待用户确认可能超过上面的超时时间，签名和广播使用新的上下文
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer sendCancel()
+
 	// 构造交易（EIP-1559 动态费用交易）
 	txData := &types.DynamicFeeTx{
 		ChainID:   chainID,   //链 ID
@@ -171,33 +278,59 @@ func sendTransaction(toAddrHex string, amountEth float64) {
 	tx := types.NewTx(txData)
 
 	// 签名交易
-	signer := types.NewLondonSigner(chainID)
-	signedTx, err := types.SignTx(tx, signer, privKey)
+	signedTx, err := txSigner.SignTx(tx, chainID)
 	if err != nil {
 		log.Fatalf("failed to sign transaction: %v", err)
 	}
 
 	// 发送交易
-	if err := client.SendTransaction(ctx, signedTx); err != nil {
+	if err := client.SendTransaction(sendCtx, signedTx); err != nil {
 		log.Fatalf("failed to send transaction: %v", err)
 	}
+	// 记入每日额度账本（交易已广播，记账失败只提示不退出）
+	if err := pol.Record(policyTx, signedTx.Hash()); err != nil {
+		fmt.Printf("Warning: failed to record spend in policy ledger: %v\n", err)
+	}
 
 	// 输出交易信息
 	fmt.Println("=== Transaction Sent ===")
 	fmt.Printf("From       : %s\n", fromAddr.Hex())
 	fmt.Printf("To         : %s\n", toAddr.Hex())
-	fmt.Printf("Value      : %s ETH (%s Wei)\n", fmt.Sprintf("%.6f", amountEth), valueWei.String())
+	fmt.Printf("Value      : %s ETH (%s Wei)\n", units.FormatEther(valueWei), valueWei.String())
 	fmt.Printf("Gas Limit  : %d\n", gasLimit)
 	fmt.Printf("Gas Tip Cap: %s Wei\n", gasTipCap.String())
 	fmt.Printf("Gas Fee Cap: %s Wei\n", gasFeeCap.String())
 	fmt.Printf("Nonce      : %d\n", nonce)
 	fmt.Printf("Tx Hash    : %s\n", signedTx.Hash().Hex())
-	fmt.Println("\nTransaction is pending. Use --tx flag to query status:")
-	fmt.Printf("  go run main.go --tx %s\n", signedTx.Hash().Hex())
+
+	// 等待确认（达到目标确认数或 safe / finalized），期间检测链重组
+	fmt.Println()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer waitCancel()
+	result, err := txwait.Wait(waitCtx, client, signedTx.Hash(), txwait.Options{Target: target, Out: os.Stdout})
+	if err != nil {
+		fmt.Printf("Stopped waiting for confirmation: %v\n", err)
+		fmt.Println("Use --tx flag to query status later:")
+		fmt.Printf("  go run main.go --tx %s\n", signedTx.Hash().Hex())
+		return
+	}
+
+	fmt.Println("=== Receipt ===")
+	printReceiptInfo(result.Receipt)
+	fmt.Printf("Gas Limit   : %d\n", gasLimit)
+	fmt.Printf("Confirmations: %d (target: %s)\n", result.Confirmations, target)
+	if result.Reorgs > 0 {
+		fmt.Printf("Reorgs      : %d\n", result.Reorgs)
+	}
+	if result.Receipt.Status == types.ReceiptStatusFailed {
+		replayCtx, replayCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer replayCancel()
+		printRevertReason(replayCtx, client, decoder, signedTx, result.Receipt)
+	}
 }
 
 // 查询交易
-func queryTransaction(txHashHex string) {
+func queryTransaction(txHashHex string, decoder *revert.Decoder, selectorDB *selectors.DB) {
 	rpcURL := os.Getenv("ETH_RPC_URL")
 	if rpcURL == "" {
 		log.Fatal("ETH_RPC_URL is not set")
@@ -230,6 +363,8 @@ func queryTransaction(txHashHex string) {
 	fmt.Println("=== Transaction ===")
 	// 输出交易基本信息
 	printTxBasicInfo(tx, isPending)
+	// 解码调用数据：前 4 字节匹配函数选择器，参数按签名解码，嵌套的调用数据递归解码
+	printCalldata(tx.Data(), selectorDB)
 
 	// 获取交易的执行结果（成功了吗，花了多少gas）
 	//收据只有在交易已被打包进区块后才会生成
@@ -244,6 +379,88 @@ func queryTransaction(txHashHex string) {
 	fmt.Println("=== Receipt ===")
 	//交易的执行结果
 	printReceiptInfo(receipt)
+
+	// 失败交易：回执中不包含回滚原因，需要在父区块上重放交易来还原
+	if receipt.Status == types.ReceiptStatusFailed {
+		printRevertReason(ctx, client, decoder, tx, receipt)
+	}
+}
+
+// printRevertReason 在父区块状态上重放失败交易，输出解码后的回滚原因
+func printRevertReason(ctx context.Context, client *ethclient.Client, decoder *revert.Decoder, tx *types.Transaction, r *types.Receipt) {
+	reason, err := decoder.Replay(ctx, client, tx, r.BlockNumber)
+	if err != nil {
+		fmt.Printf("Revert Reason : unavailable (%v)\n", err)
+		return
+	}
+	fmt.Printf("Revert Kind   : %s\n", reason.Kind)
+	fmt.Printf("Revert Reason : %s\n", reason)
+	if len(reason.Data) > 0 {
+		fmt.Printf("Revert Data   : 0x%x\n", reason.Data)
+	}
+}
+
+// 追踪交易的内部调用树
+func traceTransaction(txHashHex string, selectorDB *selectors.DB, decoder *revert.Decoder, jsonOut string) {
+	rpcURL := os.Getenv("ETH_RPC_URL")
+	if rpcURL == "" {
+		log.Fatal("ETH_RPC_URL is not set")
+	}
+	// 重新执行复杂交易可能较慢，超时时间比普通查询长
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("failed to connect to Ethereum node: %v", err)
+	}
+	defer client.Close()
+
+	txHash := common.HexToHash(txHashHex)
+	// callTracer 会在交易所在区块的状态上重新执行交易，记录每一次 CALL / DELEGATECALL / CREATE 等
+	frame, err := tracer.TraceTransaction(ctx, client.Client(), txHash, false)
+	if err != nil {
+		if tracer.IsUnsupported(err) {
+			log.Fatalf("node does not support debug_traceTransaction: %v\n"+
+				"the debug namespace must be enabled (e.g. geth --http.api eth,net,web3,debug, anvil or hardhat node)", err)
+		}
+		log.Fatalf("failed to trace transaction: %v", err)
+	}
+
+	// 导出 JSON
+	if jsonOut != "" {
+		out, err := json.MarshalIndent(frame, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to encode call tree: %v", err)
+		}
+		if jsonOut == "-" {
+			fmt.Println(string(out))
+			return
+		}
+		if err := os.WriteFile(jsonOut, out, 0o644); err != nil {
+			log.Fatalf("failed to write %s: %v", jsonOut, err)
+		}
+		fmt.Printf("Call tree exported to %s\n", jsonOut)
+	}
+
+	calls, depth, failed := frame.Stats()
+	fmt.Println("=== Call Tree ===")
+	fmt.Printf("Tx Hash     : %s\n", txHash.Hex())
+	fmt.Printf("Calls       : %d\n", calls)
+	fmt.Printf("Max Depth   : %d\n", depth)
+	fmt.Printf("Failed Calls: %d\n", failed)
+	fmt.Println()
+	tracer.Render(os.Stdout, frame, tracer.RenderOptions{
+		Method: func(input []byte) string {
+			// 用选择器数据库（内置签名 + --abi 加载的 ABI）匹配 4 字节函数选择器，碰撞时取第一个
+			if methods := selectorDB.Lookup(input); len(methods) > 0 {
+				return methods[0].Sig
+			}
+			return ""
+		},
+		Revert: func(output []byte) string {
+			return decoder.Decode(output).String()
+		},
+	})
 }
 
 // 输出交易基本信息
@@ -266,6 +483,16 @@ func printTxBasicInfo(tx *types.Transaction, isPending bool) {
 	fmt.Printf("Pending     : %v\n", isPending)
 }
 
+// printCalldata 输出调用数据的解码结果；普通转账（没有调用数据）不输出
+func printCalldata(data []byte, selectorDB *selectors.DB) {
+	call := selectorDB.Decode(data)
+	if call == nil {
+		return
+	}
+	fmt.Println("=== Calldata ===")
+	call.Print(os.Stdout, "")
+}
+
 // 交易的执行结果
 func printReceiptInfo(r *types.Receipt) {
 	// 交易状态码：收据中最重要的字段。1 表示执行成功；0 表示执行失败（例如，燃气不足、合约逻辑报错）。失败交易也会被记录并消耗燃气。
@@ -285,11 +512,3 @@ func printReceiptInfo(r *types.Receipt) {
 		fmt.Printf("First Log Address : %s\n", r.Logs[0].Address.Hex())
 	}
 }
-
-// trim0x 移除十六进制字符串前缀 "0x"
-func trim0x(s string) string {
-	if len(s) >= 2 && s[:2] == "0x" {
-		return s[2:]
-	}
-	return s
-}
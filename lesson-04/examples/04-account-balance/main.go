@@ -5,13 +5,14 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"math/big"
 	"os"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/units"
 )
 
 // 04-account-balance.go
@@ -64,12 +65,10 @@ func main() {
 	fmt.Printf("Balance Wei : %s\n", balanceWei.String())
 
 	balanceEth := weiToEth(balanceWei)
-	fmt.Printf("Balance ETH : %s\n", balanceEth.Text('f', 6))
+	fmt.Printf("Balance ETH : %s\n", balanceEth)
 }
 
-// 将wei转ETH
-func weiToEth(wei *big.Int) *big.Float {
-	fWei := new(big.Float).SetInt(wei)
-	ethValue := new(big.Float).Quo(fWei, big.NewFloat(math.Pow10(18)))
-	return ethValue
+// 将wei转ETH（精确的十进制换算，不会因为浮点数丢失精度）
+func weiToEth(wei *big.Int) string {
+	return units.FormatEther(wei)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -9,19 +10,48 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/mempool"
+	"ethutil/subscribe"
+	"ethutil/units"
 )
 
 // 01-subscribe-blocks.go
 // 通过 SubscribeNewHead 订阅新区块头。
-// 注意：大多数节点要求使用 WebSocket RPC，例如：ws://127.0.0.1:8546 或 wss://...
+// 优先使用 WebSocket RPC，例如：ws://127.0.0.1:8546 或 wss://...；
+// 只配置了 HTTP 时自动退回轮询（eth_newBlockFilter + eth_getFilterChanges，过滤器过期后按区块号轮询），-poll 指定轮询间隔
+//
+// -pending 改为订阅交易池中的待打包交易（newPendingTransactions），用于在充值交易被打包之前发现它：
+//
+//	# 监听转入某地址、金额不小于 0.1 ETH 的待打包交易
+//	go run main.go -pending -to 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb -min-value 0.1
+//
+//	# 监听对某代币合约的 transfer(address,uint256) 调用，请求完整交易体
+//	go run main.go -pending -full -to 0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48 -selector 0xa9059cbb
+//
+// -full 请求完整交易体；节点不支持时自动退回只订阅交易哈希，再用 -workers 个并发逐笔查询交易详情。
+// 公共节点服务商通常不开放交易池订阅，或只推送部分交易；待打包交易也可能被替换或丢弃，不能当作到账依据。
+// -pending 只能使用 WebSocket 连接，HTTP 连接没有等价的轮询方式
+//
 //通过 WebSocket 连接到以太坊节点，实时接收新区块头信息并打印
 func main() {
+	pending := flag.Bool("pending", false, "subscribe to pending transactions instead of new heads")
+	full := flag.Bool("full", false, "request full transaction bodies (falls back to hashes if unsupported)")
+	fromFlag := flag.String("from", "", "comma separated sender addresses to match (for -pending)")
+	toFlag := flag.String("to", "", "comma separated recipient addresses to match (for -pending)")
+	selectorFlag := flag.String("selector", "", "4-byte method selector to match, e.g. 0xa9059cbb (for -pending)")
+	minValueFlag := flag.String("min-value", "", "minimum value in ETH, supports gwei / wei suffixes (for -pending)")
+	workers := flag.Int("workers", mempool.DefaultWorkers, "concurrent transaction lookups when only hashes are available (for -pending)")
+	pollInterval := flag.Duration("poll", subscribe.DefaultPollInterval, "polling interval when the node does not support subscriptions (e.g. HTTP)")
+	flag.Parse()
+
 	//从环境变量获取节点连接地址
 	rpcURL := os.Getenv("ETH_WS_URL")
 	if rpcURL == "" {
-		// 回退到 ETH_RPC_URL，便于在只配置了 HTTP 的环境中看到错误提示
+		// 回退到 ETH_RPC_URL，HTTP 连接不支持订阅，新区块改为轮询
 		rpcURL = os.Getenv("ETH_RPC_URL")
 	}
 	if rpcURL == "" {
@@ -38,6 +68,32 @@ func main() {
 	}
 	defer client.Close() // 程序退出时关闭连接
 
+	if *pending {
+		var filter mempool.Filter
+		if filter.From, err = mempool.ParseAddresses(*fromFlag); err != nil {
+			log.Fatalf("invalid -from: %v", err)
+		}
+		if filter.To, err = mempool.ParseAddresses(*toFlag); err != nil {
+			log.Fatalf("invalid -to: %v", err)
+		}
+		if filter.Selector, err = mempool.ParseSelector(*selectorFlag); err != nil {
+			log.Fatalf("invalid -selector: %v", err)
+		}
+		if *minValueFlag != "" {
+			if filter.MinValue, err = units.ParseEther(*minValueFlag); err != nil {
+				log.Fatalf("invalid -min-value: %v", err)
+			}
+		}
+		watchPending(ctx, cancel, client, &mempool.Watcher{
+			Client:  client,
+			Filter:  filter,
+			Full:    *full,
+			Workers: *workers,
+			Out:     os.Stdout,
+		})
+		return
+	}
+
 	//创建订阅通道并开始订阅
 	// headers: 用于接收新区块头的通道，缓冲区大小为0（无缓冲）
 	// 当新区块产生时，节点会将区块头发送到这个通道
@@ -47,8 +103,11 @@ func main() {
 	// 参数1: 上下文，用于取消订阅
 	// 参数2: 接收区块头的通道
 	// 返回: 订阅对象，包含错误通道 Err()
-	// 注意: 此功能必须使用 WebSocket 连接，HTTP 连接会返回错误
-	sub, err := client.SubscribeNewHead(ctx, headers)
+	// 注意: 节点推送需要 WebSocket 连接，HTTP 连接不支持订阅时 subscribe 自动退回轮询，通道和 sub.Err() 的用法不变
+	subscriber := subscribe.New(client)
+	subscriber.PollInterval = *pollInterval
+	subscriber.Out = os.Stdout
+	sub, err := subscriber.SubscribeNewHead(ctx, headers)
 	if err != nil {
 		log.Fatalf("failed to subscribe new heads: %v", err)
 	}
@@ -99,3 +158,59 @@ func main() {
 		}
 	}
 }
+
+// watchPending 监听待打包交易并逐笔打印，Ctrl+C 退出时输出统计
+func watchPending(ctx context.Context, cancel context.CancelFunc, client *ethclient.Client, watcher *mempool.Watcher) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	matches := make(chan mempool.Pending, 64)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- watcher.Run(ctx, matches)
+	}()
+
+	for {
+		select {
+		case p := <-matches:
+			printPending(p)
+		case err := <-errCh:
+			if err != nil {
+				log.Printf("mempool watcher stopped: %v", err)
+			}
+			printPendingStats(watcher.Stats())
+			return
+		case sig := <-sigCh:
+			fmt.Printf("received signal %s, shutting down...\n", sig.String())
+			// 等待 watcher 退出，避免 worker 仍在输出
+			cancel()
+			<-errCh
+			printPendingStats(watcher.Stats())
+			return
+		}
+	}
+}
+
+// printPending 打印一笔待打包交易
+func printPending(p mempool.Pending) {
+	tx := p.Tx
+	to := "contract creation"
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+	fmt.Printf("[%s] Pending Tx - Hash: %s\n", p.SeenAt.Format(time.RFC3339), tx.Hash().Hex())
+	fmt.Printf("  From    : %s\n", p.From.Hex())
+	fmt.Printf("  To      : %s\n", to)
+	fmt.Printf("  Value   : %s ETH\n", units.FormatEther(tx.Value()))
+	fmt.Printf("  Nonce   : %d\n", tx.Nonce())
+	if len(tx.Data()) >= 4 {
+		fmt.Printf("  Selector: %s\n", hexutil.Encode(tx.Data()[:4]))
+	}
+	fmt.Printf("  Max Fee : %s gwei (tip %s gwei)\n", units.FormatGwei(tx.GasFeeCap()), units.FormatGwei(tx.GasTipCap()))
+}
+
+// printPendingStats 打印监听统计
+func printPendingStats(stats mempool.Stats) {
+	fmt.Printf("Seen: %d, Matched: %d, Dropped (queue full): %d, Gone before lookup: %d\n",
+		stats.Seen, stats.Matched, stats.Dropped, stats.Missing)
+}
@@ -8,21 +8,48 @@ import (
 	"math/big"
 	"os"
 	"os/signal"
+	"reflect"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/abifile"
+	"ethutil/abifmt"
+	"ethutil/events"
+	"ethutil/logfilter"
+	"ethutil/logwatch"
+	"ethutil/subscribe"
+	"ethutil/watchcfg"
 )
 
 // 06-subscribe-logs.go
 // 订阅指定合约的日志事件（如 ERC-20 Transfer），并解析事件参数。
 // 本示例展示了如何从 logs 中解析出事件，包括 indexed 参数和普通参数。
+// 事件通过注册表（ethutil/events）识别：内置 ERC-20/721/1155、WETH、Uniswap V2/V3 的常用事件，
+// 还可以用 -abi 加载更多 ABI 文件（逗号分隔）。注册表预先计算 Topics[0] → 事件的索引，
+// Topics[0] 相同的事件（如 ERC-20 与 ERC-721 的 Transfer）按 indexed 参数数量区分。
+// 不指定 -contract 时订阅所有合约的日志。ERC-1155 TransferBatch 的 ids / values 数组以表格输出。
+// 链重组时节点会把被移除区块中的日志再推送一次（Removed = true），输出为 Retracted Event，表示之前的同一事件作废。
+// -from-block 先用 eth_getLogs 分段回填历史日志（范围太大时自动减半），再无缝切换到实时订阅，不遗漏也不重复；
+// 同时指定 -to-block 时只回填该范围后退出。
+// -event 只监听指定事件，-from / -to / -where 按 indexed 参数过滤（编码到 Topics，多个取值为 OR），对回填同样有效，例如：
+//
+//	go run . -event Transfer -to @addresses.txt
+//
+// -config 从 YAML / JSON 配置文件读取多个合约（各自的 ABI、事件子集和标签），合并为一个订阅，输出带上合约标签；
+// 收到 SIGHUP 时重新加载配置，监听的合约变化时从当前区块重新订阅，不需要重启：
+//
+//	go run . -config watch.yaml
+//	kill -HUP <pid>
 
 // ERC-20 标准 ABI（包含 Transfer 事件定义）
 //https://cryptomus.com/zh/blog/everything-you-need-to-know-about-usdt-networks
@@ -63,15 +90,33 @@ func main() {
 	// - 参数名称: `-contract`
 	// - 类型: 字符串指针
 	// - 默认值: 空字符串
-	// - 说明: 必须提供，否则程序退出
-	contractAddr := flag.String("contract", "", "contract address to subscribe logs from (required)")
+	// - 说明: 不提供时订阅所有合约的日志（主网上数据量很大）
+	contractAddr := flag.String("contract", "", "contract address to subscribe logs from (empty = all contracts)")
+	abiPaths := flag.String("abi", "", "comma-separated ABI JSON files whose events are added to the registry")
+	// 历史日志回填：-from-block 指定起始区块，先分段回填历史日志再切换到实时订阅；
+	// 同时指定 -to-block 时只回填 [from-block, to-block] 后退出
+	fromBlock := flag.Int64("from-block", -1, "backfill logs starting from this block before following live logs (-1 means live only)")
+	toBlock := flag.Int64("to-block", -1, "backfill up to this block and exit instead of following live logs (-1 means follow)")
+	chunk := flag.Uint64("chunk", logwatch.DefaultChunk, "initial block range per eth_getLogs request when backfilling")
+	// 按事件的 indexed 参数过滤（编码到 Topics 中，由节点过滤）：多个取值用逗号分隔（OR），"@文件" 从文件读取取值列表
+	eventName := flag.String("event", "", "only watch this event, by name or signature, e.g. Transfer or Swap(address,uint256,uint256,uint256,uint256,address)")
+	fromFilter := flag.String("from", "", "comma-separated values of the indexed \"from\" parameter to match, or @file (requires -event)")
+	toFilter := flag.String("to", "", "comma-separated values of the indexed \"to\" parameter to match, or @file (requires -event)")
+	whereFilter := flag.String("where", "", "indexed parameter filters, e.g. \"owner=0x..;spender=0x..,0x..\" (requires -event)")
+	configPath := flag.String("config", "", "YAML/JSON watch config listing contracts with their ABI, events and label; reloaded on SIGHUP")
+	pollInterval := flag.Duration("poll", subscribe.DefaultPollInterval, "polling interval when the node does not support subscriptions (e.g. HTTP)")
 	flag.Parse()// 解析命令行参数，将用户输入绑定到变量
-	// 验证合约地址参数是否提供
-	if *contractAddr == "" {
-		log.Fatal("missing --contract flag")
+	if *toBlock >= 0 && *fromBlock < 0 {
+		log.Fatal("--to-block requires --from-block")
+	}
+	if *toBlock >= 0 && *toBlock < *fromBlock {
+		log.Fatalf("--to-block %d is before --from-block %d", *toBlock, *fromBlock)
+	}
+	if *configPath != "" && (*contractAddr != "" || *abiPaths != "" || *eventName != "" || *fromFilter != "" || *toFilter != "" || *whereFilter != "") {
+		log.Fatal("--config cannot be combined with --contract, --abi, --event, --from, --to or --where")
 	}
 	//从环境变量获取节点连接地址
-	//优先获取 WebSocket URL（必须，因为日志订阅需要持久连接）
+	//优先获取 WebSocket URL（日志订阅需要持久连接），只有 HTTP 时退回轮询
 	rpcURL := os.Getenv("ETH_WS_URL")
 	if rpcURL == "" {
 		rpcURL = os.Getenv("ETH_RPC_URL")
@@ -83,7 +128,7 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	// 连接以太坊节点
-	// 注意: 如果使用 HTTP URL，SubscribeFilterLogs 会返回错误
+	// 注意: 如果使用 HTTP URL，SubscribeFilterLogs 不可用，subscribe 会改为 eth_newFilter / eth_getLogs 轮询
 	client, err := ethclient.DialContext(ctx, rpcURL)
 	if err != nil {
 		log.Fatalf("failed to connect to Ethereum node: %v", err)
@@ -97,66 +142,216 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to parse ABI: %v", err)
 	}
-	// 将入参字符串格式的合约地址转换为以太坊地址类型
-	contract := common.HexToAddress(*contractAddr)
+	// 创建事件注册表：内置常用事件 + ERC-20 ABI + -abi 指定的文件（后加载的优先）
+	registry := events.Bundled()
+	registry.AddABI(&parsedABI, "ERC-20")
+	for _, path := range strings.Split(*abiPaths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		extra, err := abifile.Load(path)
+		if err != nil {
+			log.Fatalf("failed to load ABI: %v", err)
+		}
+		registry.AddABI(extra, path)
+	}
 	//创建日志过滤查询
 	// FilterQuery 定义了我们要订阅的日志过滤条件
-	// 这里我们只过滤指定合约地址产生的所有日志
+	// 这里我们只过滤指定合约地址产生的所有日志，不指定地址时不过滤
 	query := ethereum.FilterQuery{
-		Addresses: []common.Address{contract},// 只监听这一个合约地址
 		// 还可以添加其他过滤条件:
 		// - Topics: 过滤特定事件类型或特定参数
 		// - FromBlock/ToBlock: 指定区块范围
 	}
-	// 创建日志通道：用于接收节点推送的原始日志
-	logsCh := make(chan types.Log)
-	// SubscribeFilterLogs: 订阅符合过滤条件的日志
-	// 参数1: 上下文，用于取消订阅
-	// 参数2: 过滤查询条件
-	// 参数3: 接收日志的通道
-	// 返回: 订阅对象，包含错误通道 Err()
-	// 注意: 此功能必须使用 WebSocket 连接
-	sub, err := client.SubscribeFilterLogs(ctx, query, logsCh)
+	target := "all contracts"
+	if *contractAddr != "" {
+		if !common.IsHexAddress(*contractAddr) {
+			log.Fatalf("invalid contract address: %s", *contractAddr)
+		}
+		// 将入参字符串格式的合约地址转换为以太坊地址类型
+		contract := common.HexToAddress(*contractAddr)
+		query.Addresses = []common.Address{contract} // 只监听这一个合约地址
+		target = "contract " + contract.Hex()
+	}
+	// 事件和参数过滤：Topics[0] 为事件签名哈希，Topics[1..] 为 indexed 参数取值，同一位置的多个取值为 OR
+	conds, err := logfilter.Parse(*whereFilter)
 	if err != nil {
-		log.Fatalf("failed to subscribe logs: %v", err)
+		log.Fatalf("invalid --where: %v", err)
+	}
+	if *fromFilter != "" {
+		if err := conds.Add("from", *fromFilter); err != nil {
+			log.Fatalf("invalid --from: %v", err)
+		}
+	}
+	if *toFilter != "" {
+		if err := conds.Add("to", *toFilter); err != nil {
+			log.Fatalf("invalid --to: %v", err)
+		}
+	}
+	if *eventName != "" {
+		entry, topics, err := logfilter.Resolve(registry, *eventName, conds)
+		if err != nil {
+			log.Fatalf("failed to build topic filter: %v", err)
+		}
+		query.Topics = topics
+		target += fmt.Sprintf(", event %s", entry.Event.Sig)
+		names := make([]string, 0, len(conds))
+		for name := range conds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			target += fmt.Sprintf(", %s in %d value(s)", name, len(conds[name]))
+		}
+	} else if len(conds) > 0 {
+		log.Fatal("--from, --to and --where require --event")
+	}
+	// 回填时每段调用一次 eth_getLogs，节点返回范围太大 / 结果太多时自动缩小区块范围
+	// 节点不支持订阅（HTTP 连接）时，subscribe 改为轮询，对 logwatch 透明
+	subscriber := subscribe.New(client)
+	subscriber.PollInterval = *pollInterval
+	subscriber.Out = os.Stdout
+	watcher := logwatch.New(subscriber)
+	watcher.Chunk = *chunk
+	watcher.Progress = func(from, to uint64, n int) {
+		fmt.Printf("Backfilled blocks %d-%d: %d log(s)\n", from, to, n)
 	}
-	// 注意: 这里没有 defer sub.Unsubscribe()，因为程序退出时直接返回
-	// 连接关闭时会自动取消订阅
+	if *fromBlock >= 0 {
+		query.FromBlock = big.NewInt(*fromBlock)
+	}
+	if *configPath != "" {
+		runConfig(ctx, watcher, *configPath, query.FromBlock, *toBlock)
+		return
+	}
+	handle := func(vLog types.Log) {
+		// 解析日志事件，  将原始的 types.Log 解析为结构化的合约事件
+		parseLogEvent(&vLog, registry, "")
+	}
+
+	// 只回填指定区块范围，不订阅
+	if *toBlock >= 0 {
+		query.ToBlock = big.NewInt(*toBlock)
+		fmt.Printf("Backfilling logs of %s in blocks %d-%d via %s\n", target, *fromBlock, *toBlock, rpcURL)
+		fmt.Printf("Event registry: %d event(s)\n\n", registry.Len())
+		if err := watcher.Backfill(ctx, query, handle); err != nil {
+			log.Fatalf("failed to backfill logs: %v", err)
+		}
+		return
+	}
+
+	// Follow 内部通过 SubscribeFilterLogs 订阅符合过滤条件的日志（HTTP 连接时为轮询），
+	// 指定了 -from-block 时先订阅、再回填到当前区块，回填期间推送的日志先缓存，回填结束后去掉重复的再输出
+	stream := watcher.Start(ctx, query, handle)
 
-	fmt.Printf("Subscribed to logs of contract %s via %s\n", contract.Hex(), rpcURL)
+	fmt.Printf("Subscribed to logs of %s via %s\n", target, rpcURL)
+	fmt.Printf("Event registry: %d event(s)\n", registry.Len())
+	if *fromBlock >= 0 {
+		fmt.Printf("Backfilling from block %d before following live logs\n", *fromBlock)
+	}
 	fmt.Printf("Listening for events...\n\n")
 
 	// 创建信号通道，用于接收 Ctrl+C 等退出信号
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	//主事件循环
+	select {
+	//订阅或回填发生错误（连接断开、节点问题等）
+	case err := <-stream.Err():
+		log.Printf("stopped watching logs: %v", err)
+	//收到系统退出信号（用户按 Ctrl+C）
+	case sig := <-sigCh:
+		fmt.Printf("received signal %s, shutting down...\n", sig.String())
+		stream.Stop()
+	}
+}
+
+// runConfig 按配置文件监听多个合约：所有合约合并为一个订阅，按日志的合约地址找到对应的注册表和标签解码。
+// 收到 SIGHUP 时重新加载配置，加载失败时继续使用原配置；订阅条件（地址或事件）变化时切换订阅
+func runConfig(ctx context.Context, watcher *logwatch.Watcher, path string, fromBlock *big.Int, toBlock int64) {
+	w, err := watchcfg.Load(path)
+	if err != nil {
+		log.Fatalf("failed to load watch config: %v", err)
+	}
+	// 当前配置：重新加载后解码立即使用新配置，处理日志的协程通过原子指针读取
+	var current atomic.Pointer[watchcfg.Watch]
+	current.Store(w)
+	handle := func(vLog types.Log) {
+		c := current.Load().Contract(vLog.Address)
+		// 重新加载后已经移除的合约，或者不在事件子集中的事件
+		if c == nil || !c.Wants(&vLog) {
+			return
+		}
+		parseLogEvent(&vLog, c.Registry, c.Label)
+	}
+	query := w.Query()
+	query.FromBlock = fromBlock
+	printWatchConfig(w)
+
+	if toBlock >= 0 {
+		query.ToBlock = big.NewInt(toBlock)
+		fmt.Printf("Backfilling blocks %d-%d\n\n", fromBlock, toBlock)
+		if err := watcher.Backfill(ctx, query, handle); err != nil {
+			log.Fatalf("failed to backfill logs: %v", err)
+		}
+		return
+	}
+
+	stream := watcher.Start(ctx, query, handle)
+	fmt.Printf("Listening for events (send SIGHUP to reload %s)...\n\n", path)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	for {
 		select {
-		//收到新的日志事件
-		case vLog := <-logsCh:
-			// 解析日志事件，  将原始的 types.Log 解析为结构化的合约事件
-			parseLogEvent(&vLog, parsedABI)
-		//订阅发生错误（连接断开、节点问题等）
-		case err := <-sub.Err():
-			log.Printf("subscription error: %v", err)
+		case err := <-stream.Err():
+			log.Printf("stopped watching logs: %v", err)
 			return
-		//收到系统退出信号（用户按 Ctrl+C）
 		case sig := <-sigCh:
-			fmt.Printf("received signal %s, shutting down...\n", sig.String())
-			return
-		//上下文被取消
-		case <-ctx.Done():
-			fmt.Println("context cancelled, exiting...")
-			return
+			if sig != syscall.SIGHUP {
+				fmt.Printf("received signal %s, shutting down...\n", sig.String())
+				stream.Stop()
+				return
+			}
+			next, err := watchcfg.Load(path)
+			if err != nil {
+				log.Printf("failed to reload watch config, keeping the previous one: %v", err)
+				continue
+			}
+			prev := current.Swap(next)
+			fmt.Printf("Reloaded %s\n", path)
+			printWatchConfig(next)
+			// 只改了标签、ABI 等解码信息时不需要重新订阅
+			nextQuery, prevQuery := next.Query(), prev.Query()
+			if reflect.DeepEqual(nextQuery.Addresses, prevQuery.Addresses) && reflect.DeepEqual(nextQuery.Topics, prevQuery.Topics) {
+				continue
+			}
+			if err := stream.Update(nextQuery); err != nil {
+				log.Printf("failed to resubscribe with the new config: %v", err)
+				return
+			}
+			fmt.Printf("Resubscribed with the new contract list\n\n")
 		}
 	}
 }
 
+// printWatchConfig 输出配置中的合约列表
+func printWatchConfig(w *watchcfg.Watch) {
+	fmt.Printf("Watching %d contract(s) from %s:\n", len(w.Contracts), w.Path)
+	for _, c := range w.Contracts {
+		evs := "all events"
+		if len(c.Events) > 0 {
+			evs = strings.Join(c.Events, ", ")
+		}
+		fmt.Printf("  %-16s %s  %s\n", c.Label, c.Address.Hex(), evs)
+	}
+}
+
 //将原始的以太坊日志解析为结构化的合约事件
 // parseLogEvent 解析日志事件，展示如何从 logs 中提取事件信息
-func parseLogEvent(vLog *types.Log, parsedABI abi.ABI) {
-	// 检查是否有 Topics（没有 Topics 的日志可能是无效的）
+// label 为配置文件中的合约标签，为空时不输出
+func parseLogEvent(vLog *types.Log, registry *events.Registry, label string) {
+	// 检查是否有 Topics（没有 Topics 的日志可能是无效的，或者是匿名事件）
 	if len(vLog.Topics) == 0 {
 		return
 	}
@@ -168,42 +363,55 @@ func parseLogEvent(vLog *types.Log, parsedABI abi.ABI) {
 	// 例如: Transfer(address,address,uint256) 的哈希
 	eventTopic := vLog.Topics[0]
 
-	// 尝试识别是哪个事件（通过比较 Topics[0] 和事件签名的哈希）
-	var eventName string
-	var eventSig abi.Event
-
-	// 遍历 ABI 中定义的所有事件，查找匹配的事件签名
-	for name, event := range parsedABI.Events {
-		// 计算事件的签名哈希
-		// 例如: "Transfer(address,address,uint256)" 的 Keccak256 哈希
-		eventSigHash := crypto.Keccak256Hash([]byte(event.Sig))
-		// 比较哈希值是否匹配
-		if eventSigHash == eventTopic {
-			eventName = name
-			eventSig = event
-			break
-		}
-	}
+	// 在注册表中查找事件：注册表已预先计算好每个事件签名的哈希，按 Topics[0] 直接索引，
+	// 签名相同的事件（ERC-20 与 ERC-721 的 Transfer）再按 indexed 参数数量区分：indexed 参数数量 = Topics 数量 - 1
+	entry, ok := registry.Lookup(vLog)
 
 	// 如果无法识别事件类型，打印原始信息并返回
-	if eventName == "" {
+	if !ok {
 		// 如果无法识别事件类型，打印原始信息
-		fmt.Printf("[%s] Unknown Event - Block: %d, Tx: %s, Topic[0]: %s\n",
+		prefix := ""
+		if label != "" {
+			prefix = "[" + label + "] "
+		}
+		if vLog.Removed {
+			prefix += "Retracted "
+		}
+		fmt.Printf("[%s] %sUnknown Event - Block: %d, Tx: %s, Contract: %s, Topic[0]: %s\n",
 			time.Now().Format(time.RFC3339),
+			prefix,
 			vLog.BlockNumber,
 			vLog.TxHash.Hex(),
+			vLog.Address.Hex(),
 			eventTopic.Hex(),
 		)
+		// Topics[0] 能匹配但 indexed 参数数量不同的事件，列出来便于判断
+		for _, c := range registry.Candidates(eventTopic) {
+			fmt.Printf("    signature matches %s (%s) but it has %d indexed parameter(s), log has %d\n",
+				c.Event.Sig, c.Source, c.Indexed, len(vLog.Topics)-1)
+		}
 		return
 	}
+	eventName := entry.Event.Name
+	eventSig := entry.Event
 
 	// 步骤 2: 解析事件参数
 	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("[%s] Event: %s\n", time.Now().Format(time.RFC3339), eventName)
+	if vLog.Removed {
+		// 链重组：这条日志所在的区块已经不在规范链上，之前输出的同一事件（相同交易哈希和日志索引）作废
+		fmt.Printf("[%s] Retracted Event: %s (%s)\n", time.Now().Format(time.RFC3339), eventName, entry.Source)
+		fmt.Printf("  Status      : REMOVED by a chain reorganization, this event did not happen on the canonical chain\n")
+	} else {
+		fmt.Printf("[%s] Event: %s (%s)\n", time.Now().Format(time.RFC3339), eventName, entry.Source)
+	}
 	fmt.Printf("  Block Number: %d\n", vLog.BlockNumber) // 区块高度
+	fmt.Printf("  Block Hash  : %s\n", vLog.BlockHash.Hex()) // 区块哈希（重组时用于区分同一高度的不同区块）
 	fmt.Printf("  Tx Hash     : %s\n", vLog.TxHash.Hex())// 交易哈希
 	fmt.Printf("  Log Index   : %d\n", vLog.Index)		 // 日志索引（区块内）
 	fmt.Printf("  Contract    : %s\n", vLog.Address.Hex())// 合约地址
+	if label != "" {
+		fmt.Printf("  Label       : %s\n", label) // 配置文件中的合约标签
+	}
 	fmt.Printf("  Topics Count: %d\n", len(vLog.Topics))  // Topics 数量
 
 	// 步骤 3: 解析 indexed 参数（从 Topics 中解析）
@@ -238,28 +446,11 @@ func parseLogEvent(vLog *types.Log, parsedABI abi.ABI) {
 		fmt.Printf("    [%d] %s (%s): ", i+1, input.Name, input.Type)
 
 		// 根据类型解析 indexed 参数
-		// indexed 参数在 Topics 中总是以 32 字节的哈希值存储
-		switch input.Type.T {
-		case abi.AddressTy:
-			// address 类型: 在 Topics 中是 32 字节，前12字节为0，后20字节是地址
-			// address 类型：去除前 12 字节的 0 填充，后 20 字节是地址
-			// 例如: 0x000000000000000000000000742d35Cc6634C0532925a3b844Bc9eC8d731e3e1
-			addr := common.BytesToAddress(topic.Bytes())
-			fmt.Printf("%s\n", addr.Hex())
-		case abi.IntTy, abi.UintTy:
-			// 整数类型：直接转换为 big.Int
-			value := new(big.Int).SetBytes(topic.Bytes())
-			fmt.Printf("%s\n", value.String())
-		case abi.BoolTy:
-			// bool 类型：检查最后一个字节
-			fmt.Printf("%t\n", topic[31] != 0)
-		case abi.BytesTy, abi.FixedBytesTy:
-			// bytes 类型：直接显示十六进制
-			fmt.Printf("%s\n", topic.Hex())
-		default:
-			// 其他类型：显示原始十六进制
-			fmt.Printf("%s (raw)\n", topic.Hex())
-		}
+		// indexed 参数在 Topics 中总是以 32 字节存储：
+		// - address: 前 12 字节为 0，后 20 字节是地址
+		// - intN: 符号扩展为 256 位补码，负数的高位全是 f
+		// - string / bytes / 数组 / tuple: 存的是值的 keccak256 哈希，无法还原原值，只能显示哈希
+		fmt.Printf("%s\n", abifmt.FormatTopic(input.Type, topic))
 	}
 
 	// 步骤 4: 解析非 indexed 参数（从 Data 字段中解析）
@@ -280,10 +471,13 @@ func parseLogEvent(vLog *types.Log, parsedABI abi.ABI) {
 			// 使用 ABI 解码 Data 字段
 			// 方法 1: 使用 UnpackIntoInterface（需要预定义结构体）
 			// 方法 2: 使用 Unpack（返回 []interface{}）
-			// Unpack 方法会根据事件名称和 Data 内容，解码出所有非 indexed 参数
-			values, err := parsedABI.Unpack(eventName, vLog.Data)
+			// Unpack 方法会根据 Data 内容，解码出所有非 indexed 参数
+			values, err := eventSig.Inputs.NonIndexed().Unpack(vLog.Data)
 			if err != nil {
 				fmt.Printf("    Error decoding data: %v\n", err)
+			} else if eventName == "TransferBatch" {
+				// ids 和 values 按下标对应，以表格输出比两个数组更直观
+				printBatchTable(values)
 			} else {
 				// 只输出非 indexed 参数
 				nonIndexedIdx := 0
@@ -293,17 +487,8 @@ func parseLogEvent(vLog *types.Log, parsedABI abi.ABI) {
 							value := values[nonIndexedIdx]
 							fmt.Printf("    [%d] %s (%s): ", i+1, input.Name, input.Type)
 
-							// 根据类型格式化输出
-							switch v := value.(type) {
-							case *big.Int:
-								fmt.Printf("%s\n", v.String())
-							case common.Address:
-								fmt.Printf("%s\n", v.Hex())
-							case []byte:
-								fmt.Printf("0x%x\n", v)
-							default:
-								fmt.Printf("%v\n", v)
-							}
+							// 按 ABI 类型格式化输出：tuple 带字段名，数组逐个元素，string 加引号，bytesN 为十六进制
+							fmt.Printf("%s\n", abifmt.Format(input.Type, value))
 							nonIndexedIdx++
 						}
 					}
@@ -316,3 +501,24 @@ func parseLogEvent(vLog *types.Log, parsedABI abi.ABI) {
 
 	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 }
+
+// printBatchTable 以表格输出 ERC-1155 TransferBatch 的 ids 和 values
+func printBatchTable(values []interface{}) {
+	if len(values) != 2 {
+		fmt.Printf("    unexpected TransferBatch data: %v\n", values)
+		return
+	}
+	ids, _ := values[0].([]*big.Int)
+	amounts, _ := values[1].([]*big.Int)
+	if len(ids) != len(amounts) {
+		fmt.Printf("    ids and values length mismatch: %d vs %d\n", len(ids), len(amounts))
+		return
+	}
+	fmt.Printf("    %d token id(s):\n", len(ids))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "    #\tID\tVALUE")
+	for i := range ids {
+		fmt.Fprintf(w, "    %d\t%s\t%s\n", i+1, ids[i].String(), amounts[i].String())
+	}
+	w.Flush()
+}
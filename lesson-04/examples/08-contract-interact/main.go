@@ -2,30 +2,56 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"flag"
 	"fmt"
 	"log"
-	"math"
 	"math/big"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"ethutil/abiargs"
+	"ethutil/abifile"
+	"ethutil/abifmt"
+	"ethutil/msgsig"
+	"ethutil/multicall"
+	"ethutil/nftmeta"
+	"ethutil/policy"
+	"ethutil/prompt"
+	"ethutil/revert"
+	"ethutil/signer"
+	"ethutil/txsim"
+	"ethutil/txwait"
+	"ethutil/units"
 )
 
 // 08-contract-interact.go
 // 使用通用 ABI 调用 ERC-20 合约的方法，包括：
-// 1. balanceOf: 查询余额（只读调用）
-// 2. transfer: 发送 ERC-20 转账交易（需要设置 SENDER_PRIVATE_KEY 环境变量）
-// 3. parse-event: 从交易回执中解析 Transfer 事件，展示 indexed 参数和 data 的对应关系
+// 1. balanceOf: 查询余额（只读调用），支持多个代币 × 多个地址，通过 Multicall3 一次查询
+// 2. transfer: 发送 ERC-20 转账交易（需要配置签名器，见注意事项）
+//    签名前会先预执行（dry run），展示回滚原因和余额变化预览，确认后才广播（--yes 跳过确认）
+// 3. parse-event: 从交易回执中解析 Transfer 事件，展示 indexed 参数和 data 的对应关系；同时输出 Approval 事件
+// 4. sign-message: 用签名器对链下消息签名（EIP-191 personal_sign 或 EIP-712 结构化数据），例如登录挑战、链下订单
+// 5. verify-message: 验证消息签名，EOA 通过 ecrecover 恢复地址，合约钱包调用 EIP-1271 isValidSignature
+// 6. info / name / symbol / total-supply: 查询代币元数据（通过 Multicall3 一次查询），兼容 symbol 返回 bytes32 的早期代币
+// 7. allowance: 查询授权额度；approve / increase-allowance / decrease-allowance / transfer-from: 授权和代扣转账交易
+// 8. nft-owner / nft-balance / nft-uri: 查询 ERC-721 持有人、持有数量和 tokenURI（并获取元数据 JSON）；
+//    nft-transfer / nft-approve-all: safeTransferFrom 和 setApprovalForAll 交易
+// 9. erc1155-balance / erc1155-uri: 查询 ERC-1155 余额（balanceOfBatch 一次查询多个地址 × 多个 id）和 uri（替换 {id}）；
+//    erc1155-transfer / erc1155-batch-transfer: safeTransferFrom 和 safeBatchTransferFrom 交易
+// 10. call / send: 通用合约调用，从 --abi（ABI JSON 或 Foundry / Hardhat 构建产物）中按方法名或签名查找方法，
+//    命令行参数按 ABI 类型转换后编码；call 通过 eth_call 读取并按名称解码返回值，send 发送交易
 //
 // 执行示例：
 //
@@ -35,6 +61,11 @@ import (
 //      --contract 0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48 \
 //      --address 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb
 //
+//    批量查询（逗号分隔，ETH 表示原生币余额），所有组合合并为一次 Multicall3 aggregate3 调用：
+//    go run main.go --mode balance \
+//      --contract ETH,0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48,0xdAC17F958D2ee523a2206206994597C13D831ec7 \
+//      --address 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb,0x28C6c06298d514Db089934071355E5743bf21d60
+//
 // 2. 发送 ERC-20 转账交易（使用代币数量，自动根据 decimals 转换）：
 //    export ETH_RPC_URL="http://127.0.0.1:8545"
 //    export SENDER_PRIVATE_KEY="your_private_key_hex"
@@ -56,14 +87,86 @@ import (
 //    go run main.go --mode parse-event \
 //      --tx 0x1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef
 //
+// 5. 签名登录挑战（EIP-191），不需要 ETH_RPC_URL：
+//    go run main.go --mode sign-message --keystore ./keystore/UTC--... \
+//      --message "Sign in to example.com, nonce: 8f2c1a"
+//
+// 6. 签名 EIP-712 结构化数据（JSON 格式与 eth_signTypedData_v4 的参数相同，包含 types / primaryType / domain / message）：
+//    go run main.go --mode sign-message --keystore ./keystore/UTC--... --typed-data order.json
+//
+// 7. 验证签名（--address 为声明的签名者；设置 ETH_RPC_URL 后，签名者是合约时会调用 EIP-1271 isValidSignature）：
+//    export ETH_RPC_URL="http://127.0.0.1:8545"
+//    go run main.go --mode verify-message \
+//      --address 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb \
+//      --typed-data order.json --signature 0x...
+//
+// 8. 查询代币元数据（name / symbol / decimals / totalSupply）和授权额度：
+//    export ETH_RPC_URL="http://127.0.0.1:8545"
+//    go run main.go --mode info --contract 0xdAC17F958D2ee523a2206206994597C13D831ec7
+//    go run main.go --mode allowance \
+//      --contract 0xdAC17F958D2ee523a2206206994597C13D831ec7 \
+//      --address 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb \
+//      --spender 0x28C6c06298d514Db089934071355E5743bf21d60
+//
+// 9. 授权和代扣转账（--amount max 表示无限授权；transfer-from 由被授权的签名者发送）：
+//    go run main.go --mode approve --keystore ./keystore/UTC--... \
+//      --contract 0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48 \
+//      --spender 0x28C6c06298d514Db089934071355E5743bf21d60 --amount 100
+//    go run main.go --mode transfer-from --keystore ./keystore/UTC--... \
+//      --contract 0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48 \
+//      --from 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb \
+//      --to 0x28C6c06298d514Db089934071355E5743bf21d60 --amount 1.5
+//
+// 10. ERC-721：查询持有人、tokenURI 和元数据，转出 NFT：
+//    export ETH_RPC_URL="http://127.0.0.1:8545"
+//    go run main.go --mode nft-owner --contract 0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D --token-id 1
+//    go run main.go --mode nft-uri --contract 0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D --token-id 1 \
+//      --ipfs-gateway https://cloudflare-ipfs.com/ipfs/
+//    go run main.go --mode nft-transfer --keystore ./keystore/UTC--... \
+//      --contract 0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D --token-id 1 \
+//      --to 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb
+//
+// 11. ERC-1155：批量查询余额、批量转账（--token-id 和 --amount 按顺序一一对应，数量没有 decimals）：
+//    go run main.go --mode erc1155-balance --contract 0x76BE3b62873462d2142405439777e971754E8E77 \
+//      --address 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb --token-id 1,2,3
+//    go run main.go --mode erc1155-batch-transfer --keystore ./keystore/UTC--... \
+//      --contract 0x76BE3b62873462d2142405439777e971754E8E77 \
+//      --to 0x28C6c06298d514Db089934071355E5743bf21d60 --token-id 1,2 --amount 10,1
+//
+// 12. 通用合约调用（不需要为每个合约方法编写 handleX 函数）：
+//    go run main.go --mode call --abi out/Counter.sol/Counter.json \
+//      --contract 0x5FbDB2315678afecb367f032d93F642f64180aa3 --method number
+//    go run main.go --mode send --keystore ./keystore/UTC--... --abi out/Counter.sol/Counter.json \
+//      --contract 0x5FbDB2315678afecb367f032d93F642f64180aa3 --method "setNumber(uint256)" --args 42
+//    go run main.go --mode call --abi Router.json --contract 0x... --method getAmountsOut \
+//      --args-json '["1000000", ["0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"]]'
+//
 // 注意事项：
 // - 所有示例中的地址和交易哈希都是示例，请替换为实际值
-// - transfer 模式需要设置 SENDER_PRIVATE_KEY 环境变量（私钥十六进制，可带或不带 0x 前缀）
+// - transfer 模式需要签名器：--keystore <file> [--password-file <file>]、--mnemonic-file <file> [--hd-path ...]
+//   或 --clef <url>；都未指定时兼容读取 SENDER_PRIVATE_KEY 环境变量（私钥十六进制，可带或不带 0x 前缀）
+// - transfer-from / approve / increase-allowance / decrease-allowance 与 transfer 使用相同的签名器、交易策略和确认流程
+// - 非标准代币：USDT 等代币的 transfer / approve 没有返回值，预执行返回空数据视为成功，返回 false 视为失败；
+//   USDT 的 approve 要求先把非零授权改为 0，修改非零授权时会给出提示；MKR 等早期代币的 name / symbol 返回 bytes32
+// - increaseAllowance / decreaseAllowance 不属于 ERC-20 标准，OpenZeppelin v5 已移除，这类代币上预执行会回滚
+// - nft-uri 支持 http(s)://、ipfs://（通过 --ipfs-gateway 或 IPFS_GATEWAY 指定的网关获取）和 data:（链上元数据）；
+//   --no-metadata 只输出 URI。parse-event 通过 Topics 数量（4 个）识别 ERC-721 的 Transfer 事件
+// - nft-transfer 使用 safeTransferFrom，接收方是合约时必须实现 onERC721Received，否则预执行会回滚
+// - call / send 的参数写法：address、bool、整数（十进制或 0x）、string、bytes（0x 十六进制）、数组 [a,b]、
+//   tuple (a,b) 或按字段名的 JSON 对象；--args-json 把全部参数写成一个 JSON 数组。重载的方法需要用签名指定
+// - sign-message 使用与 transfer 相同的签名器参数；签名中的 v 统一为 27/28，与钱包的 personal_sign 输出一致
+// - verify-message 未设置 ETH_RPC_URL 时只能用 ecrecover 验证 EOA 签名，验证失败时以非 0 状态码退出
+// - --policy <file>（或 TX_POLICY_FILE）为发送交易的模式启用交易策略：链 ID 白名单、代币额度、收款地址名单、
+//   手续费上限等，违反时输出 JSON 格式的拒绝原因并退出，不会签名
+// - 广播后默认等待 1 个确认；--wait-for 3 等待 3 个确认，--wait-for safe / finalized 等待对应的区块标签，
+//   --wait-timeout 设置最长等待时间。等待期间会检测链重组，ETH_RPC_URL 为 ws:// 时通过新区块订阅代替轮询
 // - 仅在测试网或本地开发链上使用，不要在主网使用包含真实资产的私钥
 // - amount 参数支持两种格式：
 //   * 小数格式（如 "1.5"）：自动根据代币的 decimals 转换为最小单位
 //   * 整数格式（如 "1500000"）：直接作为代币的最小单位使用
 
+// erc20ABIJSON 除了标准方法和事件，还包含 OpenZeppelin v5（IERC6093）定义的自定义错误，
+// 用于解码转账失败时的回滚原因，例如 ERC20InsufficientBalance(sender, balance, needed)
 const erc20ABIJSON = `[
   {
     "constant": true,
@@ -89,6 +192,78 @@ const erc20ABIJSON = `[
     "outputs": [{"name": "", "type": "bool"}],
     "type": "function"
   },
+  {
+    "constant": true,
+    "inputs": [],
+    "name": "name",
+    "outputs": [{"name": "", "type": "string"}],
+    "type": "function"
+  },
+  {
+    "constant": true,
+    "inputs": [],
+    "name": "symbol",
+    "outputs": [{"name": "", "type": "string"}],
+    "type": "function"
+  },
+  {
+    "constant": true,
+    "inputs": [],
+    "name": "totalSupply",
+    "outputs": [{"name": "", "type": "uint256"}],
+    "type": "function"
+  },
+  {
+    "constant": true,
+    "inputs": [
+      {"name": "owner", "type": "address"},
+      {"name": "spender", "type": "address"}
+    ],
+    "name": "allowance",
+    "outputs": [{"name": "", "type": "uint256"}],
+    "type": "function"
+  },
+  {
+    "constant": false,
+    "inputs": [
+      {"name": "spender", "type": "address"},
+      {"name": "value", "type": "uint256"}
+    ],
+    "name": "approve",
+    "outputs": [{"name": "", "type": "bool"}],
+    "type": "function"
+  },
+  {
+    "constant": false,
+    "inputs": [
+      {"name": "from", "type": "address"},
+      {"name": "to", "type": "address"},
+      {"name": "value", "type": "uint256"}
+    ],
+    "name": "transferFrom",
+    "outputs": [{"name": "", "type": "bool"}],
+    "type": "function"
+  },
+  {
+    "constant": false,
+    "inputs": [
+      {"name": "spender", "type": "address"},
+      {"name": "addedValue", "type": "uint256"}
+    ],
+    "name": "increaseAllowance",
+    "outputs": [{"name": "", "type": "bool"}],
+    "type": "function"
+  },
+  {
+    "constant": false,
+    "inputs": [
+      {"name": "spender", "type": "address"},
+      {"name": "subtractedValue", "type": "uint256"}
+    ],
+    "name": "decreaseAllowance",
+    "outputs": [{"name": "", "type": "bool"}],
+    "type": "function"
+  },
   {
     "anonymous": false,
     "inputs": [
@@ -98,19 +273,140 @@ const erc20ABIJSON = `[
     ],
     "name": "Transfer",
     "type": "event"
+  },
+  {
+    "anonymous": false,
+    "inputs": [
+      {"indexed": true, "name": "owner", "type": "address"},
+      {"indexed": true, "name": "spender", "type": "address"},
+      {"indexed": false, "name": "value", "type": "uint256"}
+    ],
+    "name": "Approval",
+    "type": "event"
+  },
+  {
+    "inputs": [
+      {"name": "sender", "type": "address"},
+      {"name": "balance", "type": "uint256"},
+      {"name": "needed", "type": "uint256"}
+    ],
+    "name": "ERC20InsufficientBalance",
+    "type": "error"
+  },
+  {
+    "inputs": [
+      {"name": "spender", "type": "address"},
+      {"name": "allowance", "type": "uint256"},
+      {"name": "needed", "type": "uint256"}
+    ],
+    "name": "ERC20InsufficientAllowance",
+    "type": "error"
+  },
+  {
+    "inputs": [{"name": "sender", "type": "address"}],
+    "name": "ERC20InvalidSender",
+    "type": "error"
+  },
+  {
+    "inputs": [{"name": "receiver", "type": "address"}],
+    "name": "ERC20InvalidReceiver",
+    "type": "error"
   }
 ]`
 
+// signerModes 需要签名器的操作模式
+var signerModes = map[string]bool{
+	"transfer":               true,
+	"transfer-from":          true,
+	"approve":                true,
+	"increase-allowance":     true,
+	"decrease-allowance":     true,
+	"nft-transfer":           true,
+	"nft-approve-all":        true,
+	"erc1155-transfer":       true,
+	"erc1155-batch-transfer": true,
+	"send":                   true,
+	"sign-message":           true,
+}
+
+// sendModes 会发送交易的操作模式，交易策略只对这些模式生效
+var sendModes = map[string]bool{
+	"transfer":               true,
+	"transfer-from":          true,
+	"approve":                true,
+	"increase-allowance":     true,
+	"decrease-allowance":     true,
+	"nft-transfer":           true,
+	"nft-approve-all":        true,
+	"erc1155-transfer":       true,
+	"erc1155-batch-transfer": true,
+	"send":                   true,
+}
+
 func main() {
-	mode := flag.String("mode", "balance", "operation mode: balance, transfer, or parse-event")
-	contractHex := flag.String("contract", "", "ERC-20 contract address")
-	addrHex := flag.String("address", "", "address (for balanceOf or transfer to)")
-	toHex := flag.String("to", "", "recipient address (for transfer)")
-	amount := flag.String("amount", "", "transfer amount (for transfer, can be token amount like 1.5 or raw amount)")
+	mode := flag.String("mode", "balance", "operation mode: balance, info, name, symbol, total-supply, allowance, transfer, transfer-from, approve, increase-allowance, decrease-allowance, nft-owner, nft-balance, nft-uri, nft-transfer, nft-approve-all, erc1155-balance, erc1155-uri, erc1155-transfer, erc1155-batch-transfer, call, send, parse-event, sign-message, or verify-message")
+	contractHex := flag.String("contract", "", "ERC-20 / ERC-721 / ERC-1155 contract address (comma separated list for balance, ETH for native balance)")
+	addrHex := flag.String("address", "", "address (comma separated list for balance, the owner for allowance, or the claimed signer for verify-message)")
+	fromHex := flag.String("from", "", "token owner address (for transfer-from / nft-transfer / erc1155-*transfer), or the caller (for call)")
+	toHex := flag.String("to", "", "recipient address (for transfer / transfer-from / nft-transfer / erc1155-*transfer)")
+	spenderHex := flag.String("spender", "", "spender address (for allowance / approve / increase-allowance / decrease-allowance)")
+	amount := flag.String("amount", "", "token amount like 1.5 or raw amount (for transfer / transfer-from / approve / *-allowance; approve also accepts max; comma separated raw amounts for erc1155-*transfer)")
+	tokenID := flag.String("token-id", "", "NFT token id, decimal or 0x hex (for nft-* modes; comma separated list for erc1155-balance / erc1155-batch-transfer)")
+	operatorHex := flag.String("operator", "", "operator address (for nft-approve-all)")
+	approved := flag.Bool("approved", true, "grant (true) or revoke (false) operator approval (for nft-approve-all)")
+	ipfsGateway := flag.String("ipfs-gateway", "", "IPFS gateway used to fetch ipfs:// metadata (defaults to $IPFS_GATEWAY or https://ipfs.io/ipfs/)")
+	noMetadata := flag.Bool("no-metadata", false, "only print the token URI, do not fetch metadata (for nft-uri / erc1155-uri)")
 	txHashHex := flag.String("tx", "", "transaction hash (for parse-event)")
+	assumeYes := flag.Bool("yes", false, "skip the confirmation prompt after pre-flight simulation (for send modes)")
+	message := flag.String("message", "", "message text (for sign-message / verify-message, EIP-191 personal_sign)")
+	typedDataFile := flag.String("typed-data", "", "EIP-712 typed data JSON file (for sign-message / verify-message)")
+	signatureHex := flag.String("signature", "", "65-byte signature hex (for verify-message)")
+	abiFiles := flag.String("abi", "", "comma separated ABI files or Foundry / Hardhat artifacts (contract ABI for call / send, also used to decode custom revert errors)")
+	methodName := flag.String("method", "", "method name or signature like transfer(address,uint256) (for call / send)")
+	methodArgs := flag.String("args", "", "comma separated method arguments, arrays as [a,b], tuples as (a,b) or JSON objects (for call / send)")
+	methodArgsJSON := flag.String("args-json", "", "method arguments as a JSON array, overrides --args (for call / send)")
+	callValue := flag.String("value", "", "ETH sent with a payable method, e.g. 0.1 or 30gwei (for call / send)")
+	// 签名器参数：--clef / --keystore / --mnemonic-file 等，未指定时兼容 SENDER_PRIVATE_KEY
+	signerCfg := signer.RegisterFlags(flag.CommandLine, "SENDER_PRIVATE_KEY")
+	policyFile := flag.String("policy", "", "transaction policy file (defaults to $TX_POLICY_FILE)")
+	waitFor := flag.String("wait-for", "1", "wait until the transaction has N confirmations, or is covered by the safe / finalized block")
+	waitTimeout := flag.Duration("wait-timeout", 5*time.Minute, "maximum time to wait for the transaction")
 	flag.Parse()
 
+	target, err := txwait.ParseTarget(*waitFor)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	rpcURL := os.Getenv("ETH_RPC_URL")
+
+	// 需要签名器的模式放在创建超时上下文之前打开签名器，keystore 可能需要交互输入密码
+	// 交易策略（--policy 或 TX_POLICY_FILE）只对发送交易的模式生效
+	var txSigner signer.Signer
+	var pol *policy.Policy
+	if signerModes[*mode] {
+		txSigner, err = signerCfg.Open()
+		if err != nil {
+			log.Fatalf("failed to open signer: %v", err)
+		}
+	}
+	if sendModes[*mode] {
+		pol, err = policy.Load(*policyFile)
+		if err != nil {
+			log.Fatalf("failed to load policy: %v", err)
+		}
+	}
+
+	// 消息签名和验证是链下操作：签名不需要节点，验证只有在签名者可能是合约钱包时才需要节点
+	switch *mode {
+	case "sign-message":
+		handleSignMessage(txSigner, *message, *typedDataFile)
+		return
+	case "verify-message":
+		handleVerifyMessage(rpcURL, *addrHex, *message, *typedDataFile, *signatureHex)
+		return
+	}
+
 	if rpcURL == "" {
 		log.Fatal("ETH_RPC_URL is not set")
 	}
@@ -129,278 +425,1319 @@ func main() {
 		log.Fatalf("failed to parse ABI: %v", err)
 	}
 
+	// 回滚原因解码器：内置 ERC-20 ABI 中的自定义错误 + --abi 指定的额外 ABI
+	extraABIs, err := abifile.LoadList(*abiFiles)
+	if err != nil {
+		log.Fatalf("failed to load ABI: %v", err)
+	}
+	nftABI, err := abi.JSON(strings.NewReader(erc721ABIJSON))
+	if err != nil {
+		log.Fatalf("failed to parse ERC-721 ABI: %v", err)
+	}
+	mtABI, err := abi.JSON(strings.NewReader(erc1155ABIJSON))
+	if err != nil {
+		log.Fatalf("failed to parse ERC-1155 ABI: %v", err)
+	}
+	decoder := revert.NewDecoder(append(extraABIs, &parsedABI, &nftABI, &mtABI)...)
+
+	// 发送交易的模式共用签名、策略检查和等待确认的流程
+	s := &sender{
+		client:      client,
+		decoder:     decoder,
+		signer:      txSigner,
+		policy:      pol,
+		assumeYes:   *assumeYes,
+		target:      target,
+		waitTimeout: *waitTimeout,
+	}
+
 	switch *mode {
 	case "balance":
-		handleBalanceOf(ctx, client, parsedABI, *contractHex, *addrHex)
+		handleBalanceOf(ctx, client, *contractHex, *addrHex)
+	case "info", "name", "symbol", "total-supply":
+		handleTokenInfo(ctx, client, parsedABI, *mode, *contractHex)
+	case "allowance":
+		handleAllowance(ctx, client, parsedABI, *contractHex, *addrHex, *spenderHex)
 	case "transfer":
-		handleTransfer(ctx, client, parsedABI, *contractHex, *toHex, *amount)
+		handleTransfer(ctx, s, parsedABI, *contractHex, *toHex, *amount)
+	case "transfer-from":
+		handleTransferFrom(ctx, s, parsedABI, *contractHex, *fromHex, *toHex, *amount)
+	case "approve":
+		handleApprove(ctx, s, parsedABI, "approve", *contractHex, *spenderHex, *amount)
+	case "increase-allowance":
+		handleApprove(ctx, s, parsedABI, "increaseAllowance", *contractHex, *spenderHex, *amount)
+	case "decrease-allowance":
+		handleApprove(ctx, s, parsedABI, "decreaseAllowance", *contractHex, *spenderHex, *amount)
+	case "nft-owner":
+		handleNFTOwner(ctx, client, decoder, nftABI, *contractHex, *tokenID)
+	case "nft-balance":
+		handleNFTBalance(ctx, client, nftABI, *contractHex, *addrHex)
+	case "nft-uri":
+		handleNFTURI(ctx, client, decoder, nftABI, *contractHex, *tokenID, *ipfsGateway, !*noMetadata)
+	case "nft-transfer":
+		handleNFTTransfer(ctx, s, nftABI, *contractHex, *fromHex, *toHex, *tokenID)
+	case "nft-approve-all":
+		handleNFTApproveAll(ctx, s, nftABI, *contractHex, *operatorHex, *approved)
+	case "erc1155-balance":
+		handleERC1155Balance(ctx, client, mtABI, *contractHex, *addrHex, *tokenID)
+	case "erc1155-uri":
+		handleERC1155URI(ctx, client, mtABI, *contractHex, *tokenID, *ipfsGateway, !*noMetadata)
+	case "erc1155-transfer", "erc1155-batch-transfer":
+		handleERC1155Transfer(ctx, s, mtABI, *mode == "erc1155-batch-transfer", *contractHex, *fromHex, *toHex, *tokenID, *amount)
+	case "call":
+		handleCall(ctx, client, decoder, extraABIs, *contractHex, *fromHex, *methodName, *methodArgs, *methodArgsJSON, *callValue)
+	case "send":
+		handleSend(ctx, s, extraABIs, *contractHex, *methodName, *methodArgs, *methodArgsJSON, *callValue)
 	case "parse-event":
-		handleParseEvent(ctx, client, parsedABI, *txHashHex)
+		handleParseEvent(ctx, client, parsedABI, decoder, *txHashHex)
 	default:
-		log.Fatalf("unknown mode: %s (use: balance, transfer, or parse-event)", *mode)
+		log.Fatalf("unknown mode: %s (use: balance, info, name, symbol, total-supply, allowance, transfer, transfer-from, approve, increase-allowance, decrease-allowance, nft-owner, nft-balance, nft-uri, nft-transfer, nft-approve-all, erc1155-balance, erc1155-uri, erc1155-transfer, erc1155-batch-transfer, call, send, parse-event, sign-message, or verify-message)", *mode)
 	}
 }
 
-// handleBalanceOf 查询 ERC-20 代币余额
-func handleBalanceOf(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contractHex, addrHex string) {
+// handleBalanceOf 查询 ERC-20 代币余额；--contract 和 --address 都可以是逗号分隔的列表，
+// 所有 (代币, 地址) 组合的 balanceOf 以及各代币的 decimals() 通过 Multicall3 合并为一次 eth_call，
+// 链上没有部署 Multicall3 时退回逐个调用
+func handleBalanceOf(ctx context.Context, client *ethclient.Client, contractHex, addrHex string) {
 	if contractHex == "" || addrHex == "" {
 		log.Fatal("missing --contract or --address flag for balance mode")
 	}
+	tokens, err := parseAddressList(contractHex, true)
+	if err != nil {
+		log.Fatalf("invalid --contract: %v", err)
+	}
+	owners, err := parseAddressList(addrHex, false)
+	if err != nil {
+		log.Fatalf("invalid --address: %v", err)
+	}
+
+	mc := multicall.New(client)
+	balances, err := mc.Balances(ctx, tokens, owners)
+	if err != nil {
+		log.Fatalf("failed to query balances: %v", err)
+	}
+
+	if len(balances) > 1 {
+		via := "individual calls (Multicall3 not deployed)"
+		if ok, _ := mc.Available(ctx); ok {
+			via = "Multicall3 aggregate3 " + multicall.DefaultAddress.Hex()
+		}
+		fmt.Printf("Queried %d balance(s) via %s\n\n", len(balances), via)
+	}
+	for i, b := range balances {
+		if i > 0 {
+			fmt.Println()
+		}
+		if b.Token == (common.Address{}) {
+			fmt.Printf("Contract : ETH (native)\n")
+		} else {
+			fmt.Printf("Contract : %s\n", b.Token.Hex())
+		}
+		fmt.Printf("Address  : %s\n", b.Owner.Hex())
+		if b.Err != nil {
+			fmt.Printf("Balance  : error: %v\n", b.Err)
+			continue
+		}
+		fmt.Printf("Balance  : %s (raw uint256)\n", b.Amount.String())
+		// decimals() 是可选接口，查询失败时只显示原始值
+		if b.Decimals >= 0 {
+			fmt.Printf("Formatted: %s (decimals %d)\n", units.Format(b.Amount, b.Decimals), b.Decimals)
+		}
+	}
+}
+
+// parseAddressList 解析逗号分隔的地址列表；allowETH 为 true 时 "ETH" 表示原生币（零地址）
+func parseAddressList(s string, allowETH bool) ([]common.Address, error) {
+	var out []common.Address
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			continue
+		case allowETH && strings.EqualFold(part, "ETH"):
+			out = append(out, common.Address{})
+		case common.IsHexAddress(part):
+			out = append(out, common.HexToAddress(part))
+		default:
+			return nil, fmt.Errorf("invalid address %q", part)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty address list")
+	}
+	return out, nil
+}
+
+// handleTransfer 发送 ERC-20 transfer 交易
+func handleTransfer(ctx context.Context, s *sender, parsedABI abi.ABI, contractHex, toHex, amountStr string) {
+	if contractHex == "" || toHex == "" || amountStr == "" {
+		log.Fatal("missing --contract, --to, or --amount flag for transfer mode")
+	}
 
 	contractAddr := common.HexToAddress(contractHex)
-	targetAddr := common.HexToAddress(addrHex)
+	toAddr := common.HexToAddress(toHex)
 
-	// 编码 balanceOf 调用数据
-	data, err := parsedABI.Pack("balanceOf", targetAddr)
+	// 查询代币的 decimals（精度）
+	decimals, err := getTokenDecimals(ctx, s.client, parsedABI, contractAddr)
 	if err != nil {
-		log.Fatalf("failed to pack data: %v", err)
+		log.Fatalf("failed to get token decimals: %v", err)
+	}
+
+	// 解析转账金额
+	// 如果输入包含小数点，则认为是代币数量，需要根据 decimals 转换
+	// 如果输入是整数，则认为是代币的最小单位（类似 wei 的概念）
+	amount, err := parseTokenAmount(amountStr, decimals)
+	if err != nil {
+		log.Fatalf("invalid amount: %v", err)
+	}
+
+	// 编码 transfer 调用数据
+	// transfer(address to, uint256 value)
+	callData, err := parsedABI.Pack("transfer", toAddr, amount)
+	if err != nil {
+		log.Fatalf("failed to pack transfer data: %v", err)
+	}
+
+	s.send(ctx, contractTx{
+		title:       "ERC-20 Transfer",
+		returnsBool: true,
+		contract:    contractAddr,
+		data:        callData,
+		// 交易策略检查：收款地址按代币接收方判断，代币额度按 decimals 换算
+		kind: policy.KindERC20Transfer,
+		token: &policy.TokenTransfer{
+			Contract:  contractAddr,
+			Recipient: toAddr,
+			Amount:    amount,
+			Decimals:  decimals,
+		},
+		details: [][2]string{
+			{"To", toAddr.Hex()},
+			{"Contract", contractAddr.Hex()},
+			{"Token Decimals", fmt.Sprintf("%d", decimals)},
+			// 显示代币数量（根据 decimals 转换）
+			{"Amount", fmt.Sprintf("%s tokens (%s raw units)", formatTokenAmount(amount, decimals), amount.String())},
+		},
+	})
+}
+
+// handleTransferFrom 发送 transferFrom(from, to, amount)：签名者作为被授权方（spender）转出 from 的代币
+func handleTransferFrom(ctx context.Context, s *sender, parsedABI abi.ABI, contractHex, fromHex, toHex, amountStr string) {
+	if contractHex == "" || fromHex == "" || toHex == "" || amountStr == "" {
+		log.Fatal("missing --contract, --from, --to, or --amount flag for transfer-from mode")
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	ownerAddr := common.HexToAddress(fromHex)
+	toAddr := common.HexToAddress(toHex)
+
+	decimals, err := getTokenDecimals(ctx, s.client, parsedABI, contractAddr)
+	if err != nil {
+		log.Fatalf("failed to get token decimals: %v", err)
+	}
+	amount, err := parseTokenAmount(amountStr, decimals)
+	if err != nil {
+		log.Fatalf("invalid amount: %v", err)
+	}
+
+	// 授权额度不足时预执行会回滚（OpenZeppelin v5 为 ERC20InsufficientAllowance），这里提前给出更直接的提示
+	if allowance, err := getAllowance(ctx, s.client, parsedABI, contractAddr, ownerAddr, s.signer.Address()); err == nil && allowance.Cmp(amount) < 0 {
+		fmt.Printf("Warning: allowance of %s for %s is %s, less than %s\n",
+			ownerAddr.Hex(), s.signer.Address().Hex(), formatTokenAmount(allowance, decimals), formatTokenAmount(amount, decimals))
+	}
+
+	callData, err := parsedABI.Pack("transferFrom", ownerAddr, toAddr, amount)
+	if err != nil {
+		log.Fatalf("failed to pack transferFrom data: %v", err)
+	}
+	s.send(ctx, contractTx{
+		title:       "ERC-20 TransferFrom",
+		returnsBool: true,
+		contract:    contractAddr,
+		data:        callData,
+		// 代币从 owner 转出，但额度由签名者的授权控制，按签名者的代币转账计入策略额度
+		kind: policy.KindERC20Transfer,
+		token: &policy.TokenTransfer{
+			Contract:  contractAddr,
+			Recipient: toAddr,
+			Amount:    amount,
+			Decimals:  decimals,
+		},
+		details: [][2]string{
+			{"Owner", ownerAddr.Hex()},
+			{"To", toAddr.Hex()},
+			{"Contract", contractAddr.Hex()},
+			{"Token Decimals", fmt.Sprintf("%d", decimals)},
+			{"Amount", fmt.Sprintf("%s tokens (%s raw units)", formatTokenAmount(amount, decimals), amount.String())},
+		},
+	})
+}
+
+// handleApprove 发送 approve / increaseAllowance / decreaseAllowance 交易，method 为对应的 ABI 方法名
+// increaseAllowance / decreaseAllowance 只存在于 OpenZeppelin v4 及更早的实现中，v5 的代币上预执行会回滚
+func handleApprove(ctx context.Context, s *sender, parsedABI abi.ABI, method, contractHex, spenderHex, amountStr string) {
+	if contractHex == "" || spenderHex == "" || amountStr == "" {
+		log.Fatalf("missing --contract, --spender, or --amount flag for %s", method)
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	spenderAddr := common.HexToAddress(spenderHex)
+
+	decimals, err := getTokenDecimals(ctx, s.client, parsedABI, contractAddr)
+	if err != nil {
+		log.Fatalf("failed to get token decimals: %v", err)
+	}
+	// approve 支持 "max" 表示无限授权（type(uint256).max）
+	var amount *big.Int
+	if method == "approve" && (strings.EqualFold(amountStr, "max") || strings.EqualFold(amountStr, "unlimited")) {
+		amount = new(big.Int).Set(abi.MaxUint256)
+	} else if amount, err = parseTokenAmount(amountStr, decimals); err != nil {
+		log.Fatalf("invalid amount: %v", err)
+	}
+
+	current, err := getAllowance(ctx, s.client, parsedABI, contractAddr, s.signer.Address(), spenderAddr)
+	if err != nil {
+		log.Fatalf("failed to get current allowance: %v", err)
+	}
+	// USDT 等代币要求先把非零授权改为 0，才能设置新的非零授权（防止授权竞争攻击）
+	if method == "approve" && current.Sign() > 0 && amount.Sign() > 0 {
+		fmt.Printf("Warning: current allowance is %s; some tokens (e.g. USDT) require approving 0 first\n", formatAllowance(current, decimals))
+	}
+
+	callData, err := parsedABI.Pack(method, spenderAddr, amount)
+	if err != nil {
+		log.Fatalf("failed to pack %s data: %v", method, err)
+	}
+	s.send(ctx, contractTx{
+		title:       "ERC-20 " + strings.ToUpper(method[:1]) + method[1:],
+		returnsBool: true,
+		contract:    contractAddr,
+		data:        callData,
+		kind:        policy.KindContractCall,
+		recipient:   &spenderAddr, // 策略的收款地址检查针对被授权的 spender
+		details: [][2]string{
+			{"Spender", spenderAddr.Hex()},
+			{"Contract", contractAddr.Hex()},
+			{"Current", formatAllowance(current, decimals)},
+			{"Amount", fmt.Sprintf("%s tokens (%s raw units)", formatAllowance(amount, decimals), amount.String())},
+		},
+	})
+}
+
+// sender 发送合约交易所需的公共参数：签名器、交易策略、确认方式
+type sender struct {
+	client      *ethclient.Client
+	decoder     *revert.Decoder
+	signer      signer.Signer
+	policy      *policy.Policy
+	assumeYes   bool
+	target      txwait.Target
+	waitTimeout time.Duration
+}
+
+// contractTx 一笔待发送的合约调用
+type contractTx struct {
+	title       string // 输出标题，例如 "ERC-20 Transfer"
+	contract    common.Address
+	data        []byte
+	value       *big.Int              // 随调用发送的 ETH（payable 方法），nil 表示 0
+	kind        string                // 交易策略中的交易类型
+	token       *policy.TokenTransfer // 代币转账信息，用于策略的收款地址和代币额度检查，非转账为 nil
+	recipient   *common.Address       // 策略检查的接收方（NFT 接收地址、授权对象），nil 时按 token / 合约地址判断
+	details     [][2]string           // 交易信息中额外输出的字段（名称, 值）
+	returnsBool bool                  // 方法返回 bool（ERC-20 的写方法），预执行返回 false 时不发送
+}
+
+// send 预执行、估算 Gas 和费用、检查交易策略并确认后签名广播，最后等待确认
+// ERC-20 的写方法都返回 bool：返回 false 视为失败，没有返回值（USDT 等非标准代币）视为成功
+func (s *sender) send(ctx context.Context, ctc contractTx) {
+	fromAddr := s.signer.Address()
+	client := s.client
+	value := ctc.value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+
+	// 预执行（dry run）：在 pending 状态上模拟调用
+	// 相比只依赖 EstimateGas 失败，预执行可以解码回滚原因并预览余额变化
+	sim, err := txsim.Simulate(ctx, client, ethereum.CallMsg{
+		From:  fromAddr,
+		To:    &ctc.contract,
+		Value: value,
+		Data:  ctc.data,
+	}, s.decoder)
+	if err != nil {
+		log.Fatalf("failed to simulate transaction: %v", err)
+	}
+	sim.Print(os.Stdout)
+	if sim.Revert != nil {
+		log.Fatalf("simulation reverted, transaction not sent: %s", sim.Revert)
+	}
+	// 返回 false 同样表示失败（USDT 等非标准代币没有返回值，此时 ReturnData 为空）
+	if ctc.returnsBool && len(sim.ReturnData) == 32 && new(big.Int).SetBytes(sim.ReturnData).Sign() == 0 {
+		log.Fatal("simulation returned false, transaction not sent")
+	}
+	// 获取链 ID
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("failed to get chain id: %v", err)
+	}
+
+	// 获取 nonce
+	nonce, err := client.PendingNonceAt(ctx, fromAddr)
+	if err != nil {
+		log.Fatalf("failed to get nonce: %v", err)
+	}
+
+	// 估算 Gas Limit（合约调用需要更多 Gas）
+	gasLimit, err := client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  fromAddr,
+		To:    &ctc.contract,
+		Value: value,
+		Data:  ctc.data,
+	})
+	if err != nil {
+		log.Fatalf("failed to estimate gas: %v", err)
+	}
+	// 增加 20% 的缓冲，避免 Gas 不足
+	gasLimit = gasLimit * 120 / 100
+
+	// 获取建议的 Gas 价格（使用 EIP-1559 动态费用）
+	gasTipCap, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		log.Fatalf("failed to get gas tip cap: %v", err)
+	}
+
+	// 获取 base fee，计算 fee cap
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Fatalf("failed to get header: %v", err)
+	}
+
+	baseFee := header.BaseFee
+	if baseFee == nil {
+		// 如果不支持 EIP-1559，使用传统 gas price
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			log.Fatalf("failed to get gas price: %v", err)
+		}
+		baseFee = gasPrice
+	}
+
+	// fee cap = base fee * 2 + tip cap（简单策略）
+	gasFeeCap := new(big.Int).Add(
+		new(big.Int).Mul(baseFee, big.NewInt(2)),
+		gasTipCap,
+	)
+
+	// 检查 ETH 余额是否足够支付 Gas 费用
+	balance, err := client.BalanceAt(ctx, fromAddr, nil)
+	if err != nil {
+		log.Fatalf("failed to get balance: %v", err)
+	}
+
+	// 计算总费用：gasFeeCap * gasLimit（代币调用不需要发送 ETH，只需要支付 Gas；payable 方法还需要加上 value）
+	totalGasCost := new(big.Int).Mul(gasFeeCap, big.NewInt(int64(gasLimit)))
+
+	if needed := new(big.Int).Add(totalGasCost, value); balance.Cmp(needed) < 0 {
+		log.Fatalf("insufficient ETH balance for gas: have %s wei, need %s wei", balance.String(), needed.String())
+	}
+
+	policyTx := &policy.Tx{
+		Kind:      ctc.kind,
+		ChainID:   chainID,
+		From:      fromAddr,
+		To:        &ctc.contract,
+		Value:     value,
+		GasLimit:  gasLimit,
+		GasFeeCap: gasFeeCap,
+		GasTipCap: gasTipCap,
+		Token:     ctc.token,
+		// 策略的收款地址检查针对实际接收方，而不是 NFT / 代币合约本身
+		Beneficiary: ctc.recipient,
+	}
+	decision, err := s.policy.Check(policyTx)
+	if err != nil {
+		policy.PrintRejection(os.Stdout, err)
+		log.Fatalf("transaction rejected by policy: %v", err)
+	}
+	// 策略要求大额确认时，--yes 不能跳过确认
+	ok, err := decision.Confirm(s.assumeYes, fmt.Sprintf("Sign and broadcast this %s?", ctc.title), prompt.Confirm)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if !ok {
+		fmt.Println("Aborted, transaction not sent.")
+		return
+	}
+	// 等待用户确认可能超过 main 中设置的超时时间，签名和广播使用新的上下文
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	// 构造交易（EIP-1559 动态费用交易）
+	// 注意：ERC-20 调用的 value 为 0，调用数据在 Data 字段中
+	txData := &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gasLimit,
+		To:        &ctc.contract, // 合约地址
+		Value:     value,         // 代币调用为 0，payable 方法为 --value
+		Data:      ctc.data,      // 调用数据
+	}
+	tx := types.NewTx(txData)
+
+	// 签名交易
+	signedTx, err := s.signer.SignTx(tx, chainID)
+	if err != nil {
+		log.Fatalf("failed to sign transaction: %v", err)
+	}
+
+	// 发送交易
+	if err := client.SendTransaction(ctx, signedTx); err != nil {
+		log.Fatalf("failed to send transaction: %v", err)
+	}
+	// 记入每日额度账本（交易已广播，记账失败只提示不退出）
+	if err := s.policy.Record(policyTx, signedTx.Hash()); err != nil {
+		fmt.Printf("Warning: failed to record spend in policy ledger: %v\n", err)
+	}
+
+	// 输出交易信息
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("%s Transaction Sent\n", ctc.title)
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("From          : %s\n", fromAddr.Hex())
+	fmt.Printf("Signer        : %s\n", s.signer.Kind())
+	for _, d := range ctc.details {
+		fmt.Printf("%-14s: %s\n", d[0], d[1])
+	}
+	fmt.Printf("Gas Limit     : %d\n", gasLimit)
+	fmt.Printf("Gas Tip Cap   : %s Wei\n", gasTipCap.String())
+	fmt.Printf("Gas Fee Cap   : %s Wei\n", gasFeeCap.String())
+	fmt.Printf("Estimated Cost: %s Wei (max %s ETH)\n", totalGasCost.String(), units.FormatEther(totalGasCost))
+	fmt.Printf("Nonce         : %d\n", nonce)
+	fmt.Printf("Tx Hash       : %s\n", signedTx.Hash().Hex())
+	fmt.Printf("\n")
+	fmt.Printf("Transaction is pending. Waiting for confirmation...\n")
+	fmt.Printf("\n")
+
+	// 等待交易确认（上面的 ctx 只有 20 秒，等待使用独立的超时时间）
+	waitForTransaction(client, s.decoder, signedTx, s.target, s.waitTimeout)
+}
+
+// tokenInfo ERC-20 代币的元数据，可选方法调用失败时对应字段为空
+type tokenInfo struct {
+	name        string
+	symbol      string
+	decimals    int // -1 表示 decimals() 调用失败
+	totalSupply *big.Int
+}
+
+// fetchTokenInfo 通过一次 Multicall3 调用查询 name / symbol / decimals / totalSupply
+func fetchTokenInfo(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contractAddr common.Address) (*tokenInfo, error) {
+	methods := []string{"name", "symbol", "decimals", "totalSupply"}
+	calls := make([]multicall.Call, len(methods))
+	for i, method := range methods {
+		data, err := parsedABI.Pack(method)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+		}
+		calls[i] = multicall.Call{Target: contractAddr, Data: data}
+	}
+	results, err := multicall.New(client).Aggregate(ctx, calls, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &tokenInfo{decimals: -1}
+	if results[0].Success {
+		info.name = decodeTokenString(parsedABI, "name", results[0].ReturnData)
+	}
+	if results[1].Success {
+		info.symbol = decodeTokenString(parsedABI, "symbol", results[1].ReturnData)
+	}
+	if r := results[2]; r.Success && len(r.ReturnData) >= 32 {
+		if v := new(big.Int).SetBytes(r.ReturnData[:32]); v.IsUint64() && v.Uint64() <= 255 {
+			info.decimals = int(v.Uint64())
+		}
+	}
+	if r := results[3]; r.Success && len(r.ReturnData) >= 32 {
+		info.totalSupply = new(big.Int).SetBytes(r.ReturnData[:32])
+	}
+	if !results[3].Success && info.decimals < 0 {
+		return nil, fmt.Errorf("%s does not look like an ERC-20 token", contractAddr.Hex())
+	}
+	return info, nil
+}
+
+// decodeTokenString 解码 name() / symbol() 的返回值：标准实现返回 string，
+// MKR、SAI 等早期代币返回 bytes32（右侧补 0）。ABI 编码的 string 至少 64 字节，正好 32 字节即为 bytes32
+func decodeTokenString(parsedABI abi.ABI, method string, data []byte) string {
+	if len(data) == 32 {
+		return strings.TrimRight(string(data), "\x00")
+	}
+	values, err := parsedABI.Unpack(method, data)
+	if err != nil || len(values) == 0 {
+		return ""
+	}
+	s, _ := values[0].(string)
+	return s
+}
+
+// handleTokenInfo 查询代币元数据；field 为 name / symbol / total-supply 时只输出对应字段，info 输出全部
+func handleTokenInfo(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, field, contractHex string) {
+	if contractHex == "" {
+		log.Fatalf("missing --contract flag for %s mode", field)
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	info, err := fetchTokenInfo(ctx, client, parsedABI, contractAddr)
+	if err != nil {
+		log.Fatalf("failed to query token info: %v", err)
+	}
+
+	orNA := func(s string) string {
+		if s == "" {
+			return "(not available)"
+		}
+		return s
+	}
+	supply := "(not available)"
+	if info.totalSupply != nil {
+		supply = fmt.Sprintf("%s (raw uint256)", info.totalSupply.String())
+		if info.decimals >= 0 {
+			supply = fmt.Sprintf("%s %s (%s raw)", units.Format(info.totalSupply, info.decimals), info.symbol, info.totalSupply.String())
+		}
+	}
+
+	fmt.Printf("Contract    : %s\n", contractAddr.Hex())
+	switch field {
+	case "name":
+		fmt.Printf("Name        : %s\n", orNA(info.name))
+	case "symbol":
+		fmt.Printf("Symbol      : %s\n", orNA(info.symbol))
+	case "total-supply":
+		fmt.Printf("Total Supply: %s\n", supply)
+	default:
+		fmt.Printf("Name        : %s\n", orNA(info.name))
+		fmt.Printf("Symbol      : %s\n", orNA(info.symbol))
+		if info.decimals >= 0 {
+			fmt.Printf("Decimals    : %d\n", info.decimals)
+		} else {
+			fmt.Printf("Decimals    : (not available)\n")
+		}
+		fmt.Printf("Total Supply: %s\n", supply)
+	}
+}
+
+// handleAllowance 查询 owner 授权给 spender 的额度
+func handleAllowance(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contractHex, ownerHex, spenderHex string) {
+	if contractHex == "" || ownerHex == "" || spenderHex == "" {
+		log.Fatal("missing --contract, --address (owner), or --spender flag for allowance mode")
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	ownerAddr := common.HexToAddress(ownerHex)
+	spenderAddr := common.HexToAddress(spenderHex)
+
+	allowance, err := getAllowance(ctx, client, parsedABI, contractAddr, ownerAddr, spenderAddr)
+	if err != nil {
+		log.Fatalf("failed to get allowance: %v", err)
+	}
+	fmt.Printf("Contract : %s\n", contractAddr.Hex())
+	fmt.Printf("Owner    : %s\n", ownerAddr.Hex())
+	fmt.Printf("Spender  : %s\n", spenderAddr.Hex())
+	fmt.Printf("Allowance: %s (raw uint256)\n", allowance.String())
+	if decimals, err := getTokenDecimals(ctx, client, parsedABI, contractAddr); err == nil {
+		fmt.Printf("Formatted: %s (decimals %d)\n", formatAllowance(allowance, decimals), decimals)
+	}
+}
+
+// getAllowance 查询 allowance(owner, spender)
+func getAllowance(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contractAddr, owner, spender common.Address) (*big.Int, error) {
+	data, err := parsedABI.Pack("allowance", owner, spender)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack allowance data: %w", err)
+	}
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &contractAddr, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call allowance: %w", err)
+	}
+	var allowance *big.Int
+	if err := parsedABI.UnpackIntoInterface(&allowance, "allowance", output); err != nil {
+		return nil, fmt.Errorf("failed to unpack allowance output: %w", err)
+	}
+	return allowance, nil
+}
+
+// formatAllowance 格式化授权额度，type(uint256).max 显示为 unlimited
+func formatAllowance(amount *big.Int, decimals uint8) string {
+	if amount.Cmp(abi.MaxUint256) == 0 {
+		return "unlimited"
+	}
+	return formatTokenAmount(amount, decimals)
+}
+
+// erc721ABIJSON ERC-721 的常用方法、事件和 OpenZeppelin v5（IERC6093）自定义错误。
+// Transfer 事件的签名与 ERC-20 相同（Transfer(address,address,uint256)），区别是 tokenId 也是 indexed，日志有 4 个 topics
+const erc721ABIJSON = `[
+  {"inputs":[{"name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"tokenId","type":"uint256"}],"name":"ownerOf","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"tokenId","type":"uint256"}],"name":"tokenURI","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+  {"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+  {"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"tokenId","type":"uint256"}],"name":"getApproved","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"owner","type":"address"},{"name":"operator","type":"address"}],"name":"isApprovedForAll","outputs":[{"name":"","type":"bool"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"name":"safeTransferFrom","outputs":[],"stateMutability":"nonpayable","type":"function"},
+  {"inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}],"name":"setApprovalForAll","outputs":[],"stateMutability":"nonpayable","type":"function"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":true,"name":"tokenId","type":"uint256"}],"name":"Transfer","type":"event"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"owner","type":"address"},{"indexed":true,"name":"approved","type":"address"},{"indexed":true,"name":"tokenId","type":"uint256"}],"name":"Approval","type":"event"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"owner","type":"address"},{"indexed":true,"name":"operator","type":"address"},{"indexed":false,"name":"approved","type":"bool"}],"name":"ApprovalForAll","type":"event"},
+  {"inputs":[{"name":"tokenId","type":"uint256"}],"name":"ERC721NonexistentToken","type":"error"},
+  {"inputs":[{"name":"sender","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"owner","type":"address"}],"name":"ERC721IncorrectOwner","type":"error"},
+  {"inputs":[{"name":"operator","type":"address"},{"name":"tokenId","type":"uint256"}],"name":"ERC721InsufficientApproval","type":"error"},
+  {"inputs":[{"name":"receiver","type":"address"}],"name":"ERC721InvalidReceiver","type":"error"},
+  {"inputs":[{"name":"operator","type":"address"}],"name":"ERC721InvalidOperator","type":"error"}
+]`
+
+// parseTokenID 解析十进制或 0x 十六进制的 token ID
+func parseTokenID(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, fmt.Errorf("missing --token-id")
+	}
+	id, ok := new(big.Int).SetString(strings.TrimSpace(s), 0)
+	if !ok || id.Sign() < 0 || id.BitLen() > 256 {
+		return nil, fmt.Errorf("invalid token id %q", s)
+	}
+	return id, nil
+}
+
+// callView 调用只读方法并解码返回值
+func callView(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contractAddr common.Address, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := parsedABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	output, err := client.CallContract(ctx, ethereum.CallMsg{To: &contractAddr, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	values, err := parsedABI.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s output: %w", method, err)
+	}
+	return values, nil
+}
+
+// getNFTOwner 查询 ownerOf(tokenId)；不存在的 token 会回滚（OpenZeppelin v5 为 ERC721NonexistentToken）
+func getNFTOwner(ctx context.Context, client *ethclient.Client, decoder *revert.Decoder, nftABI abi.ABI, contractAddr common.Address, tokenID *big.Int) (common.Address, error) {
+	values, err := callView(ctx, client, nftABI, contractAddr, "ownerOf", tokenID)
+	if err != nil {
+		if reason := decoder.FromError(err); reason != nil {
+			return common.Address{}, fmt.Errorf("ownerOf reverted: %s", reason)
+		}
+		return common.Address{}, err
+	}
+	return values[0].(common.Address), nil
+}
+
+// handleNFTOwner 查询 NFT 的持有人和单独授权地址
+func handleNFTOwner(ctx context.Context, client *ethclient.Client, decoder *revert.Decoder, nftABI abi.ABI, contractHex, tokenIDStr string) {
+	if contractHex == "" {
+		log.Fatal("missing --contract flag for nft-owner mode")
+	}
+	tokenID, err := parseTokenID(tokenIDStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	owner, err := getNFTOwner(ctx, client, decoder, nftABI, contractAddr, tokenID)
+	if err != nil {
+		log.Fatalf("failed to get owner: %v", err)
+	}
+	fmt.Printf("Contract : %s\n", contractAddr.Hex())
+	fmt.Printf("Token ID : %s\n", tokenID.String())
+	fmt.Printf("Owner    : %s\n", owner.Hex())
+	if values, err := callView(ctx, client, nftABI, contractAddr, "getApproved", tokenID); err == nil {
+		if approved := values[0].(common.Address); approved != (common.Address{}) {
+			fmt.Printf("Approved : %s\n", approved.Hex())
+		}
+	}
+}
+
+// handleNFTBalance 查询地址持有的 NFT 数量
+func handleNFTBalance(ctx context.Context, client *ethclient.Client, nftABI abi.ABI, contractHex, addrHex string) {
+	if contractHex == "" || addrHex == "" {
+		log.Fatal("missing --contract or --address flag for nft-balance mode")
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	owner := common.HexToAddress(addrHex)
+	values, err := callView(ctx, client, nftABI, contractAddr, "balanceOf", owner)
+	if err != nil {
+		log.Fatalf("failed to get NFT balance: %v", err)
+	}
+	fmt.Printf("Contract : %s\n", contractAddr.Hex())
+	if values, err := callView(ctx, client, nftABI, contractAddr, "name"); err == nil {
+		fmt.Printf("Name     : %s\n", values[0].(string))
+	}
+	fmt.Printf("Address  : %s\n", owner.Hex())
+	fmt.Printf("Balance  : %s NFT(s)\n", values[0].(*big.Int).String())
+}
+
+// handleNFTURI 查询 tokenURI 并获取元数据（http(s)、ipfs:// 通过网关、data: 链上元数据）
+func handleNFTURI(ctx context.Context, client *ethclient.Client, decoder *revert.Decoder, nftABI abi.ABI, contractHex, tokenIDStr, gateway string, fetch bool) {
+	if contractHex == "" {
+		log.Fatal("missing --contract flag for nft-uri mode")
+	}
+	tokenID, err := parseTokenID(tokenIDStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	values, err := callView(ctx, client, nftABI, contractAddr, "tokenURI", tokenID)
+	if err != nil {
+		if reason := decoder.FromError(err); reason != nil {
+			log.Fatalf("tokenURI reverted: %s", reason)
+		}
+		log.Fatalf("failed to get token URI: %v", err)
+	}
+	uri := values[0].(string)
+
+	fmt.Printf("Contract : %s\n", contractAddr.Hex())
+	fmt.Printf("Token ID : %s\n", tokenID.String())
+	if strings.HasPrefix(uri, "data:") && len(uri) > 80 {
+		fmt.Printf("Token URI: %s... (%d bytes, on-chain data URI)\n", uri[:80], len(uri))
+	} else {
+		fmt.Printf("Token URI: %s\n", uri)
+	}
+	if resolved := nftmeta.ResolveURI(uri, gateway); resolved != uri {
+		fmt.Printf("Resolved : %s\n", resolved)
+	}
+	if !fetch || uri == "" {
+		return
+	}
+	printNFTMetadata(ctx, uri, gateway)
+}
+
+// printNFTMetadata 获取并输出元数据；获取失败只提示，不退出（URI 已经输出）
+func printNFTMetadata(ctx context.Context, uri, gateway string) {
+	fetcher := &nftmeta.Fetcher{Gateway: gateway}
+	meta, err := fetcher.Fetch(ctx, uri)
+	if err != nil {
+		fmt.Printf("Metadata : error: %v\n", err)
+		return
+	}
+	fmt.Printf("\nMetadata:\n")
+	fmt.Printf("  Name       : %s\n", meta.Name)
+	if meta.Description != "" {
+		fmt.Printf("  Description: %s\n", meta.Description)
+	}
+	if meta.Image != "" {
+		fmt.Printf("  Image      : %s\n", nftmeta.ResolveURI(meta.Image, gateway))
+	}
+	if meta.ExternalURL != "" {
+		fmt.Printf("  External   : %s\n", meta.ExternalURL)
+	}
+	for _, attr := range meta.Attributes {
+		fmt.Printf("  - %s: %v\n", attr.TraitType, attr.Value)
+	}
+}
+
+// handleNFTTransfer 发送 safeTransferFrom(from, to, tokenId)；from 默认为签名者，
+// 签名者是被授权的 operator 时可以通过 --from 转出其他人的 NFT
+func handleNFTTransfer(ctx context.Context, s *sender, nftABI abi.ABI, contractHex, fromHex, toHex, tokenIDStr string) {
+	if contractHex == "" || toHex == "" {
+		log.Fatal("missing --contract or --to flag for nft-transfer mode")
+	}
+	tokenID, err := parseTokenID(tokenIDStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	toAddr := common.HexToAddress(toHex)
+	fromAddr := s.signer.Address()
+	if fromHex != "" {
+		fromAddr = common.HexToAddress(fromHex)
+	}
+
+	owner, err := getNFTOwner(ctx, s.client, s.decoder, nftABI, contractAddr, tokenID)
+	if err != nil {
+		log.Fatalf("failed to get owner: %v", err)
+	}
+	if owner != fromAddr {
+		log.Fatalf("token %s is owned by %s, not %s", tokenID.String(), owner.Hex(), fromAddr.Hex())
+	}
+
+	callData, err := nftABI.Pack("safeTransferFrom", fromAddr, toAddr, tokenID)
+	if err != nil {
+		log.Fatalf("failed to pack safeTransferFrom data: %v", err)
+	}
+	s.send(ctx, contractTx{
+		title:     "ERC-721 Transfer",
+		contract:  contractAddr,
+		data:      callData,
+		kind:      policy.KindContractCall,
+		recipient: &toAddr,
+		details: [][2]string{
+			{"Owner", fromAddr.Hex()},
+			{"To", toAddr.Hex()},
+			{"Contract", contractAddr.Hex()},
+			{"Token ID", tokenID.String()},
+		},
+	})
+}
+
+// handleNFTApproveAll 发送 setApprovalForAll(operator, approved)：授权（或撤销）operator 转出签名者在该合约下的全部 NFT
+func handleNFTApproveAll(ctx context.Context, s *sender, nftABI abi.ABI, contractHex, operatorHex string, approved bool) {
+	if contractHex == "" || operatorHex == "" {
+		log.Fatal("missing --contract or --operator flag for nft-approve-all mode")
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	operator := common.HexToAddress(operatorHex)
+
+	if values, err := callView(ctx, s.client, nftABI, contractAddr, "isApprovedForAll", s.signer.Address(), operator); err == nil {
+		if current := values[0].(bool); current == approved {
+			fmt.Printf("Note: operator approval is already %t\n", current)
+		}
+	}
+	callData, err := nftABI.Pack("setApprovalForAll", operator, approved)
+	if err != nil {
+		log.Fatalf("failed to pack setApprovalForAll data: %v", err)
+	}
+	s.send(ctx, contractTx{
+		title:     "ERC-721 SetApprovalForAll",
+		contract:  contractAddr,
+		data:      callData,
+		kind:      policy.KindContractCall,
+		recipient: &operator,
+		details: [][2]string{
+			{"Operator", operator.Hex()},
+			{"Contract", contractAddr.Hex()},
+			{"Approved", fmt.Sprintf("%t", approved)},
+		},
+	})
+}
+
+// erc1155ABIJSON ERC-1155 多代币标准的常用方法、事件和 OpenZeppelin v5（IERC6093）自定义错误。
+// 同一个合约下每个 id 是一种代币（可以是同质化的游戏货币，也可以是数量为 1 的道具），数量没有 decimals
+const erc1155ABIJSON = `[
+  {"inputs":[{"name":"account","type":"address"},{"name":"id","type":"uint256"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"accounts","type":"address[]"},{"name":"ids","type":"uint256[]"}],"name":"balanceOfBatch","outputs":[{"name":"","type":"uint256[]"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"id","type":"uint256"}],"name":"uri","outputs":[{"name":"","type":"string"}],"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],"name":"safeTransferFrom","outputs":[],"stateMutability":"nonpayable","type":"function"},
+  {"inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"ids","type":"uint256[]"},{"name":"values","type":"uint256[]"},{"name":"data","type":"bytes"}],"name":"safeBatchTransferFrom","outputs":[],"stateMutability":"nonpayable","type":"function"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"operator","type":"address"},{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"id","type":"uint256"},{"indexed":false,"name":"value","type":"uint256"}],"name":"TransferSingle","type":"event"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"operator","type":"address"},{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"ids","type":"uint256[]"},{"indexed":false,"name":"values","type":"uint256[]"}],"name":"TransferBatch","type":"event"},
+  {"inputs":[{"name":"sender","type":"address"},{"name":"balance","type":"uint256"},{"name":"needed","type":"uint256"},{"name":"tokenId","type":"uint256"}],"name":"ERC1155InsufficientBalance","type":"error"},
+  {"inputs":[{"name":"operator","type":"address"},{"name":"owner","type":"address"}],"name":"ERC1155MissingApprovalForAll","type":"error"},
+  {"inputs":[{"name":"receiver","type":"address"}],"name":"ERC1155InvalidReceiver","type":"error"},
+  {"inputs":[{"name":"idsLength","type":"uint256"},{"name":"valuesLength","type":"uint256"}],"name":"ERC1155InvalidArrayLength","type":"error"}
+]`
+
+// parseUintList 解析逗号分隔的十进制或 0x 十六进制整数列表（token id、数量）
+func parseUintList(s, what string) ([]*big.Int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("missing %s", what)
+	}
+	var out []*big.Int
+	for _, part := range strings.Split(s, ",") {
+		v, ok := new(big.Int).SetString(strings.TrimSpace(part), 0)
+		if !ok || v.Sign() < 0 || v.BitLen() > 256 {
+			return nil, fmt.Errorf("invalid %s %q", what, part)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// handleERC1155Balance 查询 ERC-1155 余额；--address 和 --token-id 都可以是逗号分隔的列表，
+// 所有 (地址, id) 组合通过一次 balanceOfBatch 查询
+func handleERC1155Balance(ctx context.Context, client *ethclient.Client, mtABI abi.ABI, contractHex, addrHex, tokenIDs string) {
+	if contractHex == "" || addrHex == "" {
+		log.Fatal("missing --contract or --address flag for erc1155-balance mode")
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	owners, err := parseAddressList(addrHex, false)
+	if err != nil {
+		log.Fatalf("invalid --address: %v", err)
+	}
+	ids, err := parseUintList(tokenIDs, "--token-id")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// balanceOfBatch 的两个数组按下标一一对应
+	accounts := make([]common.Address, 0, len(owners)*len(ids))
+	batchIDs := make([]*big.Int, 0, len(owners)*len(ids))
+	for _, owner := range owners {
+		for _, id := range ids {
+			accounts = append(accounts, owner)
+			batchIDs = append(batchIDs, id)
+		}
+	}
+	values, err := callView(ctx, client, mtABI, contractAddr, "balanceOfBatch", accounts, batchIDs)
+	if err != nil {
+		log.Fatalf("failed to get ERC-1155 balances: %v", err)
+	}
+	balances := values[0].([]*big.Int)
+	if len(balances) != len(accounts) {
+		log.Fatalf("balanceOfBatch returned %d balances for %d queries", len(balances), len(accounts))
 	}
 
-	callMsg := ethereum.CallMsg{
-		To:   &contractAddr,
-		Data: data,
+	fmt.Printf("Contract : %s\n\n", contractAddr.Hex())
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tTOKEN ID\tBALANCE")
+	for i, balance := range balances {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", accounts[i].Hex(), batchIDs[i].String(), balance.String())
 	}
+	w.Flush()
+}
 
-	// 执行只读调用
-	output, err := client.CallContract(ctx, callMsg, nil)
+// handleERC1155URI 查询 uri(id)，替换 {id} 占位符后获取元数据
+func handleERC1155URI(ctx context.Context, client *ethclient.Client, mtABI abi.ABI, contractHex, tokenIDStr, gateway string, fetch bool) {
+	if contractHex == "" {
+		log.Fatal("missing --contract flag for erc1155-uri mode")
+	}
+	tokenID, err := parseTokenID(tokenIDStr)
 	if err != nil {
-		log.Fatalf("CallContract error: %v", err)
+		log.Fatal(err)
 	}
-
-	// 解码返回值
-	var balance *big.Int
-	err = parsedABI.UnpackIntoInterface(&balance, "balanceOf", output)
+	contractAddr := common.HexToAddress(contractHex)
+	values, err := callView(ctx, client, mtABI, contractAddr, "uri", tokenID)
 	if err != nil {
-		log.Fatalf("failed to unpack output: %v", err)
+		log.Fatalf("failed to get uri: %v", err)
 	}
+	template := values[0].(string)
+	// EIP-1155：客户端把 {id} 替换为 64 位小写十六进制 id（不带 0x），所有 id 可以共用一个 URI
+	uri := nftmeta.ExpandID(template, tokenID)
 
 	fmt.Printf("Contract : %s\n", contractAddr.Hex())
-	fmt.Printf("Address  : %s\n", targetAddr.Hex())
-	fmt.Printf("Balance  : %s (raw uint256)\n", balance.String())
+	fmt.Printf("Token ID : %s\n", tokenID.String())
+	fmt.Printf("URI      : %s\n", template)
+	if uri != template {
+		fmt.Printf("Expanded : %s\n", uri)
+	}
+	if resolved := nftmeta.ResolveURI(uri, gateway); resolved != uri {
+		fmt.Printf("Resolved : %s\n", resolved)
+	}
+	if !fetch || uri == "" {
+		return
+	}
+	printNFTMetadata(ctx, uri, gateway)
 }
 
-// handleTransfer 发送 ERC-20 transfer 交易
-func handleTransfer(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contractHex, toHex, amountStr string) {
-	if contractHex == "" || toHex == "" || amountStr == "" {
-		log.Fatal("missing --contract, --to, or --amount flag for transfer mode")
+// handleERC1155Transfer 发送 safeTransferFrom（一个 id）或 safeBatchTransferFrom（多个 id）。
+// --token-id 和 --amount 是等长的逗号分隔列表；from 默认为签名者，签名者是 operator 时可以通过 --from 指定
+func handleERC1155Transfer(ctx context.Context, s *sender, mtABI abi.ABI, batch bool, contractHex, fromHex, toHex, tokenIDs, amounts string) {
+	mode := "erc1155-transfer"
+	if batch {
+		mode = "erc1155-batch-transfer"
 	}
-
-	// 检查私钥环境变量
-	privKeyHex := os.Getenv("SENDER_PRIVATE_KEY")
-	if privKeyHex == "" {
-		log.Fatal("SENDER_PRIVATE_KEY is not set (required for transfer mode)")
+	if contractHex == "" || toHex == "" {
+		log.Fatalf("missing --contract or --to flag for %s mode", mode)
 	}
-
-	// 解析私钥
-	privKey, err := crypto.HexToECDSA(trim0x(privKeyHex))
+	ids, err := parseUintList(tokenIDs, "--token-id")
 	if err != nil {
-		log.Fatalf("invalid private key: %v", err)
+		log.Fatal(err)
 	}
-
-	// 获取发送方地址
-	publicKey := privKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("error casting public key to ECDSA")
+	values, err := parseUintList(amounts, "--amount")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(ids) != len(values) {
+		log.Fatalf("--token-id has %d entries but --amount has %d", len(ids), len(values))
+	}
+	if !batch && len(ids) != 1 {
+		log.Fatalf("%s transfers a single id, use erc1155-batch-transfer for %d ids", mode, len(ids))
 	}
-	fromAddr := crypto.PubkeyToAddress(*publicKeyECDSA)
-
 	contractAddr := common.HexToAddress(contractHex)
 	toAddr := common.HexToAddress(toHex)
+	fromAddr := s.signer.Address()
+	if fromHex != "" {
+		fromAddr = common.HexToAddress(fromHex)
+	}
 
-	// 查询代币的 decimals（精度）
-	decimals, err := getTokenDecimals(ctx, client, parsedABI, contractAddr)
+	var callData []byte
+	title := "ERC-1155 Transfer"
+	if batch {
+		title = "ERC-1155 Batch Transfer"
+		callData, err = mtABI.Pack("safeBatchTransferFrom", fromAddr, toAddr, ids, values, []byte{})
+	} else {
+		callData, err = mtABI.Pack("safeTransferFrom", fromAddr, toAddr, ids[0], values[0], []byte{})
+	}
 	if err != nil {
-		log.Fatalf("failed to get token decimals: %v", err)
+		log.Fatalf("failed to pack transfer data: %v", err)
 	}
 
-	// 解析转账金额
-	// 如果输入包含小数点，则认为是代币数量，需要根据 decimals 转换
-	// 如果输入是整数，则认为是代币的最小单位（类似 wei 的概念）
-	amount, err := parseTokenAmount(amountStr, decimals)
-	if err != nil {
-		log.Fatalf("invalid amount: %v", err)
+	details := [][2]string{
+		{"Owner", fromAddr.Hex()},
+		{"To", toAddr.Hex()},
+		{"Contract", contractAddr.Hex()},
 	}
+	for i, id := range ids {
+		details = append(details, [2]string{fmt.Sprintf("Token %s", id.String()), values[i].String()})
+	}
+	s.send(ctx, contractTx{
+		title:     title,
+		contract:  contractAddr,
+		data:      callData,
+		kind:      policy.KindContractCall,
+		recipient: &toAddr,
+		details:   details,
+	})
+}
 
-	// 获取链 ID
-	chainID, err := client.ChainID(ctx)
-	if err != nil {
-		log.Fatalf("failed to get chain id: %v", err)
+// printNFTTransferEvent 输出 ERC-721 Transfer 事件：三个参数都是 indexed，Data 为空
+func printNFTTransferEvent(index int, vLog *types.Log) {
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("ERC-721 Transfer Event #%d\n", index)
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("Contract Address: %s\n", vLog.Address.Hex())
+	fmt.Printf("Log Index       : %d\n", vLog.Index)
+	fmt.Printf("Transfer(address indexed from, address indexed to, uint256 indexed tokenId)\n")
+	fmt.Printf("与 ERC-20 的 Transfer 签名相同，但 tokenId 存储在 Topics[3] 中，Data 为空\n")
+	from := common.BytesToAddress(vLog.Topics[1].Bytes())
+	switch {
+	case from == (common.Address{}):
+		fmt.Printf("  from   : %s (mint)\n", from.Hex())
+	default:
+		fmt.Printf("  from   : %s (from Topics[1])\n", from.Hex())
 	}
+	fmt.Printf("  to     : %s (from Topics[2])\n", common.BytesToAddress(vLog.Topics[2].Bytes()).Hex())
+	fmt.Printf("  tokenId: %s (from Topics[3])\n", new(big.Int).SetBytes(vLog.Topics[3].Bytes()).String())
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("\n")
+}
 
-	// 获取 nonce
-	nonce, err := client.PendingNonceAt(ctx, fromAddr)
-	if err != nil {
-		log.Fatalf("failed to get nonce: %v", err)
+// findMethod 按方法名或签名查找方法，abis 按顺序查找（--abi 指定的多个文件）。
+// 方法名对应多个重载时返回错误，需要用签名指定，例如 "safeTransferFrom(address,address,uint256)"，
+// 也可以使用 go-ethereum 为重载生成的别名 name0、name1...
+func findMethod(abis []*abi.ABI, nameOrSig string) (abi.Method, error) {
+	nameOrSig = strings.ReplaceAll(strings.TrimSpace(nameOrSig), " ", "")
+	if strings.Contains(nameOrSig, "(") {
+		for _, parsed := range abis {
+			for _, m := range parsed.Methods {
+				if m.Sig == nameOrSig {
+					return m, nil
+				}
+			}
+		}
+		return abi.Method{}, fmt.Errorf("method %q not found in ABI", nameOrSig)
 	}
+	// 重载方法中只有一个保留原名，其余被 go-ethereum 重命名为 f0、f1…，因此按 RawName 收集全部重载，
+	// 同一签名出现在多个 ABI 文件中时只保留第一个
+	var candidates []abi.Method
+	var alias *abi.Method
+	seen := make(map[string]bool)
+	for _, parsed := range abis {
+		for _, m := range parsed.Methods {
+			switch {
+			case m.RawName == nameOrSig:
+				if !seen[m.Sig] {
+					seen[m.Sig] = true
+					candidates = append(candidates, m)
+				}
+			case m.Name == nameOrSig && alias == nil:
+				alias = &m
+			}
+		}
+	}
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(candidates) == 0 && alias != nil:
+		// go-ethereum 生成的别名 f0、f1 精确匹配
+		return *alias, nil
+	case len(candidates) == 0:
+		return abi.Method{}, fmt.Errorf("method %q not found in ABI", nameOrSig)
+	}
+	sigs := make([]string, len(candidates))
+	for i, m := range candidates {
+		sigs[i] = m.Sig
+	}
+	sort.Strings(sigs)
+	return abi.Method{}, fmt.Errorf("method %q is overloaded, use one of the signatures: %s", nameOrSig, strings.Join(sigs, ", "))
+}
 
-	// 编码 transfer 调用数据
-	// transfer(address to, uint256 value)
-	callData, err := parsedABI.Pack("transfer", toAddr, amount)
+// packMethodCall 查找方法并把命令行参数转换为 ABI 类型后编码调用数据；argsJSON 不为空时优先使用 JSON 数组
+func packMethodCall(abis []*abi.ABI, methodName, args, argsJSON string) (abi.Method, []byte, error) {
+	if len(abis) == 0 {
+		return abi.Method{}, nil, fmt.Errorf("missing --abi flag (ABI JSON file or Foundry / Hardhat artifact)")
+	}
+	if methodName == "" {
+		return abi.Method{}, nil, fmt.Errorf("missing --method flag")
+	}
+	method, err := findMethod(abis, methodName)
 	if err != nil {
-		log.Fatalf("failed to pack transfer data: %v", err)
+		return abi.Method{}, nil, err
+	}
+	var params []interface{}
+	if argsJSON != "" {
+		params, err = abiargs.ParseJSON(method.Inputs, []byte(argsJSON))
+	} else {
+		params, err = abiargs.ParseList(method.Inputs, args)
 	}
-
-	// 估算 Gas Limit（合约调用需要更多 Gas）
-	gasLimit, err := client.EstimateGas(ctx, ethereum.CallMsg{
-		From: fromAddr,
-		To:   &contractAddr,
-		Data: callData,
-	})
 	if err != nil {
-		log.Fatalf("failed to estimate gas: %v", err)
+		return abi.Method{}, nil, fmt.Errorf("invalid arguments for %s: %w", method.Sig, err)
 	}
-	// 增加 20% 的缓冲，避免 Gas 不足
-	gasLimit = gasLimit * 120 / 100
-
-	// 获取建议的 Gas 价格（使用 EIP-1559 动态费用）
-	gasTipCap, err := client.SuggestGasTipCap(ctx)
+	packedArgs, err := method.Inputs.Pack(params...)
 	if err != nil {
-		log.Fatalf("failed to get gas tip cap: %v", err)
+		return abi.Method{}, nil, fmt.Errorf("failed to pack %s: %w", method.Sig, err)
 	}
+	return method, append(append([]byte{}, method.ID...), packedArgs...), nil
+}
 
-	// 获取 base fee，计算 fee cap
-	header, err := client.HeaderByNumber(ctx, nil)
+// handleCall 通过 eth_call 调用任意合约方法（不上链），按 ABI 解码返回值并输出名称和类型
+func handleCall(ctx context.Context, client *ethclient.Client, decoder *revert.Decoder, abis []*abi.ABI, contractHex, fromHex, methodName, args, argsJSON, valueStr string) {
+	if contractHex == "" {
+		log.Fatal("missing --contract flag for call mode")
+	}
+	method, callData, err := packMethodCall(abis, methodName, args, argsJSON)
 	if err != nil {
-		log.Fatalf("failed to get header: %v", err)
+		log.Fatal(err)
+	}
+	value, err := parseCallValue(valueStr, method)
+	if err != nil {
+		log.Fatal(err)
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	msg := ethereum.CallMsg{To: &contractAddr, Data: callData, Value: value}
+	if fromHex != "" {
+		msg.From = common.HexToAddress(fromHex)
 	}
 
-	baseFee := header.BaseFee
-	if baseFee == nil {
-		// 如果不支持 EIP-1559，使用传统 gas price
-		gasPrice, err := client.SuggestGasPrice(ctx)
-		if err != nil {
-			log.Fatalf("failed to get gas price: %v", err)
+	fmt.Printf("Contract : %s\n", contractAddr.Hex())
+	fmt.Printf("Method   : %s (selector 0x%x)\n", method.Sig, method.ID)
+	fmt.Printf("Calldata : 0x%x\n", callData)
+	output, err := client.CallContract(ctx, msg, nil)
+	if err != nil {
+		if reason := decoder.FromError(err); reason != nil {
+			log.Fatalf("call reverted: %s", reason)
 		}
-		baseFee = gasPrice
+		log.Fatalf("call failed: %v", err)
+	}
+	if !method.IsConstant() {
+		fmt.Printf("Note     : %s is not a view function, the call only simulates it\n", method.RawName)
 	}
 
-	// fee cap = base fee * 2 + tip cap（简单策略）
-	gasFeeCap := new(big.Int).Add(
-		new(big.Int).Mul(baseFee, big.NewInt(2)),
-		gasTipCap,
-	)
-
-	// 检查 ETH 余额是否足够支付 Gas 费用
-	balance, err := client.BalanceAt(ctx, fromAddr, nil)
+	if len(method.Outputs) == 0 {
+		fmt.Printf("Returns  : (none)\n")
+		return
+	}
+	values, err := method.Outputs.Unpack(output)
 	if err != nil {
-		log.Fatalf("failed to get balance: %v", err)
+		log.Fatalf("failed to decode return data 0x%x: %v", output, err)
 	}
-
-	// 计算总费用：gasFeeCap * gasLimit（ERC-20 转账不需要发送 ETH，只需要支付 Gas）
-	totalGasCost := new(big.Int).Mul(gasFeeCap, big.NewInt(int64(gasLimit)))
-
-	if balance.Cmp(totalGasCost) < 0 {
-		log.Fatalf("insufficient ETH balance for gas: have %s wei, need %s wei", balance.String(), totalGasCost.String())
+	fmt.Printf("Returns  :\n")
+	for i, out := range method.Outputs {
+		name := out.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		fmt.Printf("  %s (%s): %s\n", name, out.Type.String(), abifmt.Format(out.Type, values[i]))
 	}
+}
 
-	// 构造交易（EIP-1559 动态费用交易）
-	// 注意：ERC-20 transfer 的 value 为 0，调用数据在 Data 字段中
-	txData := &types.DynamicFeeTx{
-		ChainID:   chainID,
-		Nonce:     nonce,
-		GasTipCap: gasTipCap,
-		GasFeeCap: gasFeeCap,
-		Gas:       gasLimit,
-		To:        &contractAddr, // 合约地址
-		Value:     big.NewInt(0), // ERC-20 转账不需要发送 ETH
-		Data:      callData,      // transfer 调用数据
+// handleSend 发送任意合约方法的交易，复用预执行、交易策略和确认流程
+func handleSend(ctx context.Context, s *sender, abis []*abi.ABI, contractHex, methodName, args, argsJSON, valueStr string) {
+	if contractHex == "" {
+		log.Fatal("missing --contract flag for send mode")
 	}
-	tx := types.NewTx(txData)
-
-	// 签名交易
-	signer := types.NewLondonSigner(chainID)
-	signedTx, err := types.SignTx(tx, signer, privKey)
+	method, callData, err := packMethodCall(abis, methodName, args, argsJSON)
 	if err != nil {
-		log.Fatalf("failed to sign transaction: %v", err)
+		log.Fatal(err)
 	}
-
-	// 发送交易
-	if err := client.SendTransaction(ctx, signedTx); err != nil {
-		log.Fatalf("failed to send transaction: %v", err)
+	value, err := parseCallValue(valueStr, method)
+	if err != nil {
+		log.Fatal(err)
 	}
+	if method.IsConstant() {
+		fmt.Printf("Warning: %s is a view function, use --mode call to read it without a transaction\n", method.Sig)
+	}
+	contractAddr := common.HexToAddress(contractHex)
+	details := [][2]string{
+		{"Contract", contractAddr.Hex()},
+		{"Method", method.Sig},
+	}
+	if value.Sign() > 0 {
+		details = append(details, [2]string{"Value", units.FormatEther(value) + " ETH"})
+	}
+	s.send(ctx, contractTx{
+		title:    "Contract Call",
+		contract: contractAddr,
+		data:     callData,
+		value:    value,
+		kind:     policy.KindContractCall,
+		details:  details,
+	})
+}
 
-	// 输出交易信息
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("ERC-20 Transfer Transaction Sent\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("From          : %s\n", fromAddr.Hex())
-	fmt.Printf("To            : %s\n", toAddr.Hex())
-	fmt.Printf("Contract      : %s\n", contractAddr.Hex())
-	fmt.Printf("Token Decimals: %d\n", decimals)
-	// 显示代币数量（根据 decimals 转换）
-	tokenAmount := formatTokenAmount(amount, decimals)
-	fmt.Printf("Amount        : %s tokens (%s raw units)\n", tokenAmount, amount.String())
-	fmt.Printf("Gas Limit     : %d\n", gasLimit)
-	fmt.Printf("Gas Tip Cap   : %s Wei\n", gasTipCap.String())
-	fmt.Printf("Gas Fee Cap   : %s Wei\n", gasFeeCap.String())
-	fmt.Printf("Estimated Cost: %s Wei\n", totalGasCost.String())
-	fmt.Printf("Nonce         : %d\n", nonce)
-	fmt.Printf("Tx Hash       : %s\n", signedTx.Hash().Hex())
-	fmt.Printf("\n")
-	fmt.Printf("Transaction is pending. Waiting for confirmation...\n")
-	fmt.Printf("\n")
-
-	// 等待交易确认
-	waitForTransaction(ctx, client, signedTx.Hash())
+// parseCallValue 解析 --value（默认单位 ether，支持 gwei / wei 后缀），只有 payable 方法可以携带 ETH
+func parseCallValue(valueStr string, method abi.Method) (*big.Int, error) {
+	if valueStr == "" {
+		return big.NewInt(0), nil
+	}
+	value, err := units.ParseEther(valueStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --value: %w", err)
+	}
+	if value.Sign() > 0 && !method.IsPayable() {
+		return nil, fmt.Errorf("%s is not payable, --value must be empty", method.Sig)
+	}
+	return value, nil
 }
 
-// waitForTransaction 等待交易确认并显示回执信息
-func waitForTransaction(ctx context.Context, client *ethclient.Client, txHash common.Hash) {
-	// 设置超时上下文（最多等待 2 分钟）
-	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+// waitForTransaction 等待交易达到指定确认深度（或 safe / finalized）并显示回执信息，等待期间会检测链重组
+func waitForTransaction(client *ethclient.Client, decoder *revert.Decoder, tx *types.Transaction, target txwait.Target, timeout time.Duration) {
+	txHash := tx.Hash()
+	waitCtx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	ticker := time.NewTicker(3 * time.Second)
-	defer ticker.Stop()
-
-	fmt.Printf("Polling for transaction receipt...\n")
-	for {
-		select {
-		case <-waitCtx.Done():
-			fmt.Printf("\nTimeout waiting for transaction confirmation.\n")
-			fmt.Printf("You can check the transaction status later:\n")
-			fmt.Printf("  go run main.go --mode parse-event --tx %s\n", txHash.Hex())
-			return
+	result, err := txwait.Wait(waitCtx, client, txHash, txwait.Options{Target: target, Out: os.Stdout})
+	if err != nil {
+		fmt.Printf("\nStopped waiting for transaction confirmation: %v\n", err)
+		fmt.Printf("You can check the transaction status later:\n")
+		fmt.Printf("  go run main.go --mode parse-event --tx %s\n", txHash.Hex())
+		return
+	}
+	receipt := result.Receipt
 
-		case <-ticker.C:
-			receipt, err := client.TransactionReceipt(waitCtx, txHash)
-			if err != nil {
-				// 交易可能还在 pending
-				continue
-			}
+	// 交易已确认
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("Transaction Confirmed!\n")
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("Status       : %d (1=success, 0=failed)\n", receipt.Status)
+	fmt.Printf("Block Number : %d\n", receipt.BlockNumber.Uint64())
+	fmt.Printf("Block Hash   : %s\n", receipt.BlockHash.Hex())
+	fmt.Printf("Confirmations: %d (target: %s)\n", result.Confirmations, target)
+	if result.Reorgs > 0 {
+		fmt.Printf("Reorgs       : %d\n", result.Reorgs)
+	}
+	fmt.Printf("Gas Used     : %d / %d (%.1f%% of limit)\n", receipt.GasUsed, tx.Gas(), float64(receipt.GasUsed)*100/float64(tx.Gas()))
+	fmt.Printf("Logs Count   : %d\n", len(receipt.Logs))
 
-			// 交易已确认
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-			fmt.Printf("Transaction Confirmed!\n")
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-			fmt.Printf("Status       : %d (1=success, 0=failed)\n", receipt.Status)
-			fmt.Printf("Block Number : %d\n", receipt.BlockNumber.Uint64())
-			fmt.Printf("Block Hash   : %s\n", receipt.BlockHash.Hex())
-			fmt.Printf("Gas Used     : %d / %d\n", receipt.GasUsed, receipt.GasUsed)
-			fmt.Printf("Logs Count   : %d\n", len(receipt.Logs))
-
-			if receipt.Status == 0 {
-				fmt.Printf("\n⚠️  Transaction failed! Check the transaction on block explorer.\n")
-			} else {
-				fmt.Printf("\n✅ Transaction successful!\n")
-				if len(receipt.Logs) > 0 {
-					fmt.Printf("\nTo parse Transfer event from this transaction:\n")
-					fmt.Printf("  go run main.go --mode parse-event --tx %s\n", txHash.Hex())
-				}
-			}
-			fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-			return
+	if receipt.Status == 0 {
+		fmt.Printf("\n⚠️  Transaction failed!\n")
+		// 回执中不包含回滚原因，在父区块上重放交易还原
+		replayCtx, replayCancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer replayCancel()
+		printRevertReason(replayCtx, client, decoder, tx, receipt)
+	} else {
+		fmt.Printf("\n✅ Transaction successful!\n")
+		if len(receipt.Logs) > 0 {
+			fmt.Printf("\nTo parse Transfer event from this transaction:\n")
+			fmt.Printf("  go run main.go --mode parse-event --tx %s\n", txHash.Hex())
 		}
 	}
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 }
 
-// trim0x 移除十六进制字符串前缀 "0x"
-func trim0x(s string) string {
-	if len(s) >= 2 && s[0:2] == "0x" {
-		return s[2:]
+// printRevertReason 在父区块状态上重放失败交易，输出解码后的回滚原因
+func printRevertReason(ctx context.Context, client *ethclient.Client, decoder *revert.Decoder, tx *types.Transaction, receipt *types.Receipt) {
+	reason, err := decoder.Replay(ctx, client, tx, receipt.BlockNumber)
+	if err != nil {
+		fmt.Printf("Revert Reason: unavailable (%v)\n", err)
+		return
 	}
-	return s
+	fmt.Printf("Revert Kind  : %s\n", reason.Kind)
+	fmt.Printf("Revert Reason: %s\n", reason)
 }
 
 // getTokenDecimals 查询 ERC-20 代币的 decimals（精度）
@@ -432,54 +1769,156 @@ func getTokenDecimals(ctx context.Context, client *ethclient.Client, parsedABI a
 	return decimals, nil
 }
 
-// parseTokenAmount 解析代币数量字符串
-// 如果输入包含小数点（如 "1.5"），则认为是代币数量，需要根据 decimals 转换为最小单位
+// parseTokenAmount 解析代币数量字符串（精确换算，不经过浮点数）
+// 如果输入包含小数点（如 "1.5"），则认为是代币数量，需要根据 decimals 转换为最小单位，小数位数超过 decimals 时报错
 // 如果输入是整数（如 "1500000000000000000"），则认为是代币的最小单位（类似 wei 的概念）
 func parseTokenAmount(amountStr string, decimals uint8) (*big.Int, error) {
-	// 检查是否包含小数点
 	if strings.Contains(amountStr, ".") {
-		// 解析为浮点数
-		amountFloat, err := strconv.ParseFloat(amountStr, 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid decimal amount: %w", err)
-		}
+		return units.Parse(amountStr, int(decimals))
+	}
+	// 直接解析为整数（代币的最小单位）
+	return units.Parse(amountStr, units.WeiDecimals)
+}
 
-		// 转换为 big.Float 进行精确计算
-		bigFloat := big.NewFloat(amountFloat)
+// formatTokenAmount 将代币的最小单位转换为可读的代币数量
+func formatTokenAmount(amount *big.Int, decimals uint8) string {
+	return units.Format(amount, int(decimals))
+}
 
-		// 乘以 10^decimals
-		multiplier := new(big.Float).SetFloat64(math.Pow10(int(decimals)))
-		bigFloat.Mul(bigFloat, multiplier)
+// signedMessage 待签名 / 验证的链下消息
+type signedMessage struct {
+	kind      string // 消息类型描述
+	hash      common.Hash
+	typedData *apitypes.TypedData // EIP-712 消息，personal_sign 时为 nil
+	text      []byte              // personal_sign 消息
+}
 
-		// 转换为 big.Int（截断小数部分）
-		amount, _ := bigFloat.Int(nil)
-		return amount, nil
-	} else {
-		// 直接解析为整数（代币的最小单位）
-		amount, ok := new(big.Int).SetString(amountStr, 10)
-		if !ok {
-			return nil, fmt.Errorf("invalid integer amount: %s", amountStr)
+// loadMessage 根据 --message / --typed-data 构造消息并计算签名哈希，两者必须且只能指定一个
+func loadMessage(message, typedDataFile string) (*signedMessage, error) {
+	if (message == "") == (typedDataFile == "") {
+		return nil, fmt.Errorf("specify exactly one of --message or --typed-data")
+	}
+	if message != "" {
+		text := []byte(message)
+		return &signedMessage{kind: "EIP-191 personal_sign", hash: msgsig.TextHash(text), text: text}, nil
+	}
+	data, err := msgsig.LoadTypedData(typedDataFile)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := msgsig.TypedDataHash(data)
+	if err != nil {
+		return nil, err
+	}
+	return &signedMessage{kind: "EIP-712 typed data", hash: hash, typedData: &data}, nil
+}
+
+// print 输出消息摘要；EIP-712 额外显示 domain 和 primaryType，便于确认签的是哪个合约、哪条链上的什么数据
+func (m *signedMessage) print() {
+	fmt.Printf("Type         : %s\n", m.kind)
+	if m.typedData != nil {
+		domain := m.typedData.Domain
+		fmt.Printf("Domain       : %s (version %s)\n", domain.Name, domain.Version)
+		if domain.ChainId != nil {
+			fmt.Printf("Chain ID     : %s\n", (*big.Int)(domain.ChainId).String())
 		}
-		return amount, nil
+		if domain.VerifyingContract != "" {
+			fmt.Printf("Verifying    : %s\n", domain.VerifyingContract)
+		}
+		fmt.Printf("Primary Type : %s\n", m.typedData.PrimaryType)
+	} else {
+		fmt.Printf("Message      : %q\n", m.text)
 	}
+	fmt.Printf("Hash         : %s\n", m.hash.Hex())
 }
 
-// formatTokenAmount 将代币的最小单位转换为可读的代币数量
-func formatTokenAmount(amount *big.Int, decimals uint8) string {
-	// 转换为 big.Float
-	amountFloat := new(big.Float).SetInt(amount)
+// handleSignMessage 对链下消息签名（EIP-191 或 EIP-712），使用与发送交易相同的签名器
+func handleSignMessage(txSigner signer.Signer, message, typedDataFile string) {
+	msg, err := loadMessage(message, typedDataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var sig []byte
+	if msg.typedData != nil {
+		sig, err = txSigner.SignTypedData(*msg.typedData)
+	} else {
+		sig, err = txSigner.SignText(msg.text)
+	}
+	if err != nil {
+		log.Fatalf("failed to sign message: %v", err)
+	}
+
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("Message Signed\n")
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	msg.print()
+	fmt.Printf("Signer       : %s (%s)\n", txSigner.Address().Hex(), txSigner.Kind())
+	fmt.Printf("Signature    : %s\n", hexutil.Encode(sig))
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+}
+
+// handleVerifyMessage 验证链下消息签名：EOA 用 ecrecover，设置了 ETH_RPC_URL 且签名者是合约时调用 EIP-1271
+func handleVerifyMessage(rpcURL, addrHex, message, typedDataFile, signatureHex string) {
+	if addrHex == "" || signatureHex == "" {
+		log.Fatal("missing --address or --signature flag for verify-message mode")
+	}
+	if !common.IsHexAddress(addrHex) {
+		log.Fatalf("invalid address: %s", addrHex)
+	}
+	claimed := common.HexToAddress(addrHex)
+	msg, err := loadMessage(message, typedDataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sig, err := msgsig.ParseSignature(signatureHex)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	// 未设置节点时只能验证 EOA 签名
+	var verifier msgsig.Client
+	if rpcURL != "" {
+		client, err := ethclient.DialContext(ctx, rpcURL)
+		if err != nil {
+			log.Fatalf("failed to connect to Ethereum node: %v", err)
+		}
+		defer client.Close()
+		verifier = client
+	}
 
-	// 除以 10^decimals
-	divisor := new(big.Float).SetFloat64(math.Pow10(int(decimals)))
-	amountFloat.Quo(amountFloat, divisor)
+	result, err := msgsig.Verify(ctx, verifier, claimed, msg.hash, sig)
+	if err != nil {
+		log.Fatalf("failed to verify signature: %v", err)
+	}
 
-	// 格式化为字符串，保留足够的小数位
-	return amountFloat.Text('f', int(decimals))
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Printf("Signature Verification\n")
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	msg.print()
+	fmt.Printf("Signer       : %s\n", claimed.Hex())
+	fmt.Printf("Method       : %s\n", result.Method)
+	if result.Recovered != (common.Address{}) {
+		fmt.Printf("Recovered    : %s\n", result.Recovered.Hex())
+	}
+	if rpcURL == "" {
+		fmt.Printf("Note         : ETH_RPC_URL is not set, contract wallet (EIP-1271) signatures cannot be checked\n")
+	}
+	if !result.Valid {
+		fmt.Printf("\n❌ Invalid signature: %s\n", result.Reason)
+		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		os.Exit(1)
+	}
+	fmt.Printf("\n✅ Valid signature\n")
+	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 }
 
 // handleParseEvent 从交易回执中解析 Transfer 事件
 // 详细展示 indexed 参数（存储在 Topics 中）和 non-indexed 参数（存储在 Data 中）的对应关系
-func handleParseEvent(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, txHashHex string) {
+func handleParseEvent(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, decoder *revert.Decoder, txHashHex string) {
 	if txHashHex == "" {
 		log.Fatal("missing --tx flag for parse-event mode")
 	}
@@ -500,6 +1939,11 @@ func handleParseEvent(ctx context.Context, client *ethclient.Client, parsedABI a
 	fmt.Printf("Status       : %d (1=success, 0=failed)\n", receipt.Status)
 	fmt.Printf("Gas Used     : %d\n", receipt.GasUsed)
 	fmt.Printf("Logs Count   : %d\n", len(receipt.Logs))
+	if receipt.Status == types.ReceiptStatusFailed {
+		if tx, _, err := client.TransactionByHash(ctx, txHash); err == nil {
+			printRevertReason(ctx, client, decoder, tx, receipt)
+		}
+	}
 	fmt.Printf("\n")
 
 	// 查找 Transfer 事件
@@ -512,6 +1956,12 @@ func handleParseEvent(ctx context.Context, client *ethclient.Client, parsedABI a
 		if len(vLog.Topics) == 0 || vLog.Topics[0] != transferEventSigHash {
 			continue
 		}
+		// ERC-721 的 Transfer 签名相同，但 tokenId 是 indexed 参数，共 4 个 topics
+		if len(vLog.Topics) == 4 {
+			foundTransfer = true
+			printNFTTransferEvent(i+1, vLog)
+			continue
+		}
 
 		foundTransfer = true
 		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
@@ -641,8 +2091,42 @@ func handleParseEvent(ctx context.Context, client *ethclient.Client, parsedABI a
 		fmt.Printf("\n")
 	}
 
-	if !foundTransfer {
-		fmt.Printf("No Transfer event found in this transaction.\n")
+	// 查找 Approval 事件：Approval(address indexed owner, address indexed spender, uint256 value)
+	// 存储结构与 Transfer 相同，这里只输出解析结果
+	approvalEvent := parsedABI.Events["Approval"]
+	foundApproval := false
+	for i, vLog := range receipt.Logs {
+		// ERC-721 的 Approval 有 4 个 topics（tokenId 也是 indexed），不按 ERC-20 解析
+		if len(vLog.Topics) != 3 || vLog.Topics[0] != approvalEvent.ID {
+			continue
+		}
+		values, err := parsedABI.Unpack("Approval", vLog.Data)
+		if err != nil || len(values) == 0 {
+			continue
+		}
+		value, ok := values[0].(*big.Int)
+		if !ok {
+			continue
+		}
+
+		foundApproval = true
+		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		fmt.Printf("Approval Event #%d\n", i+1)
+		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		fmt.Printf("Contract Address: %s\n", vLog.Address.Hex())
+		fmt.Printf("Log Index       : %d\n", vLog.Index)
+		fmt.Printf("  owner  : %s (from Topics[1])\n", common.BytesToAddress(vLog.Topics[1].Bytes()).Hex())
+		fmt.Printf("  spender: %s (from Topics[2])\n", common.BytesToAddress(vLog.Topics[2].Bytes()).Hex())
+		fmt.Printf("  value  : %s (from Data)\n", value.String())
+		if decimals, err := getTokenDecimals(ctx, client, parsedABI, vLog.Address); err == nil {
+			fmt.Printf("  amount : %s (decimals %d)\n", formatAllowance(value, decimals), decimals)
+		}
+		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		fmt.Printf("\n")
+	}
+
+	if !foundTransfer && !foundApproval {
+		fmt.Printf("No Transfer or Approval event found in this transaction.\n")
 		fmt.Printf("Total logs: %d\n", len(receipt.Logs))
 	}
 }
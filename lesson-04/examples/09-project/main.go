@@ -5,26 +5,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math/big"
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/abifmt"
+	"ethutil/logfilter"
+	"ethutil/logwatch"
+	"ethutil/subscribe"
+	"ethutil/watchcfg"
 )
 
 // 一个最小可运行的"迷你区块浏览器 / ERC-20 监听服务"示例：
 // - 后台 goroutine 订阅指定 ERC-20 合约的 Transfer 事件
 // - 将最近 N 条事件缓存在内存中
 // - 通过 HTTP 接口 GET /events 返回最近事件列表
+// - 可选环境变量 TRANSFER_FILTER 按 indexed 参数过滤（编码到 Topics，由节点过滤），语法与 06 的 -where 相同：
+//   TRANSFER_FILTER="to=0xA...,0xB..." 只监听转入这些地址的转账，"to=@addresses.txt" 从文件读取地址列表
+// - 设置 WATCH_CONFIG 时改为按配置文件（格式与 06 的 -config 相同）监听多个合约，每个合约有自己的 ABI、事件子集和标签，
+//   事件记录带上标签；收到 SIGHUP 时重新加载配置，增删合约不需要重启
+// - 链重组时节点会重新推送被移除区块中的日志（Removed = true），对应的事件按 (tx hash, log index) 标记为 retracted，
+//   HTTP 接口中的 "retracted": true 表示该事件已经不在规范链上，下游应当撤销据此做出的处理
 
 const erc20ABIJSON = `[
   {
@@ -39,29 +50,38 @@ const erc20ABIJSON = `[
   }
 ]`
 
-type TransferEvent struct {
-	BlockNumber uint64    `json:"block_number"`
-	TxHash      string    `json:"tx_hash"`
-	From        string    `json:"from"`
-	To          string    `json:"to"`
-	Value       string    `json:"value"` // 原始 uint256 字符串
-	Timestamp   time.Time `json:"timestamp"`
+// LogEvent 一条解码后的事件；From / To / Value 在事件有同名参数时填写（如 Transfer），全部参数在 Args 中
+type LogEvent struct {
+	Label       string            `json:"label"`
+	Contract    string            `json:"contract"`
+	Event       string            `json:"event"`
+	BlockNumber uint64            `json:"block_number"`
+	BlockHash   string            `json:"block_hash"`
+	TxHash      string            `json:"tx_hash"`
+	LogIndex    uint              `json:"log_index"`
+	From        string            `json:"from,omitempty"`
+	To          string            `json:"to,omitempty"`
+	Value       string            `json:"value,omitempty"` // 原始 uint256 字符串
+	Args        map[string]string `json:"args,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Retracted   bool              `json:"retracted"`              // 所在区块被链重组移除
+	RetractedAt *time.Time        `json:"retracted_at,omitempty"` // 收到移除通知的时间
 }
 
 type EventStore struct {
 	mu     sync.RWMutex
-	events []TransferEvent
+	events []LogEvent
 	limit  int
 }
 
 func NewEventStore(limit int) *EventStore {
 	return &EventStore{
-		events: make([]TransferEvent, 0, limit),
+		events: make([]LogEvent, 0, limit),
 		limit:  limit,
 	}
 }
 
-func (s *EventStore) Add(e TransferEvent) {
+func (s *EventStore) Add(e LogEvent) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if len(s.events) >= s.limit {
@@ -71,10 +91,27 @@ func (s *EventStore) Add(e TransferEvent) {
 	s.events = append(s.events, e)
 }
 
-func (s *EventStore) List() []TransferEvent {
+// Retract 把 (txHash, logIndex) 对应的事件标记为已撤销，返回是否找到。
+// 同一笔交易重组后可能被打包进新区块，日志索引也可能相同，所以还要比较区块哈希，避免撤销重新打包后的事件
+func (s *EventStore) Retract(txHash string, logIndex uint, blockHash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.events {
+		e := &s.events[i]
+		if e.TxHash != txHash || e.LogIndex != logIndex || e.BlockHash != blockHash || e.Retracted {
+			continue
+		}
+		now := time.Now()
+		e.Retracted, e.RetractedAt = true, &now
+		return true
+	}
+	return false
+}
+
+func (s *EventStore) List() []LogEvent {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	out := make([]TransferEvent, len(s.events))
+	out := make([]LogEvent, len(s.events))
 	copy(out, s.events)
 	return out
 }
@@ -88,11 +125,11 @@ func main() {
 		log.Fatal("ETH_WS_URL or ETH_RPC_URL must be set")
 	}
 
+	configPath := os.Getenv("WATCH_CONFIG")
 	contractHex := os.Getenv("ERC20_CONTRACT")
-	if contractHex == "" {
-		log.Fatal("ERC20_CONTRACT env is not set")
+	if configPath == "" && contractHex == "" {
+		log.Fatal("ERC20_CONTRACT or WATCH_CONFIG env must be set")
 	}
-	contractAddr := common.HexToAddress(contractHex)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -108,10 +145,47 @@ func main() {
 		log.Fatalf("failed to parse ABI: %v", err)
 	}
 
+	// 监听配置：WATCH_CONFIG 指定的配置文件，或者只有 ERC20_CONTRACT 一个合约的 Transfer 事件
+	var watch *watchcfg.Watch
+	if configPath != "" {
+		watch, err = watchcfg.Load(configPath)
+	} else {
+		watch, err = watchcfg.New(watchcfg.Config{Contracts: []watchcfg.ContractConfig{
+			{Label: "ERC20", Address: contractHex, Events: []string{"Transfer"}},
+		}}, ".")
+	}
+	if err != nil {
+		log.Fatalf("failed to load watch config: %v", err)
+	}
+	query := watch.Query()
+	conds, err := logfilter.Parse(os.Getenv("TRANSFER_FILTER"))
+	if err != nil {
+		log.Fatalf("invalid TRANSFER_FILTER: %v", err)
+	}
+	if len(conds) > 0 {
+		if configPath != "" {
+			log.Fatal("TRANSFER_FILTER cannot be combined with WATCH_CONFIG")
+		}
+		query.Topics, err = logfilter.Topics(parsedABI.Events["Transfer"], conds)
+		if err != nil {
+			log.Fatalf("invalid TRANSFER_FILTER: %v", err)
+		}
+	}
+
 	store := NewEventStore(100)
 
-	// 启动后台订阅协程
-	go subscribeTransferEvents(ctx, client, parsedABI, contractAddr, store)
+	// 启动后台订阅：当前配置通过原子指针读取，重新加载后立即生效
+	var current atomic.Pointer[watchcfg.Watch]
+	current.Store(watch)
+	for _, c := range watch.Contracts {
+		log.Printf("listening events of %s (%s)", c.Label, c.Address.Hex())
+	}
+	// 只配置了 ETH_RPC_URL（HTTP）时，subscribe 自动退回过滤器 / eth_getLogs 轮询
+	subscriber := subscribe.New(client)
+	subscriber.Out = log.Writer()
+	stream := logwatch.New(subscriber).Start(ctx, query, func(vLog types.Log) {
+		handleLog(&vLog, &current, store)
+	})
 
 	// HTTP 接口
 	mux := http.NewServeMux()
@@ -135,11 +209,28 @@ func main() {
 		}
 	}()
 
-	// 优雅退出
+	// 优雅退出；配置文件模式下 SIGHUP 重新加载配置
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-sigCh
-	fmt.Printf("received signal %s, shutting down...\n", sig.String())
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+wait:
+	for {
+		select {
+		case err := <-stream.Err():
+			log.Printf("subscription error: %v", err)
+			break wait
+		case sig := <-sigCh:
+			if sig != syscall.SIGHUP {
+				fmt.Printf("received signal %s, shutting down...\n", sig.String())
+				break wait
+			}
+			if configPath == "" {
+				log.Printf("received SIGHUP but WATCH_CONFIG is not set, ignoring")
+				continue
+			}
+			reloadConfig(configPath, &current, stream)
+		}
+	}
+	stream.Stop()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
@@ -147,59 +238,77 @@ func main() {
 	cancel()
 }
 
-func subscribeTransferEvents(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contract common.Address, store *EventStore) {
-	query := ethereum.FilterQuery{
-		Addresses: []common.Address{contract},
+// reloadConfig 重新加载配置；加载失败时继续使用原配置，监听的合约或事件变化时切换订阅
+func reloadConfig(path string, current *atomic.Pointer[watchcfg.Watch], stream *logwatch.Stream) {
+	next, err := watchcfg.Load(path)
+	if err != nil {
+		log.Printf("failed to reload watch config, keeping the previous one: %v", err)
+		return
+	}
+	prev := current.Swap(next)
+	log.Printf("reloaded %s: %d contract(s)", path, len(next.Contracts))
+	nextQuery, prevQuery := next.Query(), prev.Query()
+	if reflect.DeepEqual(nextQuery.Addresses, prevQuery.Addresses) && reflect.DeepEqual(nextQuery.Topics, prevQuery.Topics) {
+		return
 	}
+	if err := stream.Update(nextQuery); err != nil {
+		log.Printf("failed to resubscribe with the new config: %v", err)
+	}
+}
 
-	logsCh := make(chan types.Log)
-	sub, err := client.SubscribeFilterLogs(ctx, query, logsCh)
+// handleLog 按日志的合约地址找到配置中的合约，用它的注册表解码后存入 store
+func handleLog(vLog *types.Log, current *atomic.Pointer[watchcfg.Watch], store *EventStore) {
+	// 没有 Topics 的日志（LOG0 / 匿名事件）无法按签名识别；没有指定事件子集的合约会收到这类日志
+	if len(vLog.Topics) == 0 {
+		return
+	}
+	c := current.Load().Contract(vLog.Address)
+	if c == nil || !c.Wants(vLog) {
+		return
+	}
+	// 链重组：之前收到的日志所在区块不再是规范区块，撤销对应的事件
+	if vLog.Removed {
+		if store.Retract(vLog.TxHash.Hex(), vLog.Index, vLog.BlockHash.Hex()) {
+			log.Printf("retracted event of %s: tx %s log %d, block %d was removed by a chain reorganization",
+				c.Label, vLog.TxHash.Hex(), vLog.Index, vLog.BlockNumber)
+		} else {
+			log.Printf("removed log of %s not in store: tx %s log %d", c.Label, vLog.TxHash.Hex(), vLog.Index)
+		}
+		return
+	}
+	entry, ok := c.Registry.Lookup(vLog)
+	if !ok {
+		log.Printf("unknown event of %s in tx %s, topic0 %s", c.Label, vLog.TxHash.Hex(), vLog.Topics[0].Hex())
+		return
+	}
+	// 解码事件：indexed 参数从 Topics 解析，非 indexed 参数从 Data 解码
+	args, err := entry.Decode(vLog)
 	if err != nil {
-		log.Fatalf("failed to subscribe logs: %v", err)
+		log.Printf("failed to decode log: %v", err)
+		return
 	}
-
-	log.Printf("listening Transfer events of %s", contract.Hex())
-
-	for {
-		select {
-		case vLog := <-logsCh:
-			if len(vLog.Topics) == 0 {
-				continue
-			}
-
-			// 解码事件
-			var event struct {
-				From  common.Address
-				To    common.Address
-				Value *big.Int
-			}
-
-			// 非 indexed 参数从 Data 解码
-			if err := parsedABI.UnpackIntoInterface(&event, "Transfer", vLog.Data); err != nil {
-				log.Printf("failed to unpack log data: %v", err)
-				continue
-			}
-			// indexed 地址从 Topics[1], Topics[2]
-			if len(vLog.Topics) >= 3 {
-				event.From = common.BytesToAddress(vLog.Topics[1].Bytes())
-				event.To = common.BytesToAddress(vLog.Topics[2].Bytes())
-			}
-
-			store.Add(TransferEvent{
-				BlockNumber: vLog.BlockNumber,
-				TxHash:      vLog.TxHash.Hex(),
-				From:        event.From.Hex(),
-				To:          event.To.Hex(),
-				Value:       event.Value.String(),
-				Timestamp:   time.Now(), // 简化：使用当前时间；可扩展为查询区块时间
-			})
-		case err := <-sub.Err():
-			log.Printf("subscription error: %v", err)
-			return
-		case <-ctx.Done():
-			log.Println("context cancelled, stop subscription")
-			return
+	e := LogEvent{
+		Label:       c.Label,
+		Contract:    vLog.Address.Hex(),
+		Event:       entry.Event.Name,
+		BlockNumber: vLog.BlockNumber,
+		BlockHash:   vLog.BlockHash.Hex(),
+		TxHash:      vLog.TxHash.Hex(),
+		LogIndex:    vLog.Index,
+		Args:        make(map[string]string, len(args)),
+		Timestamp:   time.Now(), // 简化：使用当前时间；可扩展为查询区块时间
+	}
+	for _, arg := range args {
+		value := abifmt.Format(arg.Type, arg.Value)
+		e.Args[arg.Name] = value
+		switch arg.Name {
+		case "from":
+			e.From = value
+		case "to":
+			e.To = value
+		case "value":
+			e.Value = value
 		}
 	}
+	store.Add(e)
 }
-
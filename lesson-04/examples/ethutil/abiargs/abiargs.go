@@ -0,0 +1,354 @@
+// Package abiargs 按 ABI 参数类型把命令行字符串转换为 abi.Pack 需要的 Go 值，用于构造函数参数和通用合约调用。
+//
+// 各类型的写法：
+//
+//	address          0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb
+//	bool             true / false
+//	int / uint       100、-5、0xff（intN / uintN 按位数检查范围）
+//	string           hello（包含逗号或括号时用双引号："a, b"）
+//	bytes / bytesN   0x 开头的十六进制
+//	T[] / T[N]       [1,2,3]
+//	tuple            (0x742d...,100) 或 [0x742d...,100]，或按字段名的 JSON 对象 {"to":"0x742d...","amount":"100"}
+//
+// 多个参数之间用逗号分隔（SplitList），方括号、圆括号、花括号和双引号内的逗号不会被拆分。
+// 也可以用 ParseJSON 把全部参数写成一个 JSON 数组。
+package abiargs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// SplitList 拆分逗号分隔的参数列表，忽略括号和双引号内的逗号；空字符串返回空列表
+func SplitList(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	var (
+		parts   []string
+		depth   int
+		quoted  bool
+		current strings.Builder
+	)
+	for _, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '(' || c == '{':
+			depth++
+		case c == ']' || c == ')' || c == '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced brackets in %q", s)
+			}
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(c)
+	}
+	if quoted || depth != 0 {
+		return nil, fmt.Errorf("unbalanced brackets or quotes in %q", s)
+	}
+	return append(parts, strings.TrimSpace(current.String())), nil
+}
+
+// Parse 按参数列表的类型逐个转换字符串，数量必须一致
+func Parse(args abi.Arguments, values []string) ([]interface{}, error) {
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("expected %d argument(s) %s, got %d", len(args), signature(args), len(values))
+	}
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := ParseValue(arg.Type, values[i])
+		if err != nil {
+			name := arg.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			return nil, fmt.Errorf("argument %s (%s): %w", name, arg.Type.String(), err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// ParseList 等价于 Parse(args, SplitList(s))
+func ParseList(args abi.Arguments, s string) ([]interface{}, error) {
+	values, err := SplitList(s)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(args, values)
+}
+
+// ParseValue 把单个字符串转换为类型 t 对应的 Go 值
+func ParseValue(t abi.Type, s string) (interface{}, error) {
+	v, err := parseReflect(t, strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// parseReflect 返回的值类型与 t.GetType() 一致，便于嵌套到数组和结构体中
+func parseReflect(t abi.Type, s string) (reflect.Value, error) {
+	switch t.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(s) {
+			return reflect.Value{}, fmt.Errorf("invalid address %q", s)
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+
+	case abi.BoolTy:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid bool %q", s)
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.StringTy:
+		return reflect.ValueOf(unquote(s)), nil
+
+	case abi.BytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid bytes %q: %v", s, err)
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid bytes%d %q: %v", t.Size, s, err)
+		}
+		if len(b) != t.Size {
+			return reflect.Value{}, fmt.Errorf("expected %d bytes, got %d", t.Size, len(b))
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v, nil
+
+	case abi.IntTy, abi.UintTy:
+		return parseInt(t, s)
+
+	case abi.SliceTy, abi.ArrayTy:
+		items, err := splitGroup(s, '[', ']')
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if t.T == abi.ArrayTy && len(items) != t.Size {
+			return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", t.Size, len(items))
+		}
+		var v reflect.Value
+		if t.T == abi.ArrayTy {
+			v = reflect.New(t.GetType()).Elem()
+		} else {
+			v = reflect.MakeSlice(t.GetType(), len(items), len(items))
+		}
+		for i, item := range items {
+			elem, err := parseReflect(*t.Elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
+
+	case abi.TupleTy:
+		if strings.HasPrefix(s, "{") {
+			var obj interface{}
+			if err := decodeJSON([]byte(s), &obj); err != nil {
+				return reflect.Value{}, fmt.Errorf("invalid tuple JSON %q: %v", s, err)
+			}
+			return fromJSON(t, obj)
+		}
+		open, closing := '(', ')'
+		if strings.HasPrefix(s, "[") {
+			open, closing = '[', ']'
+		}
+		items, err := splitGroup(s, open, closing)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if len(items) != len(t.TupleElems) {
+			return reflect.Value{}, fmt.Errorf("expected %d tuple fields, got %d", len(t.TupleElems), len(items))
+		}
+		v := reflect.New(t.GetType()).Elem()
+		for i, item := range items {
+			field, err := parseReflect(*t.TupleElems[i], item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", t.TupleRawNames[i], err)
+			}
+			v.Field(i).Set(field)
+		}
+		return v, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported type %s", t.String())
+}
+
+// ParseJSON 按参数列表的类型转换 JSON 数组，例如 ["0x742d...", "100", [1, 2], {"to": "0x742d...", "amount": "5"}]。
+// 整数可以写成 JSON 数字或字符串（超过 2^53 的整数用字符串更直观），tuple 可以是按字段名的对象或按顺序的数组
+func ParseJSON(args abi.Arguments, raw []byte) ([]interface{}, error) {
+	var values []interface{}
+	if err := decodeJSON(raw, &values); err != nil {
+		return nil, fmt.Errorf("invalid JSON arguments: %w", err)
+	}
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("expected %d argument(s) %s, got %d", len(args), signature(args), len(values))
+	}
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := fromJSON(arg.Type, values[i])
+		if err != nil {
+			name := arg.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			return nil, fmt.Errorf("argument %s (%s): %w", name, arg.Type.String(), err)
+		}
+		out[i] = v.Interface()
+	}
+	return out, nil
+}
+
+// decodeJSON 解码 JSON，数字保留为 json.Number 以免大整数丢失精度
+func decodeJSON(raw []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// fromJSON 把解码后的 JSON 值转换为类型 t 对应的值；标量按字符串交给 parseReflect 处理
+func fromJSON(t abi.Type, v interface{}) (reflect.Value, error) {
+	switch val := v.(type) {
+	case string:
+		if t.T == abi.StringTy {
+			return reflect.ValueOf(val), nil
+		}
+		return parseReflect(t, strings.TrimSpace(val))
+	case json.Number:
+		return parseReflect(t, val.String())
+	case bool:
+		return parseReflect(t, strconv.FormatBool(val))
+
+	case []interface{}:
+		switch t.T {
+		case abi.SliceTy, abi.ArrayTy:
+			if t.T == abi.ArrayTy && len(val) != t.Size {
+				return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", t.Size, len(val))
+			}
+			var out reflect.Value
+			if t.T == abi.ArrayTy {
+				out = reflect.New(t.GetType()).Elem()
+			} else {
+				out = reflect.MakeSlice(t.GetType(), len(val), len(val))
+			}
+			for i, item := range val {
+				elem, err := fromJSON(*t.Elem, item)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+				}
+				out.Index(i).Set(elem)
+			}
+			return out, nil
+		case abi.TupleTy:
+			if len(val) != len(t.TupleElems) {
+				return reflect.Value{}, fmt.Errorf("expected %d tuple fields, got %d", len(t.TupleElems), len(val))
+			}
+			out := reflect.New(t.GetType()).Elem()
+			for i, item := range val {
+				field, err := fromJSON(*t.TupleElems[i], item)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field %s: %w", t.TupleRawNames[i], err)
+				}
+				out.Field(i).Set(field)
+			}
+			return out, nil
+		}
+
+	case map[string]interface{}:
+		if t.T == abi.TupleTy {
+			if len(val) != len(t.TupleElems) {
+				return reflect.Value{}, fmt.Errorf("expected %d tuple fields, got %d", len(t.TupleElems), len(val))
+			}
+			out := reflect.New(t.GetType()).Elem()
+			for i, name := range t.TupleRawNames {
+				item, ok := val[name]
+				if !ok {
+					return reflect.Value{}, fmt.Errorf("missing tuple field %q", name)
+				}
+				field, err := fromJSON(*t.TupleElems[i], item)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+				}
+				out.Field(i).Set(field)
+			}
+			return out, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use JSON value %v as %s", v, t.String())
+}
+
+// parseInt 解析十进制或 0x 十六进制整数并检查范围；8/16/32/64 位返回对应的 Go 整数类型，其余返回 *big.Int
+func parseInt(t abi.Type, s string) (reflect.Value, error) {
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("invalid integer %q", s)
+	}
+	lo, hi := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(t.Size))
+	if t.T == abi.IntTy {
+		hi.Rsh(hi, 1)
+		lo.Neg(hi)
+	}
+	hi.Sub(hi, big.NewInt(1))
+	if n.Cmp(lo) < 0 || n.Cmp(hi) > 0 {
+		return reflect.Value{}, fmt.Errorf("%s out of range for %s", s, t.String())
+	}
+
+	goType := t.GetType()
+	switch goType.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(n.Int64()).Convert(goType), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(n.Uint64()).Convert(goType), nil
+	}
+	return reflect.ValueOf(n), nil
+}
+
+// splitGroup 去掉外层括号后拆分元素
+func splitGroup(s string, open, closing rune) ([]string, error) {
+	if len(s) < 2 || rune(s[0]) != open || rune(s[len(s)-1]) != closing {
+		return nil, fmt.Errorf("expected %c...%c, got %q", open, closing, s)
+	}
+	return SplitList(s[1 : len(s)-1])
+}
+
+// unquote 去掉字符串两端的双引号
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// signature 参数列表的类型签名，例如 (address,uint256)
+func signature(args abi.Arguments) string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = arg.Type.String()
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
@@ -0,0 +1,68 @@
+// Package abifile 从文件加载合约 ABI。
+// 支持三种格式：
+//   - solc / solcjs 输出的纯 ABI 数组（如 build/Counter_sol_Counter.abi）
+//   - Foundry 构建产物（out/X.sol/X.json，abi 字段为数组）
+//   - Hardhat 构建产物（artifacts/.../X.json，abi 字段为数组）
+package abifile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Load 读取并解析 ABI 文件
+func Load(path string) (*abi.ABI, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ABI file %s: %w", path, err)
+	}
+	parsed, err := Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI file %s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+// LoadList 加载逗号分隔的多个 ABI 文件，空字符串返回空列表
+func LoadList(paths string) ([]*abi.ABI, error) {
+	var out []*abi.ABI
+	for _, p := range strings.Split(paths, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		parsed, err := Load(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, parsed)
+	}
+	return out, nil
+}
+
+// Parse 解析 ABI 内容：纯 ABI 数组，或带 abi 字段的构建产物对象
+func Parse(raw []byte) (*abi.ABI, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if err := json.Unmarshal(raw, &artifact); err != nil {
+			return nil, err
+		}
+		if len(artifact.ABI) == 0 {
+			return nil, fmt.Errorf("artifact has no abi field")
+		}
+		raw = artifact.ABI
+	}
+	parsed, err := abi.JSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
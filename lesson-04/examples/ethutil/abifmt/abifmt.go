@@ -0,0 +1,139 @@
+// Package abifmt 按 ABI 类型格式化解码后的值，用于事件参数、函数参数和返回值的输出：
+//   - address 用校验和格式，bytes / bytesN 用十六进制，string 加引号
+//   - intN 按有符号数输出（Topics 中的负数是 256 位补码）
+//   - 定长和变长数组逐个元素格式化，tuple 按 ABI 中的字段名输出（而不是 Go 结构体的大写字段名）
+//   - indexed 的动态类型（string、bytes、数组、tuple）在 Topics 中只有 keccak256 哈希，标记为哈希而不是当作值解码
+package abifmt
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Formatter 格式化选项
+type Formatter struct {
+	MaxBytes int // bytes 超过该长度时截断显示，0 表示不截断
+}
+
+// Format 使用默认选项格式化值
+func Format(t abi.Type, v interface{}) string {
+	return Formatter{}.Format(t, v)
+}
+
+// FormatTopic 使用默认选项格式化 indexed 参数
+func FormatTopic(t abi.Type, topic common.Hash) string {
+	return Formatter{}.FormatTopic(t, topic)
+}
+
+// Hashed 类型作为 indexed 参数时 Topics 中是否只有 keccak256 哈希
+func Hashed(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	}
+	return false
+}
+
+// hashed 哈希值的显示格式
+func hashed(t abi.Type, h common.Hash) string {
+	return fmt.Sprintf("%s (keccak256 of indexed %s)", h.Hex(), t.String())
+}
+
+// FormatTopic 格式化 indexed 参数：值类型从 32 字节的 topic 中还原，动态类型显示为哈希
+func (f Formatter) FormatTopic(t abi.Type, topic common.Hash) string {
+	if Hashed(t) {
+		return hashed(t, topic)
+	}
+	switch t.T {
+	case abi.AddressTy:
+		return common.BytesToAddress(topic[12:]).Hex()
+	case abi.UintTy:
+		return new(big.Int).SetBytes(topic[:]).String()
+	case abi.IntTy:
+		// 有符号整数在 topic 中符号扩展为 256 位补码
+		n := new(big.Int).SetBytes(topic[:])
+		if topic[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return n.String()
+	case abi.BoolTy:
+		return strconv.FormatBool(topic[31] != 0)
+	case abi.FixedBytesTy:
+		return hexutil.Encode(topic[:t.Size])
+	}
+	return topic.Hex()
+}
+
+// Format 按类型 t 格式化解码后的值 v
+func (f Formatter) Format(t abi.Type, v interface{}) string {
+	// events 等按 topic 解析出的动态类型 indexed 参数是哈希
+	if h, ok := v.(common.Hash); ok && Hashed(t) {
+		return hashed(t, h)
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return "<nil>"
+	}
+	switch t.T {
+	case abi.AddressTy:
+		if addr, ok := v.(common.Address); ok {
+			return addr.Hex()
+		}
+	case abi.IntTy, abi.UintTy:
+		// 小于 64 位的整数解码为 Go 原生整数，其余为 *big.Int，都按十进制输出（有符号数带负号）
+		return fmt.Sprintf("%d", v)
+	case abi.BoolTy:
+		return fmt.Sprintf("%t", v)
+	case abi.StringTy:
+		if s, ok := v.(string); ok {
+			return strconv.Quote(s)
+		}
+	case abi.BytesTy:
+		if b, ok := v.([]byte); ok {
+			return f.bytes(b)
+		}
+	case abi.FixedBytesTy, abi.FunctionTy:
+		// bytesN 解码为 [N]byte，function 为 [24]byte
+		if rv.Kind() == reflect.Array {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+	case abi.SliceTy, abi.ArrayTy:
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			parts := make([]string, rv.Len())
+			for i := range parts {
+				parts[i] = f.Format(*t.Elem, rv.Index(i).Interface())
+			}
+			return "[" + strings.Join(parts, ", ") + "]"
+		}
+	case abi.TupleTy:
+		if rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Struct && rv.NumField() == len(t.TupleElems) {
+			parts := make([]string, len(t.TupleElems))
+			for i, elem := range t.TupleElems {
+				parts[i] = fmt.Sprintf("%s: %s", t.TupleRawNames[i], f.Format(*elem, rv.Field(i).Interface()))
+			}
+			return "{" + strings.Join(parts, ", ") + "}"
+		}
+	}
+	// 值与类型不符时原样输出
+	return fmt.Sprintf("%v", v)
+}
+
+// bytes 十六进制输出，过长时截断
+func (f Formatter) bytes(b []byte) string {
+	if f.MaxBytes > 0 && len(b) > f.MaxBytes {
+		return fmt.Sprintf("%s... (%d bytes)", hexutil.Encode(b[:f.MaxBytes]), len(b))
+	}
+	return hexutil.Encode(b)
+}
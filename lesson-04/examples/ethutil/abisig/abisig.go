@@ -0,0 +1,165 @@
+// Package abisig 解析人类可读的函数和事件签名（带参数名），用于内置签名列表和命令行输入：
+//
+//	transfer(address to, uint256 value)
+//	Transfer(address indexed from, address indexed to, uint256 value)
+//	aggregate3((address target, bool allowFailure, bytes callData)[] calls)
+//
+// 参数名可以省略（自动命名为 arg0、arg1...），tuple 类型写成 (type name, ...) 加可选的数组后缀。
+package abisig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ParseFunction 解析函数签名，例如 "balanceOf(address)"，可以带 "function " 前缀
+func ParseFunction(sig string) (abi.Method, error) {
+	name, inputs, err := parse(strings.TrimPrefix(strings.TrimSpace(sig), "function "))
+	if err != nil {
+		return abi.Method{}, err
+	}
+	return abi.NewMethod(name, name, abi.Function, "nonpayable", false, false, inputs, nil), nil
+}
+
+// ParseEvent 解析事件签名，indexed 参数需要标注 indexed，可以带 "event " 前缀
+func ParseEvent(sig string) (abi.Event, error) {
+	name, inputs, err := parse(strings.TrimPrefix(strings.TrimSpace(sig), "event "))
+	if err != nil {
+		return abi.Event{}, err
+	}
+	return abi.NewEvent(name, name, false, inputs), nil
+}
+
+// parse 拆分名称和参数列表
+func parse(sig string) (string, abi.Arguments, error) {
+	sig = strings.TrimSpace(sig)
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return "", nil, fmt.Errorf("invalid signature %q", sig)
+	}
+	name := strings.TrimSpace(sig[:open])
+	params, err := parseParams(sig[open+1 : len(sig)-1])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid signature %q: %w", sig, err)
+	}
+	inputs := make(abi.Arguments, len(params))
+	for i, p := range params {
+		t, err := abi.NewType(p.Type, "", p.Components)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid signature %q: %w", sig, err)
+		}
+		inputs[i] = abi.Argument{Name: p.Name, Type: t, Indexed: p.Indexed}
+	}
+	return name, inputs, nil
+}
+
+// parseParams 解析逗号分隔的参数列表，每个参数为 "type [indexed] [name]"，tuple 类型写成 "(...)" 加可选的数组后缀
+func parseParams(s string) ([]abi.ArgumentMarshaling, error) {
+	parts, err := splitTopLevel(s)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]abi.ArgumentMarshaling, 0, len(parts))
+	for i, part := range parts {
+		var p abi.ArgumentMarshaling
+		if strings.HasPrefix(part, "(") {
+			end := matchingParen(part)
+			if end < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", part)
+			}
+			components, err := parseParams(part[1:end])
+			if err != nil {
+				return nil, err
+			}
+			p.Components = components
+			// 右括号后面紧跟数组后缀，再是可选的 indexed 和参数名
+			fields := strings.Fields(part[end+1:])
+			suffix := ""
+			if len(fields) > 0 && strings.HasPrefix(fields[0], "[") {
+				suffix, fields = fields[0], fields[1:]
+			}
+			p.Type = "tuple" + suffix
+			if err := setNameIndexed(&p, fields, part); err != nil {
+				return nil, err
+			}
+		} else {
+			fields := strings.Fields(part)
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("invalid parameter %q", part)
+			}
+			p.Type = fields[0]
+			if err := setNameIndexed(&p, fields[1:], part); err != nil {
+				return nil, err
+			}
+		}
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("arg%d", i)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// setNameIndexed 处理类型后面的 "[indexed] [name]"
+func setNameIndexed(p *abi.ArgumentMarshaling, fields []string, part string) error {
+	if len(fields) > 0 && fields[0] == "indexed" {
+		p.Indexed, fields = true, fields[1:]
+	}
+	switch len(fields) {
+	case 0:
+	case 1:
+		p.Name = fields[0]
+	default:
+		return fmt.Errorf("invalid parameter %q", part)
+	}
+	return nil
+}
+
+// splitTopLevel 按括号外的逗号拆分
+func splitTopLevel(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", s)
+	}
+	return append(parts, strings.TrimSpace(s[start:])), nil
+}
+
+// matchingParen 返回与 s[0] 的左括号匹配的右括号位置
+func matchingParen(s string) int {
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,202 @@
+// Package events 事件签名注册表：从 ABI 文件和内置常用事件列表（events.txt）加载事件，
+// 预先计算 Topics[0] → 事件的索引，用于解码任意合约的日志。
+//
+// 不同标准的事件可能签名相同（例如 ERC-20 和 ERC-721 的 Transfer），Topics[0] 一样，
+// 这时按 indexed 参数数量区分：indexed 参数数量 = Topics 数量 - 1。
+package events
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethutil/abisig"
+)
+
+//go:embed events.txt
+var bundledEvents string
+
+// Entry 注册表中的一个事件
+type Entry struct {
+	Event   abi.Event
+	Source  string // 来源：ABI 文件路径或内置分组（如 "ERC-20"）
+	Indexed int    // indexed 参数数量
+}
+
+// Registry 事件注册表：Topics[0] → 事件列表（按添加顺序，先添加的优先）
+type Registry struct {
+	byTopic map[common.Hash][]Entry
+}
+
+// New 创建空注册表
+func New() *Registry {
+	return &Registry{byTopic: make(map[common.Hash][]Entry)}
+}
+
+// Bundled 创建包含内置常用事件的注册表
+func Bundled() *Registry {
+	r := New()
+	source := ""
+	for _, line := range strings.Split(bundledEvents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			source = line[1 : len(line)-1]
+			continue
+		}
+		if err := r.AddSignature(line, source); err != nil {
+			panic(fmt.Sprintf("invalid bundled event %q: %v", line, err))
+		}
+	}
+	return r
+}
+
+// AddABI 添加 ABI 中的全部事件；ABI 中的定义优先于已有的同签名事件（包括内置事件）
+func (r *Registry) AddABI(a *abi.ABI, source string) {
+	for _, ev := range a.Events {
+		if ev.Anonymous {
+			continue // 匿名事件没有 Topics[0]，无法按签名识别
+		}
+		r.add(Entry{Event: ev, Source: source, Indexed: countIndexed(ev)}, true)
+	}
+}
+
+// AddSignature 添加一个文本事件签名，例如 "Transfer(address indexed from, address indexed to, uint256 value)"
+func (r *Registry) AddSignature(sig, source string) error {
+	ev, err := abisig.ParseEvent(sig)
+	if err != nil {
+		return err
+	}
+	r.add(Entry{Event: ev, Source: source, Indexed: countIndexed(ev)}, false)
+	return nil
+}
+
+// add 添加事件；签名和 indexed 参数数量都相同的只保留一个
+func (r *Registry) add(e Entry, prepend bool) {
+	list := r.byTopic[e.Event.ID]
+	for i, existing := range list {
+		if existing.Indexed == e.Indexed {
+			if prepend {
+				list = append(list[:i:i], list[i+1:]...)
+				break
+			}
+			return
+		}
+	}
+	if prepend {
+		r.byTopic[e.Event.ID] = append([]Entry{e}, list...)
+	} else {
+		r.byTopic[e.Event.ID] = append(list, e)
+	}
+}
+
+// Lookup 查找日志对应的事件：Topics[0] 相同且 indexed 参数数量与 Topics 数量匹配
+func (r *Registry) Lookup(log *types.Log) (*Entry, bool) {
+	if len(log.Topics) == 0 {
+		return nil, false
+	}
+	for _, e := range r.byTopic[log.Topics[0]] {
+		if e.Indexed == len(log.Topics)-1 {
+			e := e
+			return &e, true
+		}
+	}
+	return nil, false
+}
+
+// Candidates 返回 Topics[0] 对应的全部事件（不考虑 indexed 参数数量），用于解释无法识别的日志
+func (r *Registry) Candidates(topic0 common.Hash) []Entry {
+	return r.byTopic[topic0]
+}
+
+// Find 按事件名（如 "Transfer"）或规范签名（如 "Transfer(address,address,uint256)"）查找事件
+func (r *Registry) Find(name string) []Entry {
+	var out []Entry
+	for _, list := range r.byTopic {
+		for _, e := range list {
+			if e.Event.Name == name || e.Event.Sig == name {
+				out = append(out, e)
+			}
+		}
+	}
+	// map 遍历顺序不固定，按签名排序保证输出稳定
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Event.Sig < out[j].Event.Sig })
+	return out
+}
+
+// Len 注册表中的事件数量
+func (r *Registry) Len() int {
+	n := 0
+	for _, list := range r.byTopic {
+		n += len(list)
+	}
+	return n
+}
+
+// Arg 一个解码后的事件参数
+type Arg struct {
+	Name    string
+	Type    abi.Type
+	Indexed bool
+	Value   interface{} // indexed 的动态类型（string、bytes、数组、tuple）在 Topics 中只有哈希，值为 common.Hash
+}
+
+// Decode 按事件定义的参数顺序解码日志：indexed 参数从 Topics[1..] 解析，其余从 Data 解码
+func (e *Entry) Decode(log *types.Log) ([]Arg, error) {
+	if len(log.Topics) != e.Indexed+1 {
+		return nil, fmt.Errorf("%s expects %d topics, log has %d", e.Event.Sig, e.Indexed+1, len(log.Topics))
+	}
+	dataValues, err := e.Event.Inputs.NonIndexed().Unpack(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data of %s: %w", e.Event.Sig, err)
+	}
+	args := make([]Arg, 0, len(e.Event.Inputs))
+	topicIdx := 0
+	for _, input := range e.Event.Inputs {
+		arg := Arg{Name: input.Name, Type: input.Type, Indexed: input.Indexed}
+		if input.Indexed {
+			topic := log.Topics[1+topicIdx]
+			topicIdx++
+			value, err := parseTopic(input, topic)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse topic %s of %s: %w", input.Name, e.Event.Sig, err)
+			}
+			arg.Value = value
+		} else {
+			arg.Value, dataValues = dataValues[0], dataValues[1:]
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// parseTopic 解析一个 indexed 参数；动态类型在 Topics 中存的是 keccak256 哈希，只能返回哈希
+func parseTopic(input abi.Argument, topic common.Hash) (interface{}, error) {
+	switch input.Type.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return topic, nil
+	}
+	out := make(map[string]interface{})
+	if err := abi.ParseTopicsIntoMap(out, abi.Arguments{input}, []common.Hash{topic}); err != nil {
+		return nil, err
+	}
+	return out[input.Name], nil
+}
+
+// countIndexed 统计事件的 indexed 参数数量
+func countIndexed(ev abi.Event) int {
+	n := 0
+	for _, input := range ev.Inputs {
+		if input.Indexed {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+// Package logfilter 把按参数名给出的事件过滤条件编码为 FilterQuery.Topics，订阅和 eth_getLogs 回填通用：
+//
+//	from=0xA...;to=0xB...,0xC...   // from 为 A，且 to 为 B 或 C
+//	to=@addresses.txt              // 从文件读取取值列表（按换行或逗号分隔，# 开头为注释）
+//
+// 只有 indexed 参数在 Topics 中，可以由节点过滤；同一参数的多个取值为 OR，不同参数之间为 AND。
+package logfilter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethutil/abiargs"
+	"ethutil/events"
+)
+
+// Conditions 参数名 → 取值列表（OR）
+type Conditions map[string][]string
+
+// Parse 解析 "name=v1,v2;name2=v3" 形式的过滤条件；空字符串返回空条件
+func Parse(s string) (Conditions, error) {
+	conds := Conditions{}
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, values, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid filter %q: expected name=value[,value...]", part)
+		}
+		if err := conds.Add(strings.TrimSpace(name), values); err != nil {
+			return nil, err
+		}
+	}
+	return conds, nil
+}
+
+// Add 为参数 name 添加逗号分隔的取值；"@path" 表示从文件读取取值列表
+func (c Conditions) Add(name, values string) error {
+	values = strings.TrimSpace(values)
+	if strings.HasPrefix(values, "@") {
+		list, err := readList(values[1:])
+		if err != nil {
+			return err
+		}
+		c[name] = append(c[name], list...)
+		return nil
+	}
+	for _, v := range strings.Split(values, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			c[name] = append(c[name], v)
+		}
+	}
+	if len(c[name]) == 0 {
+		return fmt.Errorf("filter %q has no values", name)
+	}
+	return nil
+}
+
+// readList 读取取值列表文件：按换行或逗号分隔，忽略空行和 # 开头的注释
+func readList(path string) ([]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filter values: %w", err)
+	}
+	var out []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, v := range strings.Split(line, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no values in %s", path)
+	}
+	return out, nil
+}
+
+// Topics 生成事件 ev 的 Topics：Topics[0] 为事件签名哈希，之后按 indexed 参数顺序放入取值（没有条件的为通配），
+// 末尾的通配位置会被去掉
+func Topics(ev abi.Event, conds Conditions) ([][]common.Hash, error) {
+	var query [][]interface{}
+	used := 0
+	for _, input := range ev.Inputs {
+		if !input.Indexed {
+			if _, ok := conds[input.Name]; ok {
+				return nil, fmt.Errorf("parameter %q of %s is not indexed and cannot be filtered by topics", input.Name, ev.Sig)
+			}
+			continue
+		}
+		values, ok := conds[input.Name]
+		if !ok {
+			query = append(query, nil)
+			continue
+		}
+		used++
+		rule := make([]interface{}, len(values))
+		for i, v := range values {
+			parsed, err := abiargs.ParseValue(input.Type, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q: %w", input.Name, v, err)
+			}
+			rule[i] = parsed
+		}
+		query = append(query, rule)
+	}
+	if used != len(conds) {
+		for name := range conds {
+			if !hasInput(ev, name) {
+				return nil, fmt.Errorf("event %s has no parameter %q", ev.Sig, name)
+			}
+		}
+	}
+	topics, err := abi.MakeTopics(query...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode topics: %w", err)
+	}
+	for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+		topics = topics[:len(topics)-1]
+	}
+	return append([][]common.Hash{{ev.ID}}, topics...), nil
+}
+
+// Resolve 在注册表中按名称或签名查找事件并生成 Topics。
+// 同名但签名不同的事件（如 Uniswap V2 和 V3 的 Swap）需要用完整签名区分；
+// 签名相同只是 indexed 参数不同的（如 ERC-20 和 ERC-721 的 Transfer）取第一个能表达全部条件的
+func Resolve(reg *events.Registry, name string, conds Conditions) (*events.Entry, [][]common.Hash, error) {
+	entries := reg.Find(name)
+	if len(entries) == 0 {
+		return nil, nil, fmt.Errorf("unknown event %q", name)
+	}
+	for _, e := range entries[1:] {
+		if e.Event.ID != entries[0].Event.ID {
+			sigs := make([]string, len(entries))
+			for i, e := range entries {
+				sigs[i] = fmt.Sprintf("%s (%s)", e.Event.Sig, e.Source)
+			}
+			return nil, nil, fmt.Errorf("event %q is ambiguous, use one of: %s", name, strings.Join(sigs, ", "))
+		}
+	}
+	var firstErr error
+	for i := range entries {
+		topics, err := Topics(entries[i].Event, conds)
+		if err == nil {
+			return &entries[i], topics, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, nil, firstErr
+}
+
+func hasInput(ev abi.Event, name string) bool {
+	for _, input := range ev.Inputs {
+		if input.Name == name {
+			return true
+		}
+	}
+	return false
+}
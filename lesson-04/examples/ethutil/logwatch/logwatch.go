@@ -0,0 +1,225 @@
+// Package logwatch 按区块范围分段回填历史日志，并无缝切换到实时订阅：
+//   - 回填使用 eth_getLogs（FilterLogs），每段的区块数自适应：节点返回 "结果太多" / "范围太大" 时减半重试，
+//     连续成功后再翻倍（不超过 MaxChunk）
+//   - Follow 先建立订阅再读取当前区块号 head，回填 [FromBlock, head]，回填期间订阅推送的日志先缓存，
+//     回填结束后转发区块号大于 head 的日志：head 之后的区块一定由订阅推送，head 及之前的区块由回填覆盖；
+//     head 及之前区块的日志只有回填没有输出过（按 (区块哈希, 日志索引) 判断，例如重组后的新区块）时才转发，
+//     因此既不会遗漏也不会重复
+//   - 链重组时订阅推送的移除日志（Removed = true）总是转发，由调用方撤销之前输出的同一日志
+package logwatch
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// DefaultChunk 回填时每段的初始区块数
+	DefaultChunk = 2000
+	// DefaultMaxChunk 每段区块数的上限
+	DefaultMaxChunk = 10000
+	// growAfter 连续成功多少段后把区块数翻倍
+	growAfter = 2
+)
+
+// Client Watcher 需要的节点接口，*ethclient.Client 满足该接口
+type Client interface {
+	ethereum.LogFilterer
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Watcher 日志回填和订阅
+type Watcher struct {
+	Client   Client
+	Chunk    uint64                          // 初始每段区块数，0 表示 DefaultChunk
+	MaxChunk uint64                          // 每段区块数上限，0 表示 DefaultMaxChunk
+	Progress func(from, to uint64, logs int) // 每段回填完成后调用，可以为 nil
+}
+
+// New 创建使用默认分段参数的 Watcher
+func New(client Client) *Watcher {
+	return &Watcher{Client: client, Chunk: DefaultChunk, MaxChunk: DefaultMaxChunk}
+}
+
+// Backfill 按顺序回填 [q.FromBlock, q.ToBlock] 的日志，q.FromBlock 为 nil 时从 0 开始，q.ToBlock 为 nil 时到当前区块
+func (w *Watcher) Backfill(ctx context.Context, q ethereum.FilterQuery, fn func(types.Log)) error {
+	var from, to uint64
+	if q.FromBlock != nil {
+		from = q.FromBlock.Uint64()
+	}
+	if q.ToBlock != nil {
+		to = q.ToBlock.Uint64()
+	} else {
+		head, err := w.Client.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get latest block number: %w", err)
+		}
+		to = head
+	}
+	return w.backfill(ctx, q, from, to, fn)
+}
+
+func (w *Watcher) backfill(ctx context.Context, q ethereum.FilterQuery, from, to uint64, fn func(types.Log)) error {
+	chunk, maxChunk := w.Chunk, w.MaxChunk
+	if chunk == 0 {
+		chunk = DefaultChunk
+	}
+	if maxChunk == 0 {
+		maxChunk = DefaultMaxChunk
+	}
+	if chunk > maxChunk {
+		chunk = maxChunk
+	}
+	successes := 0
+	for from <= to {
+		end := from + chunk - 1
+		if end > to || end < from {
+			end = to
+		}
+		q.FromBlock = new(big.Int).SetUint64(from)
+		q.ToBlock = new(big.Int).SetUint64(end)
+		logs, err := w.Client.FilterLogs(ctx, q)
+		if err != nil {
+			// 范围太大：缩小一半重试同一起点；已经是单个区块时无法再缩小
+			if IsRangeError(err) && end > from {
+				chunk = (end - from + 1) / 2
+				successes = 0
+				continue
+			}
+			return fmt.Errorf("failed to get logs for blocks %d-%d: %w", from, end, err)
+		}
+		for _, l := range logs {
+			fn(l)
+		}
+		if w.Progress != nil {
+			w.Progress(from, end, len(logs))
+		}
+		if successes++; successes >= growAfter && chunk < maxChunk {
+			chunk = min(chunk*2, maxChunk)
+			successes = 0
+		}
+		if end == to {
+			break
+		}
+		from = end + 1
+	}
+	return nil
+}
+
+// Follow 订阅实时日志；q.FromBlock 不为 nil 时先回填从 FromBlock 到当前区块的历史日志，再无缝切换到订阅。
+// fn 按顺序收到每条日志，阻塞直到 ctx 取消或订阅出错
+func (w *Watcher) Follow(ctx context.Context, q ethereum.FilterQuery, fn func(types.Log)) error {
+	// 返回时结束还在进行的回填
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	live := q
+	live.FromBlock, live.ToBlock = nil, nil
+	logsCh := make(chan types.Log, 128)
+	sub, err := w.Client.SubscribeFilterLogs(ctx, live, logsCh)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe logs: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		done       chan error      // 回填结果，不回填时为 nil（永远不会就绪）
+		pending    []types.Log     // 回填期间订阅推送的日志
+		cutoff     uint64          // 回填覆盖到的区块
+		backfilled map[logKey]bool // 回填输出过的、cutoff 之前 dedupBlocks 个区块内的日志
+	)
+	// fresh 判断订阅推送的日志是否需要转发：cutoff 及之前的区块只转发回填没有输出过的日志。
+	// 移除的日志总是转发：回填可能已经输出了被移除区块中的日志
+	fresh := func(l types.Log) bool {
+		key := logKey{l.BlockHash, l.Index, false}
+		if l.Removed {
+			delete(backfilled, key)
+			return true
+		}
+		return l.BlockNumber > cutoff || !backfilled[key]
+	}
+	if q.FromBlock != nil {
+		// 订阅建立之后再读取当前区块号，之后产生的区块一定会由订阅推送
+		head, err := w.Client.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get latest block number: %w", err)
+		}
+		cutoff = head
+		backfilled = make(map[logKey]bool)
+		done = make(chan error, 1)
+		// backfilled 只由回填协程写入，主循环在收到 done 之后才读取
+		go func() {
+			done <- w.backfill(ctx, q, q.FromBlock.Uint64(), head, func(l types.Log) {
+				if l.BlockNumber+dedupBlocks > head {
+					backfilled[logKey{l.BlockHash, l.Index, false}] = true
+				}
+				fn(l)
+			})
+		}()
+		// 提前返回时等回填协程结束，保证返回后不会再调用 fn
+		defer func() {
+			if done != nil {
+				cancel()
+				<-done
+			}
+		}()
+	}
+
+	for {
+		select {
+		case l := <-logsCh:
+			if done != nil {
+				pending = append(pending, l)
+				continue
+			}
+			if fresh(l) {
+				fn(l)
+			}
+		case err := <-done:
+			// 回填已经结束，返回前不需要再等待
+			done = nil
+			if err != nil {
+				return err
+			}
+			for _, l := range pending {
+				if fresh(l) {
+					fn(l)
+				}
+			}
+			pending = nil
+		case err := <-sub.Err():
+			return fmt.Errorf("subscription error: %w", err)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// rangeErrors 各节点服务商对 eth_getLogs 范围或结果数量超限的错误描述（小写）
+var rangeErrors = []string{
+	"too many",       // "too many results"
+	"more than",      // "query returned more than 10000 results"
+	"too large",      // "block range is too large"
+	"block range",    // "... eth_getLogs requests with up to a 2K block range"
+	"response size",  // "log response size exceeded"
+	"limit exceeded", // "query limit exceeded"
+	"exceeds max",    // "block range exceeds max"
+}
+
+// IsRangeError 判断 eth_getLogs 的错误是否是范围太大或结果太多（可以缩小范围重试）
+func IsRangeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range rangeErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
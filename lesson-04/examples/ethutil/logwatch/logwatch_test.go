@@ -0,0 +1,443 @@
+package logwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	addrA = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	addrB = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+)
+
+// mkLog 构造区块 block 中的一条日志；fork 不为 0 时表示重组后同一高度的另一个区块
+func mkLog(addr common.Address, block uint64, index uint, fork byte) types.Log {
+	var hash common.Hash
+	hash[0] = fork
+	new(big.Int).SetUint64(block).FillBytes(hash[24:])
+	return types.Log{Address: addr, BlockNumber: block, BlockHash: hash, Index: index}
+}
+
+// label 日志的简短表示，例如 "aa:100/0"，重组后的区块加 "'"，移除的日志加 "r"
+func label(l types.Log) string {
+	s := fmt.Sprintf("%x:%d/%d", l.Address[19], l.BlockNumber, l.Index)
+	if l.BlockHash[0] != 0 {
+		s += "'"
+	}
+	if l.Removed {
+		s += "r"
+	}
+	return s
+}
+
+// fakeChain 内存中的链：FilterLogs 按区块范围返回规范链上的日志，范围超过 limit 时返回错误；
+// mine / push 把日志推送给当前的订阅
+type fakeChain struct {
+	mu       sync.Mutex
+	head     uint64
+	logs     []types.Log // 规范链上的日志，按区块和索引排序
+	limit    uint64      // FilterLogs 允许的最大区块数，0 表示不限制
+	limitErr string
+	calls    int
+	subs     []*fakeSub
+
+	// gate 在 FilterLogs 取得结果之后、返回之前调用（不持有锁），可以阻塞以模拟回填期间的并发；返回错误时 FilterLogs 返回该错误
+	gate func(ctx context.Context, call int) error
+	// onSubscribe 订阅建立之后、SubscribeFilterLogs 返回之前调用（不持有锁）
+	onSubscribe func()
+}
+
+type fakeSub struct {
+	ch    chan<- types.Log
+	addrs []common.Address
+	errCh chan error
+	quit  bool
+}
+
+func (s *fakeSub) Unsubscribe()      {}
+func (s *fakeSub) Err() <-chan error { return s.errCh }
+
+func matches(addrs []common.Address, l types.Log) bool {
+	if len(addrs) == 0 {
+		return true
+	}
+	for _, a := range addrs {
+		if a == l.Address {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, nil
+}
+
+func (c *fakeChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	c.mu.Lock()
+	c.calls++
+	call, gate := c.calls, c.gate
+	var out []types.Log
+	for _, l := range c.logs {
+		if l.BlockNumber >= from && l.BlockNumber <= to && matches(q.Addresses, l) {
+			out = append(out, l)
+		}
+	}
+	c.mu.Unlock()
+	if c.limit > 0 && to-from+1 > c.limit {
+		return nil, errors.New(c.limitErr)
+	}
+	// 结果在调用时确定，gate 阻塞期间链上的变化（新区块、重组）不会反映在这次结果中
+	if gate != nil {
+		if err := gate(ctx, call); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (c *fakeChain) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	sub := &fakeSub{ch: ch, addrs: q.Addresses, errCh: make(chan error)}
+	c.mu.Lock()
+	c.subs = append(c.subs, sub)
+	onSubscribe := c.onSubscribe
+	c.mu.Unlock()
+	// ctx 取消（Follow 返回）之后不再推送
+	go func() {
+		<-ctx.Done()
+		c.mu.Lock()
+		sub.quit = true
+		c.mu.Unlock()
+	}()
+	if onSubscribe != nil {
+		onSubscribe()
+	}
+	return sub, nil
+}
+
+// waitSubscribed 等待建立了 n 个订阅（Start / Update 在协程中订阅）
+func (c *fakeChain) waitSubscribed(t *testing.T, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		got := len(c.subs)
+		c.mu.Unlock()
+		if got >= n {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %d subscription(s)", n)
+}
+
+// mine 产生区块 block 并推送其中的日志
+func (c *fakeChain) mine(block uint64, logs ...types.Log) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head = max(c.head, block)
+	c.logs = append(c.logs, logs...)
+	for _, l := range logs {
+		c.pushLocked(l)
+	}
+}
+
+// reorg 把日志 old 移出规范链（推送 Removed = true），再产生替代的日志
+func (c *fakeChain) reorg(old types.Log, replacements ...types.Log) {
+	c.mu.Lock()
+	for i, l := range c.logs {
+		if l.BlockHash == old.BlockHash && l.Index == old.Index {
+			c.logs = append(c.logs[:i], c.logs[i+1:]...)
+			break
+		}
+	}
+	old.Removed = true
+	c.pushLocked(old)
+	c.mu.Unlock()
+	for _, l := range replacements {
+		c.mine(l.BlockNumber, l)
+	}
+}
+
+// push 只推送日志，不改变规范链（模拟节点重复推送）
+func (c *fakeChain) push(l types.Log) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pushLocked(l)
+}
+
+func (c *fakeChain) pushLocked(l types.Log) {
+	for _, s := range c.subs {
+		if !s.quit && matches(s.addrs, l) {
+			s.ch <- l
+		}
+	}
+}
+
+// recorder 记录 fn 收到的日志
+type recorder struct {
+	mu   sync.Mutex
+	got  []string
+	hook func(types.Log) // fn 中调用，可以阻塞
+}
+
+func (r *recorder) fn(l types.Log) {
+	if r.hook != nil {
+		r.hook(l)
+	}
+	r.mu.Lock()
+	r.got = append(r.got, label(l))
+	r.mu.Unlock()
+}
+
+// wait 等待收到 want 中的全部日志，再稍等确认没有多余（重复）的日志
+func (r *recorder) wait(t *testing.T, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		r.mu.Lock()
+		n := len(r.got)
+		r.mu.Unlock()
+		if n >= len(want) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(30 * time.Millisecond)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !reflect.DeepEqual(r.got, want) {
+		t.Fatalf("got logs %v, want %v", r.got, want)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	tests := []struct {
+		name            string
+		chunk, maxChunk uint64
+		limit           uint64
+		limitErr        string
+		from, to        uint64
+		wantRanges      [][2]uint64
+		wantErr         bool
+	}{
+		{
+			name: "grows to MaxChunk", chunk: 10, maxChunk: 40, from: 0, to: 199,
+			wantRanges: [][2]uint64{{0, 9}, {10, 19}, {20, 39}, {40, 59}, {60, 99}, {100, 139}, {140, 179}, {180, 199}},
+		},
+		{
+			name: "halves on too many results and grows back", chunk: 64, maxChunk: 64, limit: 16,
+			limitErr: "query returned more than 10000 results", from: 0, to: 99,
+			wantRanges: [][2]uint64{{0, 15}, {16, 31}, {32, 47}, {48, 63}, {64, 79}, {80, 95}, {96, 99}},
+		},
+		{
+			name: "halves on range too large", chunk: 100, maxChunk: 400, limit: 50,
+			limitErr: "block range is too large", from: 1000, to: 1299,
+			wantRanges: [][2]uint64{{1000, 1049}, {1050, 1099}, {1100, 1149}, {1150, 1199}, {1200, 1249}, {1250, 1299}},
+		},
+		{
+			name: "chunk larger than range", chunk: 2000, maxChunk: 10000, from: 5, to: 7,
+			wantRanges: [][2]uint64{{5, 7}},
+		},
+		{
+			name: "single block still too large", chunk: 8, maxChunk: 8, limit: 1,
+			limitErr: "Log response size exceeded", from: 0, to: 3,
+			wantRanges: [][2]uint64{{0, 0}, {1, 1}, {2, 2}, {3, 3}},
+		},
+		{
+			name: "other errors are not retried", chunk: 8, maxChunk: 8, limit: 4,
+			limitErr: "internal error", from: 0, to: 15, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &fakeChain{head: tt.to + 10, limit: tt.limit, limitErr: tt.limitErr}
+			var want []string
+			for b := uint64(0); b <= tt.to+10; b += 3 {
+				l := mkLog(addrA, b, 0, 0)
+				chain.logs = append(chain.logs, l)
+				if b >= tt.from && b <= tt.to {
+					want = append(want, label(l))
+				}
+			}
+			var ranges [][2]uint64
+			w := &Watcher{Client: chain, Chunk: tt.chunk, MaxChunk: tt.maxChunk, Progress: func(from, to uint64, _ int) {
+				ranges = append(ranges, [2]uint64{from, to})
+			}}
+			rec := &recorder{}
+			err := w.Backfill(context.Background(), ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(tt.from),
+				ToBlock:   new(big.Int).SetUint64(tt.to),
+			}, rec.fn)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ranges, tt.wantRanges) {
+				t.Errorf("ranges %v, want %v", ranges, tt.wantRanges)
+			}
+			if !reflect.DeepEqual(rec.got, want) {
+				t.Errorf("logs %v, want %v", rec.got, want)
+			}
+		})
+	}
+}
+
+func TestFollow(t *testing.T) {
+	l95, l100, l100b := mkLog(addrA, 95, 0, 0), mkLog(addrA, 100, 0, 0), mkLog(addrA, 100, 1, 0)
+	tests := []struct {
+		name string
+		from *big.Int
+		// onSubscribe 在订阅建立后、读取区块号之前产生区块：订阅和回填都会看到这些日志
+		onSubscribe func(c *fakeChain)
+		// duringBackfill 在回填的第一次 eth_getLogs 返回之前执行
+		duringBackfill func(c *fakeChain)
+		after          func(c *fakeChain)
+		want           []string
+	}{
+		{
+			name: "live only",
+			after: func(c *fakeChain) {
+				c.mine(101, mkLog(addrA, 101, 0, 0))
+				c.mine(102, mkLog(addrA, 102, 0, 0))
+			},
+			want: []string{"aa:101/0", "aa:102/0"},
+		},
+		{
+			name: "backfill then live",
+			from: big.NewInt(90),
+			after: func(c *fakeChain) {
+				c.mine(101, mkLog(addrA, 101, 0, 0))
+			},
+			want: []string{"aa:95/0", "aa:100/0", "aa:100/1", "aa:101/0"},
+		},
+		{
+			name: "live logs during backfill are buffered and deduplicated",
+			from: big.NewInt(90),
+			onSubscribe: func(c *fakeChain) {
+				c.mine(103, mkLog(addrA, 103, 0, 0))
+			},
+			duringBackfill: func(c *fakeChain) {
+				c.mine(104, mkLog(addrA, 104, 0, 0))
+				c.mine(105, mkLog(addrA, 105, 0, 0), mkLog(addrA, 105, 1, 0))
+			},
+			after: func(c *fakeChain) {
+				c.mine(106, mkLog(addrA, 106, 0, 0))
+			},
+			want: []string{"aa:95/0", "aa:100/0", "aa:100/1", "aa:103/0", "aa:104/0", "aa:105/0", "aa:105/1", "aa:106/0"},
+		},
+		{
+			name: "reorg during backfill",
+			from: big.NewInt(90),
+			duringBackfill: func(c *fakeChain) {
+				// 回填读取的是重组之前的区块 100
+				c.reorg(l100b, mkLog(addrA, 100, 1, 1))
+				c.mine(101, mkLog(addrA, 101, 0, 0))
+			},
+			want: []string{"aa:95/0", "aa:100/0", "aa:100/1", "aa:100/1r", "aa:100/1'", "aa:101/0"},
+		},
+		{
+			name: "reorg of a backfilled block after the handoff",
+			from: big.NewInt(90),
+			after: func(c *fakeChain) {
+				c.reorg(l100, mkLog(addrA, 100, 0, 1))
+				// 重复推送回填已经输出过的日志
+				c.push(l100b)
+			},
+			want: []string{"aa:95/0", "aa:100/0", "aa:100/1", "aa:100/0r", "aa:100/0'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &fakeChain{head: 100, logs: []types.Log{l95, l100, l100b}}
+			if tt.onSubscribe != nil {
+				chain.onSubscribe = func() { tt.onSubscribe(chain) }
+			}
+			entered, release := make(chan struct{}), make(chan struct{})
+			if tt.duringBackfill != nil {
+				chain.gate = func(ctx context.Context, call int) error {
+					if call == 1 {
+						close(entered)
+						<-release
+					}
+					return nil
+				}
+			}
+
+			rec := &recorder{}
+			ctx, cancel := context.WithCancel(context.Background())
+			errCh := make(chan error, 1)
+			go func() { errCh <- New(chain).Follow(ctx, ethereum.FilterQuery{FromBlock: tt.from}, rec.fn) }()
+
+			if tt.duringBackfill != nil {
+				<-entered
+				tt.duringBackfill(chain)
+				close(release)
+			}
+			if tt.after != nil {
+				// 等回填输出完，保证 after 中的日志在回填结束之后推送
+				time.Sleep(30 * time.Millisecond)
+				tt.after(chain)
+			}
+			rec.wait(t, tt.want)
+			cancel()
+			if err := <-errCh; !errors.Is(err, context.Canceled) {
+				t.Fatalf("Follow returned %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+func TestIsRangeError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("query returned more than 10000 results"), true},
+		{errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"), true},
+		{errors.New("block range is too large"), true},
+		{errors.New("exceed maximum block range: 50000"), true},
+		{errors.New("query limit exceeded"), true},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsRangeError(tt.err); got != tt.want {
+			t.Errorf("IsRangeError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestFollowBackfillError 回填出错时 Follow 返回该错误，不会阻塞
+func TestFollowBackfillError(t *testing.T) {
+	chain := &fakeChain{head: 100, limit: 1, limitErr: "internal error"}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- New(chain).Follow(context.Background(), ethereum.FilterQuery{FromBlock: big.NewInt(0)}, func(types.Log) {})
+	}()
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "internal error") {
+			t.Fatalf("Follow returned %v, want the backfill error", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Follow did not return after the backfill failed")
+	}
+}
@@ -0,0 +1,127 @@
+package logwatch
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// dedupBlocks 去重记录保留的区块数
+const dedupBlocks = 64
+
+// Stream 可以在运行中更换过滤条件的日志订阅（例如重新加载配置后监听的合约变了）。
+// 旧订阅停止时可能还有已经推送、尚未输出的日志，它们会被丢弃；因此新订阅从尚未完全输出的最低区块开始回填：
+// 最后输出的日志所在区块（不早于旧订阅的起始区块、不晚于当前区块）。
+// 该区块中已经输出过的日志按 (区块哈希, 日志索引) 去重，因此切换期间不遗漏也不重复
+type Stream struct {
+	w     *Watcher
+	ctx   context.Context
+	fn    func(types.Log)
+	errCh chan error
+
+	mu   sync.Mutex
+	stop context.CancelFunc
+	done chan struct{}
+	seen map[logKey]uint64 // 最近输出过的日志 → 区块号
+	last uint64            // 输出过的最大区块号
+	from uint64            // 当前 Follow 的起始区块，之前的区块已经全部输出
+}
+
+type logKey struct {
+	block   common.Hash
+	index   uint
+	removed bool // 重组撤销的日志与原日志哈希、索引相同，不能当作重复
+}
+
+// Start 启动订阅，q.FromBlock 不为 nil 时先回填（同 Follow）。订阅出错时错误发送到 Err()，ctx 取消时结束
+func (w *Watcher) Start(ctx context.Context, q ethereum.FilterQuery, fn func(types.Log)) *Stream {
+	s := &Stream{w: w, ctx: ctx, fn: fn, errCh: make(chan error, 1), seen: make(map[logKey]uint64)}
+	if q.FromBlock == nil {
+		// 只订阅实时日志：起始区块为当前区块（查询失败时为 0，Update 时退回 last）
+		if head, err := w.Client.BlockNumber(ctx); err == nil {
+			s.from = head
+		}
+	}
+	s.mu.Lock()
+	s.follow(q)
+	s.mu.Unlock()
+	return s
+}
+
+// Err 订阅出错时返回错误（Update 主动停止旧订阅不算出错）
+func (s *Stream) Err() <-chan error {
+	return s.errCh
+}
+
+// Update 更换过滤条件，切换期间的日志不遗漏也不重复
+func (s *Stream) Update(q ethereum.FilterQuery) error {
+	head, err := s.w.Client.BlockNumber(s.ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	<-s.done
+	// 旧 Follow 停止时丢弃了通道中尚未输出的日志，从尚未完全输出的区块重新开始
+	from := min(max(s.from, s.last), head)
+	if from == 0 {
+		from = head
+	}
+	q.FromBlock, q.ToBlock = new(big.Int).SetUint64(from), nil
+	s.follow(q)
+	return nil
+}
+
+// Stop 停止订阅
+func (s *Stream) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	<-s.done
+}
+
+// follow 启动一个 Follow，调用方持有 s.mu
+func (s *Stream) follow(q ethereum.FilterQuery) {
+	ctx, stop := context.WithCancel(s.ctx)
+	done := make(chan struct{})
+	if q.FromBlock != nil {
+		s.from = q.FromBlock.Uint64()
+	}
+	s.stop, s.done = stop, done
+	go func() {
+		defer close(done)
+		err := s.w.Follow(ctx, q, s.emit)
+		// 被 Update / Stop 主动停止时 ctx 已取消，不算出错
+		if ctx.Err() == nil {
+			select {
+			case s.errCh <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// emit 去重后把日志交给 fn；同一时间只有一个 Follow 在运行，不需要加锁
+func (s *Stream) emit(l types.Log) {
+	key := logKey{l.BlockHash, l.Index, l.Removed}
+	if _, ok := s.seen[key]; ok {
+		return
+	}
+	// 重组后又切回原来的区块时，同一条日志会先移除再重新出现，都需要输出
+	delete(s.seen, logKey{l.BlockHash, l.Index, !l.Removed})
+	s.seen[key] = l.BlockNumber
+	if l.BlockNumber > s.last {
+		s.last = l.BlockNumber
+		for k, n := range s.seen {
+			if n+dedupBlocks < s.last {
+				delete(s.seen, k)
+			}
+		}
+	}
+	s.fn(l)
+}
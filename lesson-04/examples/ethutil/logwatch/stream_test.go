@@ -0,0 +1,109 @@
+package logwatch
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TestStreamUpdateDuringBackfill 回填进行到一半时更换过滤条件：新条件从最后输出的区块重新回填，
+// 该区块中已经输出过的日志不重复，新增合约的日志不遗漏
+func TestStreamUpdateDuringBackfill(t *testing.T) {
+	chain := &fakeChain{head: 30, logs: []types.Log{
+		mkLog(addrA, 5, 0, 0), mkLog(addrB, 5, 1, 0),
+		mkLog(addrA, 15, 0, 0), mkLog(addrB, 15, 1, 0),
+		mkLog(addrA, 25, 0, 0), mkLog(addrB, 25, 1, 0),
+	}}
+	entered := make(chan struct{})
+	// 第二段 [10, 19] 一直阻塞，直到旧订阅被 Update 停止
+	chain.gate = func(ctx context.Context, call int) error {
+		if call == 2 {
+			close(entered)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+	w := &Watcher{Client: chain, Chunk: 10, MaxChunk: 10}
+	rec := &recorder{}
+	s := w.Start(context.Background(), ethereum.FilterQuery{FromBlock: big.NewInt(0), Addresses: []common.Address{addrA}}, rec.fn)
+	defer s.Stop()
+
+	<-entered
+	if err := s.Update(ethereum.FilterQuery{Addresses: []common.Address{addrA, addrB}}); err != nil {
+		t.Fatal(err)
+	}
+	chain.waitSubscribed(t, 2)
+	time.Sleep(30 * time.Millisecond)
+	chain.mine(31, mkLog(addrA, 31, 0, 0), mkLog(addrB, 31, 1, 0))
+
+	rec.wait(t, []string{"aa:5/0", "bb:5/1", "aa:15/0", "bb:15/1", "aa:25/0", "bb:25/1", "aa:31/0", "bb:31/1"})
+	select {
+	case err := <-s.Err():
+		t.Fatalf("unexpected stream error: %v", err)
+	default:
+	}
+}
+
+// TestStreamUpdateDuringLive 实时订阅中更换过滤条件：旧订阅已经推送、尚未输出的日志被丢弃后由新订阅回填，
+// 重新回填的区块中已经输出过的日志不重复
+func TestStreamUpdateDuringLive(t *testing.T) {
+	chain := &fakeChain{head: 10}
+	rec := &recorder{}
+	first, release := make(chan struct{}), make(chan struct{})
+	// 输出第一条日志时阻塞，让之后推送的日志停留在旧订阅的通道中
+	rec.hook = func(l types.Log) {
+		if l.BlockNumber == 11 && l.Index == 0 {
+			close(first)
+			<-release
+		}
+	}
+	s := New(chain).Start(context.Background(), ethereum.FilterQuery{}, rec.fn)
+	defer s.Stop()
+	chain.waitSubscribed(t, 1)
+
+	chain.mine(11, mkLog(addrA, 11, 0, 0), mkLog(addrA, 11, 1, 0))
+	<-first
+	chain.mine(12, mkLog(addrA, 12, 0, 0))
+	chain.mine(13, mkLog(addrA, 13, 0, 0))
+
+	updated := make(chan error, 1)
+	go func() { updated <- s.Update(ethereum.FilterQuery{}) }()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if err := <-updated; err != nil {
+		t.Fatal(err)
+	}
+	chain.waitSubscribed(t, 2)
+	time.Sleep(30 * time.Millisecond)
+	chain.mine(14, mkLog(addrA, 14, 0, 0))
+
+	rec.wait(t, []string{"aa:11/0", "aa:11/1", "aa:12/0", "aa:13/0", "aa:14/0"})
+}
+
+// TestStreamReorg 重组撤销的日志与原日志的区块哈希、索引相同，需要输出；切回原区块后同一条日志再次出现时也要输出，
+// 节点重复推送的日志只输出一次
+func TestStreamReorg(t *testing.T) {
+	chain := &fakeChain{head: 19}
+	rec := &recorder{}
+	s := New(chain).Start(context.Background(), ethereum.FilterQuery{}, rec.fn)
+	defer s.Stop()
+	chain.waitSubscribed(t, 1)
+
+	l := mkLog(addrA, 20, 0, 0)
+	removed := l
+	removed.Removed = true
+	chain.mine(20, l)
+	chain.push(l)
+	chain.push(removed)
+	chain.push(removed)
+	chain.push(l)
+	chain.push(l)
+
+	rec.wait(t, []string{"aa:20/0", "aa:20/0r", "aa:20/0"})
+}
@@ -0,0 +1,259 @@
+// Package mempool 通过 eth_subscribe("newPendingTransactions") 监听节点交易池中的待打包交易，
+// 按发送方 / 接收方、合约方法选择器和最小金额过滤，用于在交易被打包之前发现转入的充值。
+//   - Full 为 true 时请求完整交易体（geth 支持 newPendingTransactions 的 true 参数），省去逐笔查询
+//   - 节点不支持完整交易体时退回只订阅交易哈希，再由固定数量的 worker 通过 eth_getTransactionByHash 查询；
+//     查询队列有上限，交易池过于繁忙时丢弃新到的哈希并计数，而不是无限堆积
+package mempool
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 默认参数
+const (
+	DefaultWorkers   = 8
+	DefaultQueueSize = 1024
+)
+
+// Filter 待打包交易的过滤条件，各条件之间为 "且" 的关系，空条件不过滤
+type Filter struct {
+	From     map[common.Address]bool
+	To       map[common.Address]bool
+	Selector []byte   // 调用数据的前 4 字节
+	MinValue *big.Int // 最小转账金额（wei）
+}
+
+// ParseAddresses 解析逗号分隔的地址列表，空字符串返回 nil（不过滤）
+func ParseAddresses(s string) (map[common.Address]bool, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	set := make(map[common.Address]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if !common.IsHexAddress(part) {
+			return nil, fmt.Errorf("invalid address %q", part)
+		}
+		set[common.HexToAddress(part)] = true
+	}
+	return set, nil
+}
+
+// ParseSelector 解析 4 字节方法选择器（如 0xa9059cbb），空字符串返回 nil（不过滤）
+func ParseSelector(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "0x")
+	if s == "" {
+		return nil, nil
+	}
+	sel, err := hex.DecodeString(s)
+	if err != nil || len(sel) != 4 {
+		return nil, fmt.Errorf("invalid selector %q: expected 4 bytes hex", s)
+	}
+	return sel, nil
+}
+
+// Match 判断交易是否满足过滤条件
+func (f *Filter) Match(tx *types.Transaction, from common.Address) bool {
+	if len(f.From) > 0 && !f.From[from] {
+		return false
+	}
+	if len(f.To) > 0 && (tx.To() == nil || !f.To[*tx.To()]) {
+		return false
+	}
+	if len(f.Selector) > 0 && (len(tx.Data()) < 4 || !bytes.Equal(tx.Data()[:4], f.Selector)) {
+		return false
+	}
+	if f.MinValue != nil && tx.Value().Cmp(f.MinValue) < 0 {
+		return false
+	}
+	return true
+}
+
+// Pending 一笔满足过滤条件的待打包交易
+type Pending struct {
+	Tx     *types.Transaction
+	From   common.Address
+	SeenAt time.Time
+}
+
+// Stats 监听统计
+type Stats struct {
+	Seen    uint64 // 收到的待打包交易（或哈希）数量
+	Matched uint64 // 满足过滤条件的数量
+	Dropped uint64 // 查询队列已满而丢弃的哈希数量
+	Missing uint64 // 查询时已不在交易池中（已打包或被替换）的数量
+}
+
+// Watcher 待打包交易监听器，需要 WebSocket / IPC 连接
+type Watcher struct {
+	Client    *ethclient.Client
+	Filter    Filter
+	Full      bool      // 请求完整交易体
+	Workers   int       // 只有交易哈希时的查询并发数，为 0 时使用 DefaultWorkers
+	QueueSize int       // 等待查询的哈希队列长度，为 0 时使用 DefaultQueueSize
+	Out       io.Writer // 输出订阅模式切换等提示，为 nil 时不输出
+	signer    types.Signer
+	stats     Stats
+}
+
+// Stats 返回当前统计（可在 Run 运行期间调用）
+func (w *Watcher) Stats() Stats {
+	return Stats{
+		Seen:    atomic.LoadUint64(&w.stats.Seen),
+		Matched: atomic.LoadUint64(&w.stats.Matched),
+		Dropped: atomic.LoadUint64(&w.stats.Dropped),
+		Missing: atomic.LoadUint64(&w.stats.Missing),
+	}
+}
+
+// Run 开始监听，满足过滤条件的交易发送到 out；ctx 取消时返回 nil，订阅出错时返回错误
+func (w *Watcher) Run(ctx context.Context, out chan<- Pending) error {
+	chainID, err := w.Client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain id: %w", err)
+	}
+	w.signer = types.LatestSignerForChainID(chainID)
+	rpcClient := w.Client.Client()
+
+	if w.Full {
+		err := w.runFull(ctx, rpcClient, out)
+		if !errors.Is(err, errFullUnsupported) {
+			return err
+		}
+		w.logf("Node does not support full pending transactions (%v), falling back to hashes\n", err)
+	}
+	return w.runHashes(ctx, rpcClient, out)
+}
+
+// errFullUnsupported 节点不支持完整交易体订阅
+var errFullUnsupported = errors.New("full pending transactions not supported")
+
+// runFull 订阅完整交易体。不支持该参数的节点要么直接拒绝订阅，要么忽略参数推送交易哈希（解码失败导致订阅出错），
+// 两种情况在收到第一笔交易之前发生时都视为不支持
+func (w *Watcher) runFull(ctx context.Context, rpcClient *rpc.Client, out chan<- Pending) error {
+	txs := make(chan *types.Transaction, 256)
+	sub, err := rpcClient.EthSubscribe(ctx, txs, "newPendingTransactions", true)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errFullUnsupported, err)
+	}
+	defer sub.Unsubscribe()
+	w.logf("Subscribed to full pending transactions\n")
+
+	received := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			if !received {
+				return fmt.Errorf("%w: %v", errFullUnsupported, err)
+			}
+			return fmt.Errorf("pending transaction subscription failed: %w", err)
+		case tx := <-txs:
+			received = true
+			atomic.AddUint64(&w.stats.Seen, 1)
+			w.handle(ctx, tx, out)
+		}
+	}
+}
+
+// runHashes 订阅交易哈希，由 worker 池逐笔查询交易详情
+func (w *Watcher) runHashes(ctx context.Context, rpcClient *rpc.Client, out chan<- Pending) error {
+	hashes := make(chan common.Hash, 256)
+	sub, err := rpcClient.EthSubscribe(ctx, hashes, "newPendingTransactions")
+	if err != nil {
+		return fmt.Errorf("failed to subscribe pending transactions: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	workers, queueSize := w.Workers, w.QueueSize
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	w.logf("Subscribed to pending transaction hashes (%d workers)\n", workers)
+
+	queue := make(chan common.Hash, queueSize)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for hash := range queue {
+				w.fetch(ctx, hash, out)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(queue)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return fmt.Errorf("pending transaction subscription failed: %w", err)
+		case hash := <-hashes:
+			atomic.AddUint64(&w.stats.Seen, 1)
+			select {
+			case queue <- hash:
+			default:
+				atomic.AddUint64(&w.stats.Dropped, 1)
+			}
+		}
+	}
+}
+
+// fetch 查询交易详情并过滤；交易可能在查询前已被打包或替换，此时跳过
+func (w *Watcher) fetch(ctx context.Context, hash common.Hash, out chan<- Pending) {
+	if ctx.Err() != nil {
+		return
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	tx, isPending, err := w.Client.TransactionByHash(reqCtx, hash)
+	if err != nil || !isPending {
+		atomic.AddUint64(&w.stats.Missing, 1)
+		return
+	}
+	w.handle(ctx, tx, out)
+}
+
+// handle 恢复发送方并过滤，满足条件时发送到 out
+func (w *Watcher) handle(ctx context.Context, tx *types.Transaction, out chan<- Pending) {
+	from, err := types.Sender(w.signer, tx)
+	if err != nil {
+		return
+	}
+	if !w.Filter.Match(tx, from) {
+		return
+	}
+	atomic.AddUint64(&w.stats.Matched, 1)
+	select {
+	case out <- Pending{Tx: tx, From: from, SeenAt: time.Now()}:
+	case <-ctx.Done():
+	}
+}
+
+func (w *Watcher) logf(format string, args ...interface{}) {
+	if w.Out != nil {
+		fmt.Fprintf(w.Out, format, args...)
+	}
+}
@@ -0,0 +1,158 @@
+// Package msgsig 计算链下消息的签名哈希并验证签名：
+//   - EIP-191 personal_sign：keccak256("\x19Ethereum Signed Message:\n" + len(message) + message)
+//   - EIP-712 结构化数据：keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))，从 JSON 文件加载
+//   - 声明的签名者是 EOA 时用 ecrecover 恢复地址比对；是合约（智能合约钱包）时调用
+//     EIP-1271 的 isValidSignature(bytes32,bytes)，返回 0x1626ba7e 表示签名有效
+package msgsig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// 验证方式
+const (
+	MethodECRecover = "ecrecover"
+	MethodEIP1271   = "eip1271"
+)
+
+// EIP1271MagicValue isValidSignature 验证通过时返回的值：bytes4(keccak256("isValidSignature(bytes32,bytes)"))
+var EIP1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+// eip1271ABI 只包含 isValidSignature 的最小 ABI
+const eip1271ABI = `[{"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"name":"magicValue","type":"bytes4"}],"stateMutability":"view","type":"function"}]`
+
+// Client Verify 需要的节点接口，*ethclient.Client 满足该接口
+type Client interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// Result 验证结果
+type Result struct {
+	Valid     bool
+	Method    string         // MethodECRecover / MethodEIP1271
+	Recovered common.Address // ecrecover 恢复出的地址（EIP-1271 验证时为零地址）
+	Reason    string         // 验证失败的原因
+}
+
+// TextHash 计算 EIP-191 personal_sign 消息哈希
+func TextHash(message []byte) common.Hash {
+	return common.BytesToHash(accounts.TextHash(message))
+}
+
+// LoadTypedData 从 JSON 文件加载 EIP-712 结构化数据（与 eth_signTypedData_v4 的参数格式相同）
+func LoadTypedData(path string) (apitypes.TypedData, error) {
+	var data apitypes.TypedData
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return data, fmt.Errorf("failed to read typed data: %w", err)
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return data, fmt.Errorf("failed to parse typed data %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// TypedDataHash 计算 EIP-712 签名哈希
+func TypedDataHash(data apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// ParseSignature 解析 0x 开头的十六进制签名
+func ParseSignature(s string) ([]byte, error) {
+	sig, err := hexutil.Decode(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature hex: %w", err)
+	}
+	return sig, nil
+}
+
+// Recover 从 65 字节签名恢复签名者地址；v 可以是 0/1 或 27/28
+func Recover(hash common.Hash, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d, expected %d", len(sig), crypto.SignatureLength)
+	}
+	normalized := bytes.Clone(sig)
+	if v := normalized[crypto.RecoveryIDOffset]; v == 27 || v == 28 {
+		normalized[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash.Bytes(), normalized)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Verify 验证 claimed 是否为 hash 的签名者。client 不为 nil 且 claimed 地址上有合约代码时走 EIP-1271，
+// 否则走 ecrecover；client 为 nil 时只能验证 EOA 签名
+func Verify(ctx context.Context, client Client, claimed common.Address, hash common.Hash, sig []byte) (*Result, error) {
+	if client != nil {
+		code, err := client.CodeAt(ctx, claimed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get code at %s: %w", claimed.Hex(), err)
+		}
+		if len(code) > 0 {
+			return verifyEIP1271(ctx, client, claimed, hash, sig)
+		}
+	}
+
+	res := &Result{Method: MethodECRecover}
+	recovered, err := Recover(hash, sig)
+	if err != nil {
+		res.Reason = err.Error()
+		return res, nil
+	}
+	res.Recovered = recovered
+	res.Valid = recovered == claimed
+	if !res.Valid {
+		res.Reason = fmt.Sprintf("signature was made by %s", recovered.Hex())
+	}
+	return res, nil
+}
+
+// verifyEIP1271 调用合约钱包的 isValidSignature；调用回滚或返回值不是魔数都视为签名无效
+func verifyEIP1271(ctx context.Context, client Client, contract common.Address, hash common.Hash, sig []byte) (*Result, error) {
+	parsed, err := abi.JSON(strings.NewReader(eip1271ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse EIP-1271 ABI: %w", err)
+	}
+	input, err := parsed.Pack("isValidSignature", hash, sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack isValidSignature: %w", err)
+	}
+
+	res := &Result{Method: MethodEIP1271}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: input}, nil)
+	if err != nil {
+		res.Reason = fmt.Sprintf("isValidSignature call failed: %v", err)
+		return res, nil
+	}
+	// 返回值是 ABI 编码的 bytes4（左对齐补齐到 32 字节）
+	if len(out) < 4 {
+		res.Reason = fmt.Sprintf("unexpected isValidSignature result 0x%x", out)
+		return res, nil
+	}
+	res.Valid = bytes.Equal(out[:4], EIP1271MagicValue[:])
+	if !res.Valid {
+		res.Reason = fmt.Sprintf("isValidSignature returned 0x%x, expected 0x%x", out[:4], EIP1271MagicValue)
+	}
+	return res, nil
+}
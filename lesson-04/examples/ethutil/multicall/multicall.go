@@ -0,0 +1,266 @@
+// Package multicall 通过 Multicall3 合约的 aggregate3 把多个只读调用合并为一次 eth_call，
+// 每个调用单独允许失败（allowFailure = true），一个调用回滚不影响其它调用的结果。
+// Multicall3 部署在绝大多数 EVM 链的同一地址上（DefaultAddress）；链上没有部署时自动退回逐个 eth_call。
+package multicall
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultAddress Multicall3 的标准部署地址
+var DefaultAddress = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// DefaultBatchSize 单次 aggregate3 包含的最大调用数，避免超出节点的 eth_call Gas 上限
+const DefaultBatchSize = 500
+
+// multicall3ABI 只包含用到的 aggregate3 和 getEthBalance
+const multicall3ABI = `[
+  {"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],
+   "name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],
+   "stateMutability":"payable","type":"function"},
+  {"inputs":[{"name":"addr","type":"address"}],"name":"getEthBalance","outputs":[{"name":"balance","type":"uint256"}],"stateMutability":"view","type":"function"}
+]`
+
+var parsedABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// 常用 ERC-20 只读方法的选择器
+var (
+	selectorBalanceOf = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+	selectorDecimals  = crypto.Keccak256([]byte("decimals()"))[:4]
+)
+
+// Caller 需要的节点接口，*ethclient.Client 满足该接口
+type Caller interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// Call 一个只读调用
+type Call struct {
+	Target common.Address
+	Data   []byte
+}
+
+// Result 调用结果；Success 为 false 时 ReturnData 是回滚数据（逐个调用模式下为空，错误在 Err 中）
+type Result struct {
+	Success    bool
+	ReturnData []byte
+	Err        error
+}
+
+// Client Multicall3 客户端
+type Client struct {
+	caller    Caller
+	address   common.Address
+	BatchSize int // 为 0 时使用 DefaultBatchSize
+
+	mu        sync.Mutex
+	checked   bool // 成功检查过一次；出错时不缓存，下次调用重新检查
+	available bool
+}
+
+// New 使用标准地址的 Multicall3
+func New(caller Caller) *Client {
+	return NewAt(caller, DefaultAddress)
+}
+
+// NewAt 使用指定地址的 Multicall3（例如本地开发链上自行部署的合约）
+func NewAt(caller Caller, address common.Address) *Client {
+	return &Client{caller: caller, address: address}
+}
+
+// Available 检查 Multicall3 是否部署在当前链上（成功的结果会被缓存，出错时下次调用重试）
+func (c *Client) Available(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checked {
+		return c.available, nil
+	}
+	code, err := c.caller.CodeAt(ctx, c.address, nil)
+	if err != nil {
+		return false, err
+	}
+	c.checked, c.available = true, len(code) > 0
+	return c.available, nil
+}
+
+// Aggregate 执行一组只读调用，返回与 calls 一一对应的结果；blockNumber 为 nil 表示最新区块
+func (c *Client) Aggregate(ctx context.Context, calls []Call, blockNumber *big.Int) ([]Result, error) {
+	ok, err := c.Available(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check Multicall3 deployment: %w", err)
+	}
+	if !ok {
+		return c.individual(ctx, calls, blockNumber), nil
+	}
+
+	batch := c.BatchSize
+	if batch <= 0 {
+		batch = DefaultBatchSize
+	}
+	results := make([]Result, 0, len(calls))
+	for start := 0; start < len(calls); start += batch {
+		chunk, err := c.aggregate3(ctx, calls[start:min(start+batch, len(calls))], blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, chunk...)
+	}
+	return results, nil
+}
+
+// aggregate3 用一次 eth_call 执行一批调用
+func (c *Client) aggregate3(ctx context.Context, calls []Call, blockNumber *big.Int) ([]Result, error) {
+	type call3 struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	packedCalls := make([]call3, len(calls))
+	for i, call := range calls {
+		packedCalls[i] = call3{Target: call.Target, AllowFailure: true, CallData: call.Data}
+	}
+	input, err := parsedABI.Pack("aggregate3", packedCalls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3: %w", err)
+	}
+	output, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &c.address, Data: input}, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate3 call failed: %w", err)
+	}
+	unpacked, err := parsedABI.Unpack("aggregate3", output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3 result: %w", err)
+	}
+	type result3 struct {
+		Success    bool
+		ReturnData []byte
+	}
+	decoded := *abi.ConvertType(unpacked[0], new([]result3)).(*[]result3)
+	if len(decoded) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(decoded), len(calls))
+	}
+	results := make([]Result, len(decoded))
+	for i, r := range decoded {
+		results[i] = Result{Success: r.Success, ReturnData: r.ReturnData}
+		if !r.Success {
+			results[i].Err = fmt.Errorf("call to %s reverted", calls[i].Target.Hex())
+		}
+	}
+	return results, nil
+}
+
+// individual Multicall3 不可用时逐个调用
+func (c *Client) individual(ctx context.Context, calls []Call, blockNumber *big.Int) []Result {
+	results := make([]Result, len(calls))
+	for i, call := range calls {
+		target := call.Target
+		out, err := c.caller.CallContract(ctx, ethereum.CallMsg{To: &target, Data: call.Data}, blockNumber)
+		results[i] = Result{Success: err == nil, ReturnData: out, Err: err}
+	}
+	return results
+}
+
+// Balance 一个 (代币, 持有人) 的余额查询结果
+type Balance struct {
+	Token    common.Address // 零地址表示 ETH
+	Owner    common.Address
+	Amount   *big.Int // 查询失败时为 nil
+	Decimals int      // 代币精度，decimals() 调用失败时为 -1；ETH 为 18
+	Err      error
+}
+
+// Balances 批量查询 tokens × owners 的余额，每个代币的 decimals() 也在同一批调用中查询。
+// tokens 中的零地址表示 ETH 余额（通过 Multicall3 的 getEthBalance，或退回 eth_getBalance）
+func (c *Client) Balances(ctx context.Context, tokens, owners []common.Address) ([]Balance, error) {
+	ok, err := c.Available(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check Multicall3 deployment: %w", err)
+	}
+
+	// 调用顺序：每个代币先 decimals()，再逐个 balanceOf(owner)
+	var calls []Call
+	for _, token := range tokens {
+		if token == (common.Address{}) {
+			if !ok {
+				continue // ETH 余额退回 eth_getBalance，不经过 Aggregate
+			}
+			for _, owner := range owners {
+				data, err := parsedABI.Pack("getEthBalance", owner)
+				if err != nil {
+					return nil, err
+				}
+				calls = append(calls, Call{Target: c.address, Data: data})
+			}
+			continue
+		}
+		calls = append(calls, Call{Target: token, Data: selectorDecimals})
+		for _, owner := range owners {
+			calls = append(calls, Call{Target: token, Data: append(append([]byte{}, selectorBalanceOf...), common.LeftPadBytes(owner.Bytes(), 32)...)})
+		}
+	}
+	results, err := c.Aggregate(ctx, calls, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]Balance, 0, len(tokens)*len(owners))
+	next := 0
+	for _, token := range tokens {
+		decimals := 18
+		if token == (common.Address{}) && !ok {
+			for _, owner := range owners {
+				amount, err := c.caller.BalanceAt(ctx, owner, nil)
+				balances = append(balances, Balance{Token: token, Owner: owner, Amount: amount, Decimals: decimals, Err: err})
+			}
+			continue
+		}
+		if token != (common.Address{}) {
+			decimals = decodeDecimals(results[next])
+			next++
+		}
+		for _, owner := range owners {
+			b := Balance{Token: token, Owner: owner, Decimals: decimals}
+			b.Amount, b.Err = decodeUint256(results[next])
+			next++
+			balances = append(balances, b)
+		}
+	}
+	return balances, nil
+}
+
+// decodeUint256 解码返回单个 uint256 的调用结果
+func decodeUint256(r Result) (*big.Int, error) {
+	if !r.Success {
+		return nil, r.Err
+	}
+	if len(r.ReturnData) < 32 {
+		return nil, fmt.Errorf("unexpected return data 0x%x", r.ReturnData)
+	}
+	return new(big.Int).SetBytes(r.ReturnData[:32]), nil
+}
+
+// decodeDecimals 解码 decimals() 的返回值，失败或超出 uint8 时返回 -1
+func decodeDecimals(r Result) int {
+	v, err := decodeUint256(r)
+	if err != nil || !v.IsUint64() || v.Uint64() > 255 {
+		return -1
+	}
+	return int(v.Uint64())
+}
@@ -0,0 +1,66 @@
+package multicall
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fakeCaller CodeAt 依次返回 errs 中的错误，用完之后返回 code
+type fakeCaller struct {
+	code  []byte
+	errs  []error
+	calls int
+}
+
+func (f *fakeCaller) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeCaller) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return f.code, nil
+}
+
+func (f *fakeCaller) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	return nil, errors.New("not implemented")
+}
+
+// TestAvailable 检查出错时不缓存，下次调用重试；成功之后不再查询
+func TestAvailable(t *testing.T) {
+	tests := []struct {
+		name string
+		code []byte
+		errs []error
+		want []bool // 每次调用的结果
+		fail []bool // 每次调用是否返回错误
+	}{
+		{"deployed", []byte{0x60}, nil, []bool{true, true}, []bool{false, false}},
+		{"not deployed", nil, nil, []bool{false, false}, []bool{false, false}},
+		{"retry after error", []byte{0x60}, []error{errors.New("timeout"), errors.New("timeout")}, []bool{false, false, true, true}, []bool{true, true, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := &fakeCaller{code: tt.code, errs: tt.errs}
+			c := New(caller)
+			for i := range tt.want {
+				got, err := c.Available(context.Background())
+				if (err != nil) != tt.fail[i] || got != tt.want[i] {
+					t.Fatalf("call %d: Available() = %v, %v; want %v, error %v", i, got, err, tt.want[i], tt.fail[i])
+				}
+			}
+			if want := len(tt.errs) + 1; caller.calls != want {
+				t.Errorf("CodeAt called %d times, want %d", caller.calls, want)
+			}
+		})
+	}
+}
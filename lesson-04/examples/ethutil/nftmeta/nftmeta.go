@@ -0,0 +1,154 @@
+// Package nftmeta 解析 NFT 的 tokenURI / uri 并获取元数据 JSON：
+//   - http(s)://：直接请求
+//   - ipfs://<cid>/<path>（以及 ipfs://ipfs/<cid>）：改写为 IPFS 网关 URL 后请求
+//   - data:application/json;base64,... 和 data:application/json,...：链上元数据，直接解码
+//   - ERC-1155 的 {id} 占位符替换为 64 位小写十六进制 token ID（不带 0x，见 ExpandID）
+package nftmeta
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultGateway 默认的 IPFS 网关
+const DefaultGateway = "https://ipfs.io/ipfs/"
+
+// EnvGateway 未指定网关时读取的环境变量
+const EnvGateway = "IPFS_GATEWAY"
+
+// DefaultMaxSize 元数据 JSON 的最大字节数，避免恶意 URI 指向超大文件
+const DefaultMaxSize = 1 << 20
+
+// Metadata ERC-721 / ERC-1155 元数据中的常用字段（OpenSea 约定），完整内容见 Raw
+type Metadata struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Image       string          `json:"image"`
+	ExternalURL string          `json:"external_url"`
+	Attributes  []Attribute     `json:"attributes"`
+	Raw         json.RawMessage `json:"-"`
+}
+
+// Attribute 元数据中的一个属性，value 可能是字符串或数字
+type Attribute struct {
+	TraitType string      `json:"trait_type"`
+	Value     interface{} `json:"value"`
+}
+
+// Fetcher 元数据获取器，零值可用
+type Fetcher struct {
+	HTTP    *http.Client // 为 nil 时使用 15 秒超时的默认客户端
+	Gateway string       // IPFS 网关，为空时使用 $IPFS_GATEWAY 或 DefaultGateway
+	MaxSize int64        // 为 0 时使用 DefaultMaxSize
+}
+
+// ExpandID 替换 ERC-1155 URI 中的 {id} 占位符（EIP-1155：64 位小写十六进制，左侧补 0）
+func ExpandID(uri string, id *big.Int) string {
+	if !strings.Contains(uri, "{id}") {
+		return uri
+	}
+	return strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%064x", id))
+}
+
+// ResolveURI 把 ipfs:// URI 改写为网关 URL，其它 URI 原样返回；gateway 为空时使用 $IPFS_GATEWAY 或 DefaultGateway
+func ResolveURI(uri, gateway string) string {
+	if gateway == "" {
+		gateway = os.Getenv(EnvGateway)
+	}
+	if gateway == "" {
+		gateway = DefaultGateway
+	}
+	if !strings.HasSuffix(gateway, "/") {
+		gateway += "/"
+	}
+	if rest, ok := strings.CutPrefix(uri, "ipfs://"); ok {
+		return gateway + strings.TrimPrefix(rest, "ipfs/")
+	}
+	return uri
+}
+
+// Fetch 获取并解析 uri 指向的元数据
+func (f *Fetcher) Fetch(ctx context.Context, uri string) (*Metadata, error) {
+	raw, err := f.load(ctx, strings.TrimSpace(uri))
+	if err != nil {
+		return nil, err
+	}
+	var meta Metadata
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
+	}
+	meta.Raw = raw
+	return &meta, nil
+}
+
+// load 按 URI 的 scheme 读取原始内容
+func (f *Fetcher) load(ctx context.Context, uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		return decodeDataURI(uri)
+	}
+	resolved := ResolveURI(uri, f.Gateway)
+	u, err := url.Parse(resolved)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("unsupported metadata URI %q", uri)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resolved, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	client := f.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", resolved, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", resolved, resp.Status)
+	}
+
+	maxSize := f.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", resolved, err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("metadata at %s exceeds %d bytes", resolved, maxSize)
+	}
+	return body, nil
+}
+
+// decodeDataURI 解码 RFC 2397 data URI：data:[<mediatype>][;base64],<data>
+func decodeDataURI(uri string) ([]byte, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid data URI")
+	}
+	if strings.HasSuffix(header, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 in data URI: %w", err)
+		}
+		return decoded, nil
+	}
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data URI: %w", err)
+	}
+	return []byte(decoded), nil
+}
@@ -0,0 +1,138 @@
+package nftmeta
+
+import (
+	"context"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpandID(t *testing.T) {
+	tests := []struct {
+		uri  string
+		id   *big.Int
+		want string
+	}{
+		{"https://example.com/{id}.json", big.NewInt(1), "https://example.com/" + strings.Repeat("0", 63) + "1.json"},
+		{"https://example.com/{id}.json", big.NewInt(0x4cce), "https://example.com/" + strings.Repeat("0", 60) + "4cce.json"},
+		{"ipfs://cid/{id}/{id}", big.NewInt(255), "ipfs://cid/" + strings.Repeat("0", 62) + "ff/" + strings.Repeat("0", 62) + "ff"},
+		{"https://example.com/1.json", big.NewInt(7), "https://example.com/1.json"},
+	}
+	for _, tt := range tests {
+		if got := ExpandID(tt.uri, tt.id); got != tt.want {
+			t.Errorf("ExpandID(%q, %s) = %q, want %q", tt.uri, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestResolveURI(t *testing.T) {
+	t.Setenv(EnvGateway, "")
+	tests := []struct {
+		uri, gateway, want string
+	}{
+		{"ipfs://QmCid/1.json", "", DefaultGateway + "QmCid/1.json"},
+		{"ipfs://ipfs/QmCid/1.json", "", DefaultGateway + "QmCid/1.json"},
+		{"ipfs://QmCid", "https://gw.example/ipfs", "https://gw.example/ipfs/QmCid"},
+		{"ipfs://QmCid", "https://gw.example/ipfs/", "https://gw.example/ipfs/QmCid"},
+		{"https://example.com/1.json", "https://gw.example/ipfs/", "https://example.com/1.json"},
+		{"data:application/json,{}", "", "data:application/json,{}"},
+	}
+	for _, tt := range tests {
+		if got := ResolveURI(tt.uri, tt.gateway); got != tt.want {
+			t.Errorf("ResolveURI(%q, %q) = %q, want %q", tt.uri, tt.gateway, got, tt.want)
+		}
+	}
+
+	// 未指定网关时读取 $IPFS_GATEWAY
+	t.Setenv(EnvGateway, "https://env.example/ipfs")
+	if got, want := ResolveURI("ipfs://QmCid", ""), "https://env.example/ipfs/QmCid"; got != want {
+		t.Errorf("ResolveURI with %s = %q, want %q", EnvGateway, got, want)
+	}
+}
+
+func TestDecodeDataURI(t *testing.T) {
+	const doc = `{"name":"Token #1"}`
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{"data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(doc)), doc, false},
+		{"data:application/json," + doc, doc, false},
+		{"data:application/json;utf8,%7B%22name%22%3A%22Token%20%231%22%7D", doc, false},
+		{"data:,", "", false},
+		{"data:application/json;base64,!!!", "", true},
+		{"data:application/json", "", true},
+		{"data:application/json,%zz", "", true},
+	}
+	for _, tt := range tests {
+		got, err := decodeDataURI(tt.uri)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("decodeDataURI(%q) error = %v, wantErr %v", tt.uri, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && string(got) != tt.want {
+			t.Errorf("decodeDataURI(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	const doc = `{"name":"Token #1","image":"ipfs://QmImage","attributes":[{"trait_type":"level","value":3}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/meta/1.json", "/ipfs/QmCid/1.json":
+			w.Write([]byte(doc))
+		case "/large.json":
+			w.Write([]byte(`{"name":"` + strings.Repeat("x", 200) + `"}`))
+		case "/broken.json":
+			w.Write([]byte(`{"name":`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{HTTP: srv.Client(), Gateway: srv.URL + "/ipfs/", MaxSize: 128}
+	ctx := context.Background()
+
+	for _, uri := range []string{
+		srv.URL + "/meta/1.json",
+		"ipfs://QmCid/1.json", // 改写为网关 URL
+		"ipfs://ipfs/QmCid/1.json",
+		"data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(doc)),
+	} {
+		meta, err := f.Fetch(ctx, uri)
+		if err != nil {
+			t.Errorf("Fetch(%q): %v", uri, err)
+			continue
+		}
+		if meta.Name != "Token #1" || meta.Image != "ipfs://QmImage" || len(meta.Attributes) != 1 || string(meta.Raw) != doc {
+			t.Errorf("Fetch(%q) = %+v", uri, meta)
+		}
+	}
+
+	errTests := []struct {
+		uri, want string
+	}{
+		{srv.URL + "/missing.json", "404"},
+		{srv.URL + "/large.json", "exceeds 128 bytes"},
+		{srv.URL + "/broken.json", "failed to parse metadata JSON"},
+		{"ftp://example.com/1.json", "unsupported metadata URI"},
+	}
+	for _, tt := range errTests {
+		_, err := f.Fetch(ctx, tt.uri)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Fetch(%q) error = %v, want %q", tt.uri, err, tt.want)
+		}
+	}
+
+	// 未超过 MaxSize 时正常返回
+	f.MaxSize = 1 << 10
+	if _, err := f.Fetch(ctx, srv.URL+"/large.json"); err != nil {
+		t.Errorf("Fetch large.json with MaxSize %d: %v", f.MaxSize, err)
+	}
+}
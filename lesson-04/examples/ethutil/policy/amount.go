@@ -0,0 +1,42 @@
+package policy
+
+import (
+	"math/big"
+	"strings"
+
+	"ethutil/units"
+)
+
+// parseAmount 解析策略中的 ETH / gwei 金额，支持单位后缀（如 "100gwei"、"0.5ether"）；空字符串返回 nil（未配置）
+func parseAmount(s string, decimals int) (*big.Int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return units.ParseWithUnit(s, decimals)
+}
+
+// parseTokenAmount 解析代币额度（代币单位，不支持后缀）；空字符串返回 nil（未配置）
+func parseTokenAmount(s string, decimals int) (*big.Int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return units.Parse(s, decimals)
+}
+
+// mustAmount 解析已经在 Load 时校验过的 ETH / gwei 金额
+func mustAmount(s string, decimals int) *big.Int {
+	v, err := parseAmount(s, decimals)
+	if err != nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+// mustTokenAmount 解析代币额度；额度的小数位数超过代币精度时视为 0 上限，保守地拒绝转账
+func mustTokenAmount(s string, decimals int) *big.Int {
+	v, err := parseTokenAmount(s, decimals)
+	if err != nil {
+		return new(big.Int)
+	}
+	return v
+}
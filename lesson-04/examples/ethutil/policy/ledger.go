@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// dailyWindow 每日额度的统计窗口（滚动 24 小时，而不是自然日，避免零点前后连续大额转出）
+const dailyWindow = 24 * time.Hour
+
+// ledgerEntry 一笔已广播交易的支出记录
+type ledgerEntry struct {
+	Time    time.Time       `json:"time"`
+	ChainID string          `json:"chain_id"`
+	From    common.Address  `json:"from"`
+	Token   *common.Address `json:"token,omitempty"` // nil 表示 ETH
+	Amount  string          `json:"amount"`          // 最小单位
+	TxHash  common.Hash     `json:"tx_hash"`
+}
+
+// ledger 每日额度账本（JSON 文件）
+type ledger struct {
+	Entries []ledgerEntry `json:"entries"`
+}
+
+func loadLedger(path string) (*ledger, error) {
+	var l ledger
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spend ledger: %w", err)
+	}
+	if err := json.Unmarshal(raw, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse spend ledger %s: %w", path, err)
+	}
+	return &l, nil
+}
+
+// spent 统计窗口内某账户在某条链上的支出；token 为 nil 时统计 ETH
+func (l *ledger) spent(chainID *big.Int, from common.Address, token *common.Address) *big.Int {
+	total := new(big.Int)
+	since := time.Now().Add(-dailyWindow)
+	for _, e := range l.Entries {
+		if e.Time.Before(since) || e.ChainID != chainID.String() || e.From != from {
+			continue
+		}
+		if (token == nil) != (e.Token == nil) || (token != nil && *token != *e.Token) {
+			continue
+		}
+		if v, ok := new(big.Int).SetString(e.Amount, 10); ok {
+			total.Add(total, v)
+		}
+	}
+	return total
+}
+
+// add 记录一笔交易：ETH 金额和代币金额分别记账；同时清理超出窗口的旧记录
+func (l *ledger) add(tx *Tx, txHash common.Hash) {
+	now := time.Now().UTC()
+	kept := l.Entries[:0]
+	for _, e := range l.Entries {
+		if now.Sub(e.Time) <= dailyWindow {
+			kept = append(kept, e)
+		}
+	}
+	l.Entries = kept
+
+	if tx.Value != nil && tx.Value.Sign() > 0 {
+		l.Entries = append(l.Entries, ledgerEntry{Time: now, ChainID: tx.ChainID.String(), From: tx.From, Amount: tx.Value.String(), TxHash: txHash})
+	}
+	if tx.Token != nil && tx.Token.Amount.Sign() > 0 {
+		token := tx.Token.Contract
+		l.Entries = append(l.Entries, ledgerEntry{Time: now, ChainID: tx.ChainID.String(), From: tx.From, Token: &token, Amount: tx.Token.Amount.String(), TxHash: txHash})
+	}
+}
+
+// save 先写临时文件再重命名，避免中途退出导致账本损坏
+func (l *ledger) save(path string) error {
+	raw, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode spend ledger: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write spend ledger: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write spend ledger: %w", err)
+	}
+	return nil
+}
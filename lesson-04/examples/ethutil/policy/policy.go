@@ -0,0 +1,434 @@
+// Package policy 在交易签名和广播之前执行本地安全策略（guardrails），防止误操作：
+// 链 ID 白名单、单笔/每日转账上限、收款地址白名单/黑名单、Gas 费用上限、大额强制交互确认。
+//
+// 策略文件为 JSON，所有字段都是可选的，未配置的规则不生效：
+//
+//	{
+//	  "chain_ids": [1, 11155111, 31337],
+//	  "max_value_per_tx": "1.5",
+//	  "max_daily_value": "5",
+//	  "allow_recipients": ["0x70997970C51812dc3A010C7d01b50e0d17dc79C8"],
+//	  "deny_recipients": [],
+//	  "max_fee_per_gas": "100",
+//	  "max_priority_fee_per_gas": "5",
+//	  "max_tx_fee": "0.05",
+//	  "confirm_above": "0.5",
+//	  "allow_deploy": true,
+//	  "tokens": {
+//	    "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48": {"max_per_tx": "1000", "max_daily": "5000", "confirm_above": "100"}
+//	  },
+//	  "ledger_file": "spend-ledger.json"
+//	}
+//
+// 单位：ETH 金额（max_value_per_tx、max_daily_value、max_tx_fee、confirm_above）默认以 ETH 计，
+// 费率（max_fee_per_gas、max_priority_fee_per_gas）默认以 gwei 计，两者都可以带单位后缀（如 "0.5ether"、"30gwei"）；
+// tokens 中的额度以代币单位计（按 decimals 换算）。
+// 每日额度按发送账户统计最近 24 小时内已广播的交易，记录在 ledger_file（相对路径基于策略文件所在目录）；
+// max_tx_fee 限制最坏情况下的手续费 gasLimit * maxFeePerGas；超过 confirm_above 时即使指定 --yes 也必须交互确认。
+//
+// 违反策略时返回 *Rejection，其中每条 Violation 都带有稳定的错误码，可以通过 PrintRejection 输出为 JSON。
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethutil/units"
+)
+
+// EnvFile 未指定 --policy 时读取的环境变量
+const EnvFile = "TX_POLICY_FILE"
+
+// 交易类型
+const (
+	KindETHTransfer   = "eth-transfer"
+	KindERC20Transfer = "erc20-transfer"
+	KindDeploy        = "deploy"
+	KindContractCall  = "contract-call"
+)
+
+// 违规错误码
+const (
+	CodeChainNotAllowed     = "CHAIN_NOT_ALLOWED"
+	CodeValuePerTxExceeded  = "VALUE_PER_TX_EXCEEDED"
+	CodeDailyValueExceeded  = "DAILY_VALUE_EXCEEDED"
+	CodeRecipientDenied     = "RECIPIENT_DENIED"
+	CodeRecipientNotAllowed = "RECIPIENT_NOT_ALLOWED"
+	CodeFeeCapExceeded      = "FEE_CAP_EXCEEDED"
+	CodeTipCapExceeded      = "TIP_CAP_EXCEEDED"
+	CodeTxFeeExceeded       = "TX_FEE_EXCEEDED"
+	CodeDeployNotAllowed    = "DEPLOY_NOT_ALLOWED"
+	CodeTokenPerTxExceeded  = "TOKEN_PER_TX_EXCEEDED"
+	CodeTokenDailyExceeded  = "TOKEN_DAILY_EXCEEDED"
+)
+
+// TokenLimit 单个代币的额度限制（代币单位，按 decimals 换算）
+type TokenLimit struct {
+	MaxPerTx     string `json:"max_per_tx,omitempty"`
+	MaxDaily     string `json:"max_daily,omitempty"`
+	ConfirmAbove string `json:"confirm_above,omitempty"`
+}
+
+// Policy 策略文件内容
+type Policy struct {
+	ChainIDs             []uint64              `json:"chain_ids,omitempty"`
+	MaxValuePerTx        string                `json:"max_value_per_tx,omitempty"`
+	MaxDailyValue        string                `json:"max_daily_value,omitempty"`
+	AllowRecipients      []common.Address      `json:"allow_recipients,omitempty"`
+	DenyRecipients       []common.Address      `json:"deny_recipients,omitempty"`
+	MaxFeePerGas         string                `json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas string                `json:"max_priority_fee_per_gas,omitempty"`
+	MaxTxFee             string                `json:"max_tx_fee,omitempty"`
+	ConfirmAbove         string                `json:"confirm_above,omitempty"`
+	AllowDeploy          *bool                 `json:"allow_deploy,omitempty"`
+	Tokens               map[string]TokenLimit `json:"tokens,omitempty"`
+	LedgerFile           string                `json:"ledger_file,omitempty"`
+
+	path string
+}
+
+// TokenTransfer ERC-20 转账信息
+type TokenTransfer struct {
+	Contract  common.Address
+	Recipient common.Address
+	Amount    *big.Int // 最小单位
+	Decimals  uint8
+}
+
+// Tx 待检查的交易
+type Tx struct {
+	Kind        string
+	ChainID     *big.Int
+	From        common.Address
+	To          *common.Address // 合约部署时为 nil
+	Value       *big.Int        // wei
+	GasLimit    uint64
+	GasFeeCap   *big.Int // EIP-1559 maxFeePerGas，传统交易为 gasPrice
+	GasTipCap   *big.Int // 传统交易为 nil
+	Token       *TokenTransfer
+	Beneficiary *common.Address // 合约调用的实际接收方（NFT 接收地址、授权对象），不为 nil 时优先于 Token 和 To
+}
+
+// Recipient 资金的实际接收方：指定了 Beneficiary 时为 Beneficiary，ERC-20 转账为代币接收地址，否则为交易 To
+func (t *Tx) Recipient() *common.Address {
+	if t.Beneficiary != nil {
+		return t.Beneficiary
+	}
+	if t.Token != nil {
+		return &t.Token.Recipient
+	}
+	return t.To
+}
+
+// Violation 一条违规记录，Limit / Actual 使用与策略文件相同的单位
+type Violation struct {
+	Code    string `json:"code"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Limit   string `json:"limit,omitempty"`
+	Actual  string `json:"actual,omitempty"`
+}
+
+// Rejection 交易被策略拒绝，包含所有违反的规则（而不仅是第一条）
+type Rejection struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (r *Rejection) Error() string {
+	msgs := make([]string, len(r.Violations))
+	for i, v := range r.Violations {
+		msgs[i] = v.Code + ": " + v.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Decision 通过检查后的附加要求
+type Decision struct {
+	// ConfirmRequired 为 true 时必须交互确认，--yes 不能跳过
+	ConfirmRequired bool
+	ConfirmReason   string
+}
+
+// Load 读取策略文件；path 为空时读取 TX_POLICY_FILE，仍为空则返回 nil（不启用策略）
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		path = os.Getenv(EnvFile)
+	}
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var p Policy
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields() // 拼错的规则名直接报错，避免规则静默失效
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	p.path = path
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// validate 提前解析所有金额字段，避免检查时才发现格式错误
+func (p *Policy) validate() error {
+	fields := []struct {
+		name, value string
+		decimals    int
+	}{
+		{"max_value_per_tx", p.MaxValuePerTx, units.EtherDecimals},
+		{"max_daily_value", p.MaxDailyValue, units.EtherDecimals},
+		{"max_fee_per_gas", p.MaxFeePerGas, units.GweiDecimals},
+		{"max_priority_fee_per_gas", p.MaxPriorityFeePerGas, units.GweiDecimals},
+		{"max_tx_fee", p.MaxTxFee, units.EtherDecimals},
+		{"confirm_above", p.ConfirmAbove, units.EtherDecimals},
+	}
+	for _, f := range fields {
+		if _, err := parseAmount(f.value, f.decimals); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	for token, l := range p.Tokens {
+		if !common.IsHexAddress(token) {
+			return fmt.Errorf("tokens: invalid token address %q", token)
+		}
+		// 代币精度在检查时才知道，这里按额度本身的小数位数只校验数字格式
+		for name, v := range map[string]string{"max_per_tx": l.MaxPerTx, "max_daily": l.MaxDaily, "confirm_above": l.ConfirmAbove} {
+			_, frac, _ := strings.Cut(strings.TrimSpace(v), ".")
+			if _, err := parseTokenAmount(v, len(frac)); err != nil {
+				return fmt.Errorf("tokens.%s.%s: %w", token, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// tokenLimit 查找代币的额度配置（地址大小写不敏感）
+func (p *Policy) tokenLimit(token common.Address) (TokenLimit, bool) {
+	for k, l := range p.Tokens {
+		if common.HexToAddress(k) == token {
+			return l, true
+		}
+	}
+	return TokenLimit{}, false
+}
+
+// Check 检查交易是否符合策略。p 为 nil 时总是通过；
+// 违规时返回 *Rejection，daily 额度需要读取账本，账本损坏等情况返回普通 error
+func (p *Policy) Check(tx *Tx) (*Decision, error) {
+	decision := &Decision{}
+	if p == nil {
+		return decision, nil
+	}
+	var violations []Violation
+	add := func(code, rule, limit, actual, format string, args ...interface{}) {
+		violations = append(violations, Violation{Code: code, Rule: rule, Message: fmt.Sprintf(format, args...), Limit: limit, Actual: actual})
+	}
+	value := tx.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+
+	// 链 ID 白名单
+	if len(p.ChainIDs) > 0 {
+		allowed := false
+		for _, id := range p.ChainIDs {
+			if tx.ChainID != nil && tx.ChainID.IsUint64() && tx.ChainID.Uint64() == id {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			add(CodeChainNotAllowed, "chain_ids", fmt.Sprint(p.ChainIDs), fmt.Sprint(tx.ChainID),
+				"chain id %v is not in the allowlist", tx.ChainID)
+		}
+	}
+
+	// 合约部署 / 收款地址
+	if tx.Kind == KindDeploy {
+		if p.AllowDeploy != nil && !*p.AllowDeploy {
+			add(CodeDeployNotAllowed, "allow_deploy", "false", "", "contract deployments are disabled by policy")
+		}
+	} else if to := tx.Recipient(); to != nil {
+		if containsAddr(p.DenyRecipients, *to) {
+			add(CodeRecipientDenied, "deny_recipients", "", to.Hex(), "recipient %s is on the denylist", to.Hex())
+		}
+		if len(p.AllowRecipients) > 0 && !containsAddr(p.AllowRecipients, *to) {
+			add(CodeRecipientNotAllowed, "allow_recipients", "", to.Hex(), "recipient %s is not on the allowlist", to.Hex())
+		}
+	}
+
+	// ETH 单笔上限
+	if limit := mustAmount(p.MaxValuePerTx, units.EtherDecimals); limit != nil && value.Cmp(limit) > 0 {
+		add(CodeValuePerTxExceeded, "max_value_per_tx", p.MaxValuePerTx, units.Format(value, units.EtherDecimals),
+			"value %s ETH exceeds the per-transaction cap of %s ETH", units.Format(value, units.EtherDecimals), p.MaxValuePerTx)
+	}
+
+	// Gas 费用上限
+	if limit := mustAmount(p.MaxFeePerGas, units.GweiDecimals); limit != nil && tx.GasFeeCap != nil && tx.GasFeeCap.Cmp(limit) > 0 {
+		add(CodeFeeCapExceeded, "max_fee_per_gas", p.MaxFeePerGas, units.Format(tx.GasFeeCap, units.GweiDecimals),
+			"max fee per gas %s gwei exceeds the cap of %s gwei", units.Format(tx.GasFeeCap, units.GweiDecimals), p.MaxFeePerGas)
+	}
+	if limit := mustAmount(p.MaxPriorityFeePerGas, units.GweiDecimals); limit != nil && tx.GasTipCap != nil && tx.GasTipCap.Cmp(limit) > 0 {
+		add(CodeTipCapExceeded, "max_priority_fee_per_gas", p.MaxPriorityFeePerGas, units.Format(tx.GasTipCap, units.GweiDecimals),
+			"priority fee %s gwei exceeds the cap of %s gwei", units.Format(tx.GasTipCap, units.GweiDecimals), p.MaxPriorityFeePerGas)
+	}
+	if limit := mustAmount(p.MaxTxFee, units.EtherDecimals); limit != nil && tx.GasFeeCap != nil {
+		fee := new(big.Int).Mul(tx.GasFeeCap, new(big.Int).SetUint64(tx.GasLimit))
+		if fee.Cmp(limit) > 0 {
+			add(CodeTxFeeExceeded, "max_tx_fee", p.MaxTxFee, units.Format(fee, units.EtherDecimals),
+				"worst-case fee %s ETH (gas limit %d) exceeds the cap of %s ETH", units.Format(fee, units.EtherDecimals), tx.GasLimit, p.MaxTxFee)
+		}
+	}
+
+	// 代币单笔上限
+	var tokenLimit TokenLimit
+	hasTokenLimit := false
+	if tx.Token != nil {
+		tokenLimit, hasTokenLimit = p.tokenLimit(tx.Token.Contract)
+		dec := int(tx.Token.Decimals)
+		if limit := mustTokenAmount(tokenLimit.MaxPerTx, dec); hasTokenLimit && limit != nil && tx.Token.Amount.Cmp(limit) > 0 {
+			add(CodeTokenPerTxExceeded, "tokens."+tx.Token.Contract.Hex()+".max_per_tx", tokenLimit.MaxPerTx, units.Format(tx.Token.Amount, dec),
+				"token amount %s exceeds the per-transaction cap of %s", units.Format(tx.Token.Amount, dec), tokenLimit.MaxPerTx)
+		}
+	}
+
+	// 每日额度：需要读取账本
+	dailyETH := mustAmount(p.MaxDailyValue, units.EtherDecimals)
+	var dailyToken *big.Int
+	if hasTokenLimit {
+		dailyToken = mustTokenAmount(tokenLimit.MaxDaily, int(tx.Token.Decimals))
+	}
+	if dailyETH != nil || dailyToken != nil {
+		ledger, err := loadLedger(p.ledgerPath())
+		if err != nil {
+			return nil, err
+		}
+		if dailyETH != nil && value.Sign() > 0 {
+			spent := ledger.spent(tx.ChainID, tx.From, nil)
+			total := new(big.Int).Add(spent, value)
+			if total.Cmp(dailyETH) > 0 {
+				add(CodeDailyValueExceeded, "max_daily_value", p.MaxDailyValue, units.Format(total, units.EtherDecimals),
+					"24h total %s ETH (already sent %s ETH) would exceed the daily cap of %s ETH",
+					units.Format(total, units.EtherDecimals), units.Format(spent, units.EtherDecimals), p.MaxDailyValue)
+			}
+		}
+		if dailyToken != nil {
+			dec := int(tx.Token.Decimals)
+			spent := ledger.spent(tx.ChainID, tx.From, &tx.Token.Contract)
+			total := new(big.Int).Add(spent, tx.Token.Amount)
+			if total.Cmp(dailyToken) > 0 {
+				add(CodeTokenDailyExceeded, "tokens."+tx.Token.Contract.Hex()+".max_daily", tokenLimit.MaxDaily, units.Format(total, dec),
+					"24h token total %s (already sent %s) would exceed the daily cap of %s",
+					units.Format(total, dec), units.Format(spent, dec), tokenLimit.MaxDaily)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, &Rejection{Violations: violations}
+	}
+
+	// 大额强制确认
+	if limit := mustAmount(p.ConfirmAbove, units.EtherDecimals); limit != nil && value.Cmp(limit) > 0 {
+		decision.ConfirmRequired = true
+		decision.ConfirmReason = fmt.Sprintf("value %s ETH is above the confirmation threshold of %s ETH", units.Format(value, units.EtherDecimals), p.ConfirmAbove)
+	}
+	if hasTokenLimit {
+		dec := int(tx.Token.Decimals)
+		if limit := mustTokenAmount(tokenLimit.ConfirmAbove, dec); limit != nil && tx.Token.Amount.Cmp(limit) > 0 {
+			decision.ConfirmRequired = true
+			decision.ConfirmReason = fmt.Sprintf("token amount %s is above the confirmation threshold of %s", units.Format(tx.Token.Amount, dec), tokenLimit.ConfirmAbove)
+		}
+	}
+	return decision, nil
+}
+
+// Record 在交易广播成功后记入每日额度账本；p 为 nil 或未配置每日额度时不做任何事
+func (p *Policy) Record(tx *Tx, txHash common.Hash) error {
+	if p == nil || !p.tracksDaily() {
+		return nil
+	}
+	path := p.ledgerPath()
+	ledger, err := loadLedger(path)
+	if err != nil {
+		return err
+	}
+	ledger.add(tx, txHash)
+	return ledger.save(path)
+}
+
+// tracksDaily 是否配置了任何每日额度
+func (p *Policy) tracksDaily() bool {
+	if p.MaxDailyValue != "" {
+		return true
+	}
+	for _, l := range p.Tokens {
+		if l.MaxDaily != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ledgerPath 账本路径：默认与策略文件同目录的 <策略文件名>.ledger.json
+func (p *Policy) ledgerPath() string {
+	if p.LedgerFile == "" {
+		return p.path + ".ledger.json"
+	}
+	if filepath.IsAbs(p.LedgerFile) {
+		return p.LedgerFile
+	}
+	return filepath.Join(filepath.Dir(p.path), p.LedgerFile)
+}
+
+// Confirm 根据 Decision 决定是否需要确认：策略要求确认时忽略 assumeYes。
+// confirm 为交互确认函数（通常是 prompt.Confirm），返回 false 表示用户取消
+func (d *Decision) Confirm(assumeYes bool, question string, confirm func(string) (bool, error)) (bool, error) {
+	if assumeYes && !d.ConfirmRequired {
+		return true, nil
+	}
+	if d.ConfirmRequired {
+		fmt.Printf("Policy: %s, interactive confirmation required\n", d.ConfirmReason)
+	}
+	ok, err := confirm(question)
+	if err != nil {
+		return false, fmt.Errorf("confirmation failed: %w", err)
+	}
+	return ok, nil
+}
+
+// PrintRejection 将拒绝原因以 JSON 输出到 w；err 不是 *Rejection 时不输出
+func PrintRejection(w io.Writer, err error) {
+	var r *Rejection
+	if !errors.As(err, &r) {
+		return
+	}
+	out := struct {
+		Rejected   bool        `json:"rejected"`
+		Violations []Violation `json:"violations"`
+	}{true, r.Violations}
+	raw, _ := json.MarshalIndent(out, "", "  ")
+	fmt.Fprintln(w, string(raw))
+}
+
+func containsAddr(list []common.Address, addr common.Address) bool {
+	for _, a := range list {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
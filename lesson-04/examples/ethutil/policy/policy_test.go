@@ -0,0 +1,303 @@
+package policy
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethutil/units"
+)
+
+var (
+	sender    = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	recipient = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	other     = common.HexToAddress("0x3000000000000000000000000000000000000003")
+	usdc      = common.HexToAddress("0x4000000000000000000000000000000000000004")
+)
+
+// loadPolicy 把策略写入临时目录后加载，账本使用默认路径（同目录）
+func loadPolicy(t *testing.T, doc string) *Policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return p
+}
+
+func ether(s string) *big.Int {
+	v, err := units.ParseEther(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ethTx 1 ETH 的普通转账，chain 1，21000 gas，30 gwei / 1 gwei
+func ethTx(mods ...func(*Tx)) *Tx {
+	to := recipient
+	tx := &Tx{
+		Kind:      KindETHTransfer,
+		ChainID:   big.NewInt(1),
+		From:      sender,
+		To:        &to,
+		Value:     ether("1"),
+		GasLimit:  21000,
+		GasFeeCap: ether("30gwei"),
+		GasTipCap: ether("1gwei"),
+	}
+	for _, m := range mods {
+		m(tx)
+	}
+	return tx
+}
+
+// tokenTx 6 位精度代币的转账，amount 以代币单位计
+func tokenTx(amount string, mods ...func(*Tx)) *Tx {
+	v, err := units.Parse(amount, 6)
+	if err != nil {
+		panic(err)
+	}
+	return ethTx(append([]func(*Tx){func(tx *Tx) {
+		to := usdc
+		tx.Kind, tx.To, tx.Value = KindERC20Transfer, &to, new(big.Int)
+		tx.Token = &TokenTransfer{Contract: usdc, Recipient: recipient, Amount: v, Decimals: 6}
+	}}, mods...)...)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		sent    []*Tx         // 检查之前已经广播并记账的交易
+		ledger  []ledgerEntry // 直接写入账本的记录（用于构造超出窗口的旧记录）
+		tx      *Tx
+		codes   []string // 期望的违规错误码，为空表示通过
+		confirm bool
+	}{
+		{name: "empty policy", policy: `{}`, tx: ethTx()},
+
+		// 链 ID 白名单
+		{name: "chain allowed", policy: `{"chain_ids": [1, 31337]}`, tx: ethTx()},
+		{name: "chain not allowed", policy: `{"chain_ids": [11155111]}`, tx: ethTx(), codes: []string{CodeChainNotAllowed}},
+		{name: "chain id missing", policy: `{"chain_ids": [1]}`, tx: ethTx(func(tx *Tx) { tx.ChainID = nil }), codes: []string{CodeChainNotAllowed}},
+
+		// 单笔上限
+		{name: "value at per-tx cap", policy: `{"max_value_per_tx": "1"}`, tx: ethTx()},
+		{name: "value above per-tx cap", policy: `{"max_value_per_tx": "0.5ether"}`, tx: ethTx(), codes: []string{CodeValuePerTxExceeded}},
+		{name: "per-tx cap in gwei", policy: `{"max_value_per_tx": "100gwei"}`, tx: ethTx(func(tx *Tx) { tx.Value = ether("101gwei") }), codes: []string{CodeValuePerTxExceeded}},
+
+		// 每日额度
+		{
+			name:   "daily cap not reached",
+			policy: `{"max_daily_value": "2"}`,
+			sent:   []*Tx{ethTx()},
+			tx:     ethTx(),
+		},
+		{
+			name:   "daily cap exceeded",
+			policy: `{"max_daily_value": "2"}`,
+			sent:   []*Tx{ethTx(), ethTx(func(tx *Tx) { tx.Value = ether("0.5") })},
+			tx:     ethTx(),
+			codes:  []string{CodeDailyValueExceeded},
+		},
+		{
+			name:   "daily cap counts only the same chain and sender",
+			policy: `{"max_daily_value": "2"}`,
+			sent: []*Tx{
+				ethTx(func(tx *Tx) { tx.ChainID, tx.Value = big.NewInt(5), ether("2") }),
+				ethTx(func(tx *Tx) { tx.From, tx.Value = other, ether("2") }),
+				tokenTx("1000"),
+			},
+			tx: ethTx(),
+		},
+		{
+			name:   "daily cap ignores entries older than 24h",
+			policy: `{"max_daily_value": "2"}`,
+			ledger: []ledgerEntry{{Time: time.Now().Add(-25 * time.Hour), ChainID: "1", From: sender, Amount: ether("5").String()}},
+			tx:     ethTx(),
+		},
+
+		// 收款地址
+		{name: "recipient denied", policy: `{"deny_recipients": ["` + recipient.Hex() + `"]}`, tx: ethTx(), codes: []string{CodeRecipientDenied}},
+		{name: "recipient allowed", policy: `{"allow_recipients": ["` + recipient.Hex() + `"]}`, tx: ethTx()},
+		{name: "recipient not allowed", policy: `{"allow_recipients": ["` + other.Hex() + `"]}`, tx: ethTx(), codes: []string{CodeRecipientNotAllowed}},
+		{
+			name:   "token recipient checked instead of contract",
+			policy: `{"allow_recipients": ["` + usdc.Hex() + `"]}`,
+			tx:     tokenTx("1"),
+			codes:  []string{CodeRecipientNotAllowed},
+		},
+		{
+			name:   "beneficiary checked instead of contract",
+			policy: `{"deny_recipients": ["` + other.Hex() + `"]}`,
+			tx: ethTx(func(tx *Tx) {
+				b := other
+				tx.Kind, tx.Value, tx.Beneficiary = KindContractCall, new(big.Int), &b
+			}),
+			codes: []string{CodeRecipientDenied},
+		},
+
+		// Gas 费用上限
+		{name: "fee cap exceeded", policy: `{"max_fee_per_gas": "20"}`, tx: ethTx(), codes: []string{CodeFeeCapExceeded}},
+		{name: "tip cap exceeded", policy: `{"max_priority_fee_per_gas": "0.5"}`, tx: ethTx(), codes: []string{CodeTipCapExceeded}},
+		{name: "legacy tx has no tip", policy: `{"max_priority_fee_per_gas": "0.5"}`, tx: ethTx(func(tx *Tx) { tx.GasTipCap = nil })},
+		{name: "tx fee within cap", policy: `{"max_tx_fee": "0.00063"}`, tx: ethTx()},
+		{name: "tx fee exceeded", policy: `{"max_tx_fee": "0.0006"}`, tx: ethTx(), codes: []string{CodeTxFeeExceeded}},
+
+		// 合约部署
+		{name: "deploy allowed by default", policy: `{"allow_recipients": ["` + other.Hex() + `"]}`, tx: ethTx(func(tx *Tx) { tx.Kind, tx.To = KindDeploy, nil })},
+		{name: "deploy disabled", policy: `{"allow_deploy": false}`, tx: ethTx(func(tx *Tx) { tx.Kind, tx.To = KindDeploy, nil }), codes: []string{CodeDeployNotAllowed}},
+
+		// 所有违规都会报告
+		{
+			name:   "multiple violations",
+			policy: `{"chain_ids": [5], "max_value_per_tx": "0.1", "deny_recipients": ["` + recipient.Hex() + `"], "max_fee_per_gas": "1"}`,
+			tx:     ethTx(),
+			codes:  []string{CodeChainNotAllowed, CodeRecipientDenied, CodeValuePerTxExceeded, CodeFeeCapExceeded},
+		},
+
+		// 大额确认
+		{name: "confirm above threshold", policy: `{"confirm_above": "0.5"}`, tx: ethTx(), confirm: true},
+		{name: "no confirm at threshold", policy: `{"confirm_above": "1"}`, tx: ethTx()},
+		{name: "rejection wins over confirmation", policy: `{"confirm_above": "0.5", "max_value_per_tx": "0.8"}`, tx: ethTx(), codes: []string{CodeValuePerTxExceeded}},
+
+		// 代币额度
+		{name: "token within caps", policy: `{"tokens": {"` + usdc.Hex() + `": {"max_per_tx": "100", "max_daily": "500"}}}`, tx: tokenTx("100")},
+		{name: "token per-tx cap exceeded", policy: `{"tokens": {"` + usdc.Hex() + `": {"max_per_tx": "100"}}}`, tx: tokenTx("100.000001"), codes: []string{CodeTokenPerTxExceeded}},
+		{
+			name:   "token address matched case-insensitively",
+			policy: `{"tokens": {"` + strings.ToLower(usdc.Hex()) + `": {"max_per_tx": "100"}}}`,
+			tx:     tokenTx("150"),
+			codes:  []string{CodeTokenPerTxExceeded},
+		},
+		{
+			name:   "token daily cap exceeded",
+			policy: `{"tokens": {"` + usdc.Hex() + `": {"max_daily": "200"}}}`,
+			sent:   []*Tx{tokenTx("100"), ethTx()},
+			tx:     tokenTx("150"),
+			codes:  []string{CodeTokenDailyExceeded},
+		},
+		{name: "token confirm above threshold", policy: `{"tokens": {"` + usdc.Hex() + `": {"confirm_above": "50"}}}`, tx: tokenTx("50.5"), confirm: true},
+		{name: "unconfigured token", policy: `{"tokens": {"` + other.Hex() + `": {"max_per_tx": "1"}}}`, tx: tokenTx("1000")},
+		{
+			// 额度的小数位数超过代币精度时无法换算，按 0 上限拒绝
+			name:   "token limit with more decimals than the token",
+			policy: `{"tokens": {"` + usdc.Hex() + `": {"max_per_tx": "100.0000001", "max_daily": "0.0000001"}}}`,
+			tx:     tokenTx("1"),
+			codes:  []string{CodeTokenPerTxExceeded, CodeTokenDailyExceeded},
+		},
+		{
+			name:    "token confirm threshold with more decimals than the token",
+			policy:  `{"tokens": {"` + usdc.Hex() + `": {"confirm_above": "1000.0000001"}}}`,
+			tx:      tokenTx("1"),
+			confirm: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := loadPolicy(t, tt.policy)
+			if tt.ledger != nil {
+				if err := (&ledger{Entries: tt.ledger}).save(p.ledgerPath()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, tx := range tt.sent {
+				if err := p.Record(tx, common.BigToHash(big.NewInt(int64(i+1)))); err != nil {
+					t.Fatalf("Record: %v", err)
+				}
+			}
+
+			decision, err := p.Check(tt.tx)
+			var codes []string
+			var rejection *Rejection
+			if errors.As(err, &rejection) {
+				for _, v := range rejection.Violations {
+					codes = append(codes, v.Code)
+				}
+			} else if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.codes, ",") {
+				t.Fatalf("violations = %v, want %v (%v)", codes, tt.codes, err)
+			}
+			if err == nil && decision.ConfirmRequired != tt.confirm {
+				t.Errorf("ConfirmRequired = %v, want %v", decision.ConfirmRequired, tt.confirm)
+			}
+		})
+	}
+}
+
+// TestCheckNil 未配置策略时总是通过
+func TestCheckNil(t *testing.T) {
+	var p *Policy
+	decision, err := p.Check(ethTx())
+	if err != nil || decision.ConfirmRequired {
+		t.Errorf("nil policy Check() = %+v, %v", decision, err)
+	}
+	if err := p.Record(ethTx(), common.Hash{}); err != nil {
+		t.Errorf("nil policy Record(): %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		doc     string
+		wantErr string
+	}{
+		{`{"chain_ids": [1], "max_value_per_tx": "1.5", "max_fee_per_gas": "30gwei", "tokens": {"` + usdc.Hex() + `": {"max_per_tx": "1000000", "max_daily": "5000000.5"}}}`, ""},
+		{`{"max_value": "1"}`, "unknown field"},
+		{`{"max_value_per_tx": "1.5x"}`, "max_value_per_tx"},
+		{`{"max_fee_per_gas": "-1"}`, "max_fee_per_gas"},
+		{`{"max_tx_fee": "0.1wei"}`, "max_tx_fee"},
+		{`{"tokens": {"usdc": {"max_per_tx": "1"}}}`, "invalid token address"},
+		{`{"tokens": {"` + usdc.Hex() + `": {"max_daily": "1,000"}}}`, "max_daily"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		if err := os.WriteFile(path, []byte(tt.doc), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(path)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Load(%s): %v", tt.doc, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Load(%s) error = %v, want %q", tt.doc, err, tt.wantErr)
+		}
+	}
+
+	// 没有指定路径、也没有设置环境变量时不启用策略
+	t.Setenv(EnvFile, "")
+	if p, err := Load(""); p != nil || err != nil {
+		t.Errorf(`Load("") = %v, %v; want nil, nil`, p, err)
+	}
+}
+
+func TestLedgerPath(t *testing.T) {
+	dir := t.TempDir()
+	abs := filepath.Join(t.TempDir(), "abs.json")
+	tests := []struct {
+		ledgerFile, want string
+	}{
+		{"", filepath.Join(dir, "policy.json.ledger.json")},
+		{"spend.json", filepath.Join(dir, "spend.json")},
+		{abs, abs},
+	}
+	for _, tt := range tests {
+		p := &Policy{LedgerFile: tt.ledgerFile, path: filepath.Join(dir, "policy.json")}
+		if got := p.ledgerPath(); got != tt.want {
+			t.Errorf("ledgerPath() with ledger_file %q = %s, want %s", tt.ledgerFile, got, tt.want)
+		}
+	}
+}
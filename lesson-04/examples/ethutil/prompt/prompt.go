@@ -0,0 +1,47 @@
+// Package prompt 提供命令行交互确认、密码输入等小工具。
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdin 在多次提示之间共享，避免管道输入时前一次读取把后续行缓冲掉
+var stdin = bufio.NewReader(os.Stdin)
+
+// Confirm 在终端打印问题并等待用户输入 y/yes 确认，其他输入（包括直接回车）视为取消
+func Confirm(question string) (bool, error) {
+	fmt.Printf("%s [y/N]: ", question)
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes", nil
+}
+
+// Password 在终端提示输入密码，输入内容不回显；stdin 不是终端时（如管道）按普通行读取
+func Password(label string) (string, error) {
+	fmt.Printf("%s: ", label)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		pw, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return string(pw), nil
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,255 @@
+// Package revert 解码交易回滚（revert）原因。
+//
+// 合约回滚时返回的数据有三种常见格式：
+//   - Error(string)：require(cond, "msg") / revert("msg")，选择器 0x08c379a0
+//   - Panic(uint256)：assert 失败、算术溢出、数组越界等，选择器 0x4e487b71
+//   - 自定义错误（Solidity 0.8.4+ 的 error Foo(...)）：选择器为错误签名哈希的前 4 字节，需要 ABI 才能解码
+//
+// 已上链的失败交易，回执中只有 Status=0，不包含回滚数据。
+// 可以通过在父区块状态上以 eth_call 重放该交易来还原回滚原因。
+package revert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 回滚原因的类型
+const (
+	KindError   = "Error"   // Error(string)
+	KindPanic   = "Panic"   // Panic(uint256)
+	KindCustom  = "Custom"  // ABI 中定义的自定义错误
+	KindUnknown = "Unknown" // 无法识别的回滚数据
+	KindEmpty   = "Empty"   // 回滚但没有返回数据（revert() 或 require(cond) 不带消息）
+	KindVM      = "VM"      // 非 revert 的 EVM 错误，例如 out of gas、invalid opcode
+)
+
+var (
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// ErrNoRevert 表示重放时交易执行成功，无法还原回滚原因
+// 通常是因为失败依赖于同一区块中排在它前面的交易所修改的状态
+var ErrNoRevert = errors.New("replay at parent block did not revert")
+
+// panicCodes Solidity Panic(uint256) 错误码说明
+// https://docs.soliditylang.org/en/latest/control-structures.html#panic-via-assert-and-error-via-require
+var panicCodes = map[uint64]string{
+	0x00: "generic compiler inserted panic",
+	0x01: "assert(false) or failed assertion",
+	0x11: "arithmetic overflow or underflow outside an unchecked block",
+	0x12: "division or modulo by zero",
+	0x21: "conversion of a too big or negative value into an enum type",
+	0x22: "access to an incorrectly encoded storage byte array",
+	0x31: "pop() on an empty array",
+	0x32: "array, bytesN or slice index out of bounds",
+	0x41: "too much memory allocated or array too large",
+	0x51: "call to a zero-initialized variable of internal function type",
+}
+
+// Reason 解码后的回滚原因
+type Reason struct {
+	Kind      string
+	Message   string   // 可读描述
+	PanicCode *big.Int // 仅 KindPanic
+	Error     *abi.Error
+	Args      []interface{} // 仅 KindCustom，自定义错误的参数
+	Data      []byte        // 原始回滚数据
+}
+
+// String 返回一行可读描述
+func (r *Reason) String() string {
+	switch r.Kind {
+	case KindError:
+		return fmt.Sprintf("Error(%q)", r.Message)
+	case KindPanic:
+		return fmt.Sprintf("Panic(0x%x): %s", r.PanicCode, r.Message)
+	case KindCustom:
+		return r.Message
+	case KindEmpty:
+		return "reverted without reason"
+	default:
+		return r.Message
+	}
+}
+
+// Decoder 回滚原因解码器，按选择器索引已加载 ABI 中的自定义错误
+type Decoder struct {
+	errors map[[4]byte][]abi.Error
+}
+
+// NewDecoder 创建解码器，abis 中定义的 error 会被用于匹配自定义错误
+func NewDecoder(abis ...*abi.ABI) *Decoder {
+	d := &Decoder{errors: make(map[[4]byte][]abi.Error)}
+	for _, a := range abis {
+		d.Add(a)
+	}
+	return d
+}
+
+// Add 追加一个 ABI 中的自定义错误
+func (d *Decoder) Add(a *abi.ABI) {
+	if a == nil {
+		return
+	}
+	for _, e := range a.Errors {
+		var sel [4]byte
+		copy(sel[:], e.ID[:4])
+		// 不同 ABI 中的同一个错误只保留一份
+		dup := false
+		for _, existing := range d.errors[sel] {
+			if existing.Sig == e.Sig {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			d.errors[sel] = append(d.errors[sel], e)
+		}
+	}
+}
+
+// Decode 解码回滚数据；d 为 nil 时只识别 Error(string) 和 Panic(uint256)
+func (d *Decoder) Decode(data []byte) *Reason {
+	r := &Reason{Data: data}
+	if len(data) == 0 {
+		r.Kind = KindEmpty
+		return r
+	}
+	if len(data) < 4 {
+		r.Kind = KindUnknown
+		r.Message = fmt.Sprintf("malformed revert data 0x%x", data)
+		return r
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if msg, err := abi.UnpackRevert(data); err == nil {
+			r.Kind = KindError
+			r.Message = msg
+			return r
+		}
+	case bytes.Equal(data[:4], panicSelector) && len(data) == 36:
+		code := new(big.Int).SetBytes(data[4:])
+		r.Kind = KindPanic
+		r.PanicCode = code
+		r.Message = "unknown panic code"
+		if code.IsUint64() {
+			if desc, ok := panicCodes[code.Uint64()]; ok {
+				r.Message = desc
+			}
+		}
+		return r
+	}
+
+	if d != nil {
+		var sel [4]byte
+		copy(sel[:], data[:4])
+		for _, e := range d.errors[sel] {
+			values, err := e.Inputs.Unpack(data[4:])
+			if err != nil {
+				// 选择器冲突时尝试下一个候选
+				continue
+			}
+			r.Kind = KindCustom
+			r.Error = &e
+			r.Args = values
+			r.Message = formatCustom(&e, values)
+			return r
+		}
+	}
+
+	r.Kind = KindUnknown
+	r.Message = fmt.Sprintf("unknown custom error 0x%x (load the contract ABI to decode it)", data[:4])
+	return r
+}
+
+// formatCustom 将自定义错误格式化为 Name(arg=value, ...)
+func formatCustom(e *abi.Error, values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%s=%v", e.Inputs[i].Name, v)
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(parts, ", "))
+}
+
+// ExtractData 从 eth_call / eth_estimateGas 的错误中提取回滚数据
+// 返回值 reverted 表示该错误是否为一次 revert（而不是网络错误等）
+func ExtractData(err error) (data []byte, reverted bool) {
+	if err == nil {
+		return nil, false
+	}
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if s, ok := dataErr.ErrorData().(string); ok {
+			if decoded, decodeErr := hexutil.Decode(s); decodeErr == nil {
+				return decoded, true
+			}
+		}
+	}
+	// 部分节点只返回 "execution reverted" 而不带 data
+	if strings.Contains(err.Error(), "execution reverted") {
+		return nil, true
+	}
+	return nil, false
+}
+
+// FromError 将调用错误解码为回滚原因；不是 revert 的错误返回 nil
+func (d *Decoder) FromError(err error) *Reason {
+	data, reverted := ExtractData(err)
+	if !reverted {
+		return nil
+	}
+	return d.Decode(data)
+}
+
+// Replay 在交易所在区块的父区块状态上，以 eth_call 重放交易并解码回滚原因
+//
+// 注意：父区块状态不包含同一区块中排在该交易之前的交易，
+// 如果失败依赖这些交易（例如抢跑导致的滑点失败），重放可能成功并返回 ErrNoRevert。
+func (d *Decoder) Replay(ctx context.Context, client *ethclient.Client, tx *types.Transaction, blockNumber *big.Int) (*Reason, error) {
+	if blockNumber == nil || blockNumber.Sign() == 0 {
+		return nil, fmt.Errorf("cannot replay transaction in genesis block")
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %w", err)
+	}
+
+	// 不携带 gas 价格字段：父区块的 base fee 可能高于交易的 fee cap，
+	// eth_call 在 gas 价格为 0 时不会扣费，也不会因此报错
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	parent := new(big.Int).Sub(blockNumber, big.NewInt(1))
+
+	_, err = client.CallContract(ctx, msg, parent)
+	if err == nil {
+		return nil, ErrNoRevert
+	}
+	if reason := d.FromError(err); reason != nil {
+		return reason, nil
+	}
+	// out of gas、invalid opcode 等 EVM 错误没有回滚数据，直接返回节点给出的错误信息
+	if strings.Contains(err.Error(), "out of gas") || strings.Contains(err.Error(), "invalid opcode") {
+		return &Reason{Kind: KindVM, Message: err.Error()}, nil
+	}
+	return nil, fmt.Errorf("replay failed: %w", err)
+}
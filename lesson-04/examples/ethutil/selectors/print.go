@@ -0,0 +1,49 @@
+package selectors
+
+import (
+	"fmt"
+	"io"
+
+	"ethutil/abifmt"
+)
+
+// valueFormatter 参数的格式化方式：过长的 bytes（例如嵌套的 calldata）截断显示
+var valueFormatter = abifmt.Formatter{MaxBytes: 68}
+
+// Print 以缩进形式输出解码结果，嵌套调用逐层缩进
+//
+//	Function : multicall(bytes[])
+//	  data (bytes[]): [2 items]
+//	    ↳ [0] exactInputSingle(...)
+//	          params (tuple): {tokenIn: 0x..., ...}
+func (c *Call) Print(w io.Writer, indent string) {
+	if c.Method == nil {
+		switch {
+		case len(c.Selector) == 0:
+			fmt.Fprintf(w, "%sFunction : (none, plain transfer)\n", indent)
+		case c.Err != nil:
+			fmt.Fprintf(w, "%sFunction : unknown (selector 0x%x, candidates failed to decode: %v)\n", indent, c.Selector, c.Err)
+		default:
+			fmt.Fprintf(w, "%sFunction : unknown (selector 0x%x, %d bytes)\n", indent, c.Selector, len(c.Raw))
+		}
+		return
+	}
+	fmt.Fprintf(w, "%sFunction : %s (selector 0x%x)\n", indent, c.Method.Sig, c.Selector)
+	for _, alt := range c.Alternatives {
+		fmt.Fprintf(w, "%s  also matches: %s (selector collision)\n", indent, alt.Sig)
+	}
+	for _, arg := range c.Args {
+		fmt.Fprintf(w, "%s  %s (%s): %s\n", indent, arg.Name, arg.Type.String(), valueFormatter.Format(arg.Type, arg.Value))
+		for i, nested := range arg.Nested {
+			header := fmt.Sprintf("%s    ↳ [%d]", indent, i)
+			if nested.Target != nil {
+				header += " to " + nested.Target.Hex()
+			}
+			if nested.Value != nil && nested.Value.Sign() > 0 {
+				header += " value " + nested.Value.String()
+			}
+			fmt.Fprintln(w, header)
+			nested.Print(w, indent+"      ")
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Package selectors 用本地的函数选择器数据库解码交易的 calldata：
+//   - 内置常用合约的函数签名（signatures.txt），可以通过 ABI 文件或签名文本扩展
+//   - 前 4 字节对应多个签名（选择器碰撞）时，能成功解码参数的第一个作为结果，其余作为候选列出
+//   - 参数中的 bytes（例如 multicall(bytes[])、Multicall3 的 callData、Safe execTransaction 的 data）
+//     会被递归解码；Safe MultiSend 的 packed 编码交易列表也会被拆开解码
+package selectors
+
+import (
+	_ "embed"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethutil/abisig"
+)
+
+//go:embed signatures.txt
+var bundledSignatures string
+
+// MaxDepth 递归解码的最大深度
+const MaxDepth = 4
+
+// DB 选择器数据库：4 字节选择器 → 函数签名列表（按添加顺序，先添加的优先）
+type DB struct {
+	methods map[[4]byte][]abi.Method
+}
+
+// New 创建空数据库
+func New() *DB {
+	return &DB{methods: make(map[[4]byte][]abi.Method)}
+}
+
+// Bundled 创建包含内置签名的数据库
+func Bundled() *DB {
+	db := New()
+	for _, line := range strings.Split(bundledSignatures, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := db.AddSignature(line); err != nil {
+			panic(fmt.Sprintf("invalid bundled signature %q: %v", line, err))
+		}
+	}
+	return db
+}
+
+// AddABI 添加 ABI 中的全部函数；ABI 中的定义（带参数名）优先于内置签名
+func (db *DB) AddABI(a *abi.ABI) {
+	for _, m := range a.Methods {
+		db.add(m, true)
+	}
+}
+
+// AddSignature 添加一个文本签名，例如 "transfer(address to, uint256 value)"，参数名可以省略
+func (db *DB) AddSignature(sig string) error {
+	m, err := abisig.ParseFunction(sig)
+	if err != nil {
+		return err
+	}
+	db.add(m, false)
+	return nil
+}
+
+// add 添加函数，签名相同的只保留一个（例如 ERC-20 和 ERC-721 的 transferFrom）
+func (db *DB) add(m abi.Method, prepend bool) {
+	var sel [4]byte
+	copy(sel[:], m.ID)
+	list := db.methods[sel]
+	for i, existing := range list {
+		if existing.Sig == m.Sig {
+			if prepend {
+				list = append(list[:i:i], list[i+1:]...)
+				break
+			}
+			return
+		}
+	}
+	if prepend {
+		db.methods[sel] = append([]abi.Method{m}, list...)
+	} else {
+		db.methods[sel] = append(list, m)
+	}
+}
+
+// Lookup 返回选择器对应的全部签名
+func (db *DB) Lookup(selector []byte) []abi.Method {
+	if len(selector) < 4 {
+		return nil
+	}
+	var sel [4]byte
+	copy(sel[:], selector)
+	return db.methods[sel]
+}
+
+// Len 数据库中的签名数量
+func (db *DB) Len() int {
+	n := 0
+	for _, list := range db.methods {
+		n += len(list)
+	}
+	return n
+}
+
+// Collisions 返回对应多个签名的选择器
+func (db *DB) Collisions() map[[4]byte][]abi.Method {
+	out := make(map[[4]byte][]abi.Method)
+	for sel, list := range db.methods {
+		if len(list) > 1 {
+			out[sel] = list
+		}
+	}
+	return out
+}
+
+// Call 一段 calldata 的解码结果
+type Call struct {
+	Target       *common.Address // 调用目标（嵌套调用中能确定时才有）
+	Value        *big.Int        // 随调用发送的 ETH（嵌套调用中能确定时才有）
+	Selector     []byte
+	Method       *abi.Method  // 无法识别或所有候选都解码失败时为 nil
+	Args         []Arg        // 解码后的参数
+	Alternatives []abi.Method // 选择器相同的其它签名（碰撞）
+	Err          error        // 候选签名都无法解码参数时的错误
+	Raw          []byte       // 原始 calldata
+}
+
+// Arg 一个解码后的参数；Nested 为参数（或其中的 bytes 字段）递归解码出的调用
+type Arg struct {
+	Name   string
+	Type   abi.Type
+	Value  interface{}
+	Nested []*Call
+}
+
+// Decode 解码 calldata；不足 4 字节（普通转账）返回 nil
+func (db *DB) Decode(data []byte) *Call {
+	return db.decode(data, 0)
+}
+
+func (db *DB) decode(data []byte, depth int) *Call {
+	if len(data) < 4 {
+		return nil
+	}
+	call := &Call{Selector: data[:4], Raw: data}
+	candidates := db.Lookup(data[:4])
+	for i := range candidates {
+		m := candidates[i]
+		values, err := m.Inputs.Unpack(data[4:])
+		if err != nil {
+			if call.Err == nil {
+				call.Err = fmt.Errorf("%s: %w", m.Sig, err)
+			}
+			continue
+		}
+		if call.Method != nil {
+			call.Alternatives = append(call.Alternatives, m)
+			continue
+		}
+		call.Method = &m
+		call.Err = nil
+		call.Args = make([]Arg, len(m.Inputs))
+		for j, input := range m.Inputs {
+			call.Args[j] = Arg{Name: input.Name, Type: input.Type, Value: values[j]}
+			if depth < MaxDepth {
+				call.Args[j].Nested = db.nested(m, j, values, depth+1)
+			}
+		}
+	}
+	// 解码失败的候选也列出，便于判断碰撞
+	if call.Method != nil {
+		for _, m := range candidates {
+			if m.Sig != call.Method.Sig && !containsSig(call.Alternatives, m.Sig) {
+				call.Alternatives = append(call.Alternatives, m)
+			}
+		}
+	}
+	return call
+}
+
+// nested 递归解码参数 j 中的调用数据
+func (db *DB) nested(m abi.Method, j int, values []interface{}, depth int) []*Call {
+	// Safe MultiSend 的 transactions 参数是 packed 编码的交易列表
+	if m.RawName == "multiSend" {
+		if b, ok := values[j].([]byte); ok {
+			return db.decodeMultiSend(b, depth)
+		}
+	}
+	calls := db.findCalls(reflect.ValueOf(values[j]), depth)
+	// execTransaction(to, value, data, ...) 的 data 发往 to
+	if m.RawName == "execTransaction" && m.Inputs[j].Name == "data" && len(calls) == 1 {
+		if to, ok := values[0].(common.Address); ok {
+			calls[0].Target = &to
+		}
+		if v, ok := values[1].(*big.Int); ok {
+			calls[0].Value = v
+		}
+	}
+	return calls
+}
+
+// findCalls 在值中查找 bytes（包括数组元素和 tuple 字段），能识别选择器的递归解码。
+// tuple 中同时有 address 字段时，把它作为嵌套调用的目标（Multicall3 的 (target, callData)）
+func (db *DB) findCalls(v reflect.Value, depth int) []*Call {
+	if !v.IsValid() || v.Type() == reflect.TypeOf((*big.Int)(nil)) {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Kind() != reflect.Slice {
+				return nil // bytesN 不是调用数据
+			}
+			b := v.Bytes()
+			if len(db.Lookup(b)) == 0 {
+				return nil
+			}
+			return []*Call{db.decode(b, depth)}
+		}
+		var out []*Call
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, db.findCalls(v.Index(i), depth)...)
+		}
+		return out
+	case reflect.Struct:
+		var (
+			target *common.Address
+			value  *big.Int
+			out    []*Call
+		)
+		for i := 0; i < v.NumField(); i++ {
+			switch f := v.Field(i).Interface().(type) {
+			case common.Address:
+				target = &f
+			case *big.Int:
+				value = f
+			default:
+				out = append(out, db.findCalls(v.Field(i), depth)...)
+			}
+		}
+		if len(out) == 1 {
+			out[0].Target, out[0].Value = target, value
+		}
+		return out
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return db.findCalls(v.Elem(), depth)
+	}
+	return nil
+}
+
+// decodeMultiSend 拆分 MultiSend 的交易列表：每笔为 operation(1) ‖ to(20) ‖ value(32) ‖ dataLength(32) ‖ data
+func (db *DB) decodeMultiSend(b []byte, depth int) []*Call {
+	var out []*Call
+	for len(b) >= 85 {
+		to := common.BytesToAddress(b[1:21])
+		value := new(big.Int).SetBytes(b[21:53])
+		lenWord := new(big.Int).SetBytes(b[53:85])
+		if !lenWord.IsUint64() || lenWord.Uint64() > uint64(len(b)-85) {
+			break
+		}
+		n := int(lenWord.Uint64())
+		data := b[85 : 85+n]
+		call := db.decode(data, depth)
+		if call == nil {
+			// 没有 calldata 的 ETH 转账
+			call = &Call{}
+		}
+		call.Target, call.Value = &to, value
+		out = append(out, call)
+		b = b[85+n:]
+	}
+	return out
+}
+
+func containsSig(list []abi.Method, sig string) bool {
+	for _, m := range list {
+		if m.Sig == sig {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package signer
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/external"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// externalSigner 通过 clef 风格的 JSON-RPC 接口（account_list / account_signTransaction /
+// account_signData / account_signTypedData）签名；私钥保存在外部签名器中，每次签名都需要在签名器一侧确认
+type externalSigner struct {
+	api     *external.ExternalSigner
+	rpc     *rpc.Client // external.ExternalSigner 没有封装 account_signTypedData，单独保留一个连接
+	account accounts.Account
+}
+
+func openExternal(endpoint, account string) (Signer, error) {
+	api, err := external.NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external signer %s: %w", endpoint, err)
+	}
+	accs := api.Accounts()
+	if len(accs) == 0 {
+		return nil, fmt.Errorf("external signer %s has no accounts (or listing was denied)", endpoint)
+	}
+
+	selected := accs[0]
+	if account != "" {
+		want := common.HexToAddress(account)
+		found := false
+		for _, a := range accs {
+			if a.Address == want {
+				selected, found = a, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("account %s not managed by external signer", want.Hex())
+		}
+	}
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to external signer %s: %w", endpoint, err)
+	}
+	return &externalSigner{api: api, rpc: client, account: selected}, nil
+}
+
+func (s *externalSigner) Address() common.Address { return s.account.Address }
+func (s *externalSigner) Kind() string            { return "clef" }
+
+func (s *externalSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return s.api.SignTx(s.account, tx, chainID)
+}
+
+func (s *externalSigner) SignText(text []byte) ([]byte, error) {
+	sig, err := s.api.SignText(s.account, text)
+	if err != nil {
+		return nil, err
+	}
+	// ExternalSigner.SignText 把 v 转换成了 0/1，这里统一为 27/28
+	return toLegacyV(sig), nil
+}
+
+func (s *externalSigner) SignTypedData(data apitypes.TypedData) ([]byte, error) {
+	var sig hexutil.Bytes
+	addr := common.NewMixedcaseAddress(s.account.Address)
+	if err := s.rpc.Call(&sig, "account_signTypedData", &addr, data); err != nil {
+		return nil, err
+	}
+	return toLegacyV(sig), nil
+}
+
+// toLegacyV 将签名的 v 统一为 27/28
+func toLegacyV(sig []byte) []byte {
+	if len(sig) == crypto.SignatureLength && sig[crypto.RecoveryIDOffset] < 27 {
+		sig[crypto.RecoveryIDOffset] += 27
+	}
+	return sig
+}
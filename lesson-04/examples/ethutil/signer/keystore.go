@@ -0,0 +1,75 @@
+package signer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethutil/prompt"
+)
+
+// openKeystore 解密 go-ethereum 加密 keystore 文件
+// path 为目录时，按 geth 的文件命名规则（UTC--<时间>--<地址>）查找 account 对应的文件
+func openKeystore(path, passwordFile, account string) (Signer, error) {
+	file, err := resolveKeystoreFile(path, account)
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	var passphrase string
+	if passwordFile != "" {
+		raw, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password file: %w", err)
+		}
+		passphrase = strings.TrimRight(string(raw), "\r\n")
+	} else {
+		passphrase, err = prompt.Password(fmt.Sprintf("Passphrase for %s", filepath.Base(file)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %w", filepath.Base(file), err)
+	}
+	if account != "" && key.Address != common.HexToAddress(account) {
+		return nil, fmt.Errorf("keystore address %s does not match --account %s", key.Address.Hex(), account)
+	}
+	return newKeySigner(key.PrivateKey, "keystore"), nil
+}
+
+// resolveKeystoreFile 返回 keystore 文件路径
+func resolveKeystoreFile(path, account string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("keystore not found: %w", err)
+	}
+	if !info.IsDir() {
+		return path, nil
+	}
+	if account == "" {
+		return "", fmt.Errorf("keystore %s is a directory, --account is required", path)
+	}
+
+	want := strings.ToLower(strings.TrimPrefix(common.HexToAddress(account).Hex(), "0x"))
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read keystore directory: %w", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(strings.ToLower(e.Name()), want) {
+			return filepath.Join(path, e.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("no keystore file for account %s in %s", account, path)
+}
@@ -0,0 +1,80 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// openMnemonic 由 BIP-39 助记词和 BIP-44 路径派生私钥
+//
+//	助记词 --(PBKDF2, BIP-39)--> 512 位种子 --(HMAC-SHA512, BIP-32)--> 主私钥 --(按路径逐级派生)--> 账户私钥
+func openMnemonic(mnemonic, passphrase, hdPath string) (Signer, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	// NewSeedWithErrorChecking 会校验单词表和校验位，拼写错误的助记词会直接报错
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %w", err)
+	}
+	path, err := accounts.ParseDerivationPath(hdPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hd path %q: %w", hdPath, err)
+	}
+	key, err := deriveKey(seed, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key at %s: %w", hdPath, err)
+	}
+	return newKeySigner(key, "mnemonic"), nil
+}
+
+// deriveKey 按 BIP-32 从种子派生 secp256k1 私钥
+func deriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := sum[:32], sum[32:]
+
+	n := crypto.S256().Params().N
+	for _, index := range path {
+		var data []byte
+		if index >= 0x80000000 {
+			// 硬化派生：0x00 || 父私钥
+			data = append([]byte{0}, key...)
+		} else {
+			// 普通派生：父公钥（压缩格式）
+			parent, err := crypto.ToECDSA(key)
+			if err != nil {
+				return nil, err
+			}
+			data = crypto.CompressPubkey(&parent.PublicKey)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac = hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum = mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(n) >= 0 {
+			return nil, errors.New("invalid child key, try the next index")
+		}
+		child := il.Add(il, new(big.Int).SetBytes(key))
+		child.Mod(child, n)
+		if child.Sign() == 0 {
+			return nil, errors.New("invalid child key, try the next index")
+		}
+		key = math.PaddedBigBytes(child, 32)
+		chainCode = sum[32:]
+	}
+	return crypto.ToECDSA(key)
+}
@@ -0,0 +1,190 @@
+// Package signer 提供可插拔的交易签名抽象，所有发送交易的命令都通过 Signer 签名，
+// 私钥材料不会离开签名器，也不会被打印。
+//
+// 支持的后端（按优先级）：
+//  1. 外部签名器（clef 风格的 JSON-RPC，account_signTransaction）：--clef http://127.0.0.1:8550
+//  2. go-ethereum 加密 keystore 文件：--keystore UTC--...json，密码通过 --password-file 或交互输入
+//  3. BIP-39 助记词 + BIP-44 路径：--mnemonic-file words.txt --hd-path "m/44'/60'/0'/0/0"
+//  4. 兼容旧用法：十六进制私钥环境变量（如 SENDER_PRIVATE_KEY），仅建议在本地开发链使用
+//
+// 每个 flag 都可以用对应的环境变量提供默认值（SIGNER_CLEF_URL、SIGNER_KEYSTORE、SIGNER_PASSWORD_FILE、
+// SIGNER_MNEMONIC_FILE、SIGNER_MNEMONIC、SIGNER_HD_PATH、SIGNER_ACCOUNT），便于在 .env 中配置。
+package signer
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer 交易签名器
+type Signer interface {
+	// Address 返回签名账户地址
+	Address() common.Address
+	// SignTx 使用 chainID 对交易签名（EIP-155 / EIP-1559）
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignText 按 EIP-191（personal_sign）对消息签名，返回 65 字节签名（v 为 27/28）
+	SignText(text []byte) ([]byte, error)
+	// SignTypedData 按 EIP-712 对结构化数据签名，返回 65 字节签名（v 为 27/28）
+	SignTypedData(data apitypes.TypedData) ([]byte, error)
+	// Kind 返回后端类型描述，例如 "keystore"、"mnemonic"、"clef"
+	Kind() string
+}
+
+// Config 签名器配置
+type Config struct {
+	ClefURL      string // 外部签名器地址（http/ws/ipc）
+	Keystore     string // keystore 文件，或 keystore 目录（需配合 Account）
+	PasswordFile string // keystore 密码文件，为空时交互输入
+	MnemonicFile string // 助记词文件
+	Mnemonic     string // 助记词（仅从环境变量读取，不提供 flag，避免出现在 shell 历史中）
+	HDPath       string // BIP-44 派生路径
+	Account      string // 指定账户地址（keystore 目录 / clef 多账户时使用）
+	KeyEnv       string // 兼容旧用法的私钥环境变量名
+}
+
+// DefaultHDPath 以太坊默认的 BIP-44 派生路径（MetaMask 第一个账户）
+const DefaultHDPath = "m/44'/60'/0'/0/0"
+
+// ConfigFromEnv 仅从环境变量构造配置，用于没有命令行参数的程序（如 task、task2）
+func ConfigFromEnv(keyEnv string) *Config {
+	return &Config{
+		ClefURL:      os.Getenv("SIGNER_CLEF_URL"),
+		Keystore:     os.Getenv("SIGNER_KEYSTORE"),
+		PasswordFile: os.Getenv("SIGNER_PASSWORD_FILE"),
+		MnemonicFile: os.Getenv("SIGNER_MNEMONIC_FILE"),
+		Mnemonic:     os.Getenv("SIGNER_MNEMONIC"),
+		HDPath:       envOr("SIGNER_HD_PATH", DefaultHDPath),
+		Account:      os.Getenv("SIGNER_ACCOUNT"),
+		KeyEnv:       keyEnv,
+	}
+}
+
+// RegisterFlags 在 fs 上注册签名器相关的命令行参数，默认值来自环境变量
+// keyEnv 为兼容旧用法的私钥环境变量名（如 SENDER_PRIVATE_KEY）
+func RegisterFlags(fs *flag.FlagSet, keyEnv string) *Config {
+	c := ConfigFromEnv(keyEnv)
+	fs.StringVar(&c.ClefURL, "clef", c.ClefURL, "external signer (clef) endpoint, e.g. http://127.0.0.1:8550")
+	fs.StringVar(&c.Keystore, "keystore", c.Keystore, "encrypted keystore file (or keystore directory with --account)")
+	fs.StringVar(&c.PasswordFile, "password-file", c.PasswordFile, "file containing the keystore passphrase (prompted if empty)")
+	fs.StringVar(&c.MnemonicFile, "mnemonic-file", c.MnemonicFile, "file containing a BIP-39 mnemonic")
+	fs.StringVar(&c.HDPath, "hd-path", c.HDPath, "BIP-44 derivation path used with the mnemonic")
+	fs.StringVar(&c.Account, "account", c.Account, "account address to use (keystore directory or clef)")
+	return c
+}
+
+// Open 根据配置打开签名器
+func (c *Config) Open() (Signer, error) {
+	switch {
+	case c.ClefURL != "":
+		return openExternal(c.ClefURL, c.Account)
+	case c.Keystore != "":
+		return openKeystore(c.Keystore, c.PasswordFile, c.Account)
+	case c.MnemonicFile != "" || c.Mnemonic != "":
+		mnemonic := c.Mnemonic
+		if c.MnemonicFile != "" {
+			raw, err := os.ReadFile(c.MnemonicFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read mnemonic file: %w", err)
+			}
+			mnemonic = string(raw)
+		}
+		hdPath := c.HDPath
+		if hdPath == "" {
+			hdPath = DefaultHDPath
+		}
+		return openMnemonic(mnemonic, os.Getenv("SIGNER_MNEMONIC_PASSPHRASE"), hdPath)
+	case c.KeyEnv != "" && os.Getenv(c.KeyEnv) != "":
+		return openHexKey(os.Getenv(c.KeyEnv), "env:"+c.KeyEnv)
+	}
+	hint := "--clef, --keystore or --mnemonic-file"
+	if c.KeyEnv != "" {
+		hint += " (or set " + c.KeyEnv + ")"
+	}
+	return nil, errors.New("no signer configured: use " + hint)
+}
+
+// keySigner 持有本地私钥的签名器（keystore、助记词、环境变量私钥）
+type keySigner struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+	kind string
+}
+
+func newKeySigner(key *ecdsa.PrivateKey, kind string) *keySigner {
+	return &keySigner{key: key, addr: crypto.PubkeyToAddress(key.PublicKey), kind: kind}
+}
+
+func (s *keySigner) Address() common.Address { return s.addr }
+func (s *keySigner) Kind() string            { return s.kind }
+
+// String 只输出地址，防止误用 %v 打印出私钥
+func (s *keySigner) String() string { return fmt.Sprintf("%s(%s)", s.kind, s.addr.Hex()) }
+
+func (s *keySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *keySigner) SignText(text []byte) ([]byte, error) {
+	return s.signHash(accounts.TextHash(text))
+}
+
+func (s *keySigner) SignTypedData(data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	return s.signHash(hash)
+}
+
+// signHash 对 32 字节哈希签名，并把 v 从 0/1 转换为钱包通用的 27/28
+func (s *keySigner) signHash(hash []byte) ([]byte, error) {
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// openHexKey 解析十六进制私钥（可带 0x 前缀）
+func openHexKey(hexKey, kind string) (Signer, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		// 不在错误信息中包含私钥内容
+		return nil, errors.New("invalid private key in " + kind)
+	}
+	return newKeySigner(key, kind), nil
+}
+
+// TransactOpts 将 Signer 适配为 abigen 绑定使用的 bind.TransactOpts
+func TransactOpts(s Signer, chainID *big.Int) *bind.TransactOpts {
+	from := s.Address()
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return s.SignTx(tx, chainID)
+		},
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
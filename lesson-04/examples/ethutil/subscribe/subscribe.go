@@ -0,0 +1,348 @@
+// Package subscribe 与传输方式无关的订阅：WebSocket / IPC 连接使用 eth_subscribe，
+// HTTP 连接（或节点不支持订阅）时自动退回轮询，调用方拿到的通道和 ethereum.Subscription 完全相同：
+//   - 新区块头：eth_newBlockFilter + eth_getFilterChanges 得到新区块哈希，再查询区块头
+//   - 日志：eth_newFilter + eth_getFilterChanges
+//   - 过滤器过期（节点一段时间没有收到轮询会删除过滤器）或节点不支持过滤器时，改为按区块范围轮询：
+//     区块头用 eth_blockNumber + eth_getBlockByNumber，日志用 eth_getLogs，从上次处理到的区块之后继续，不遗漏
+//
+// 按区块范围轮询日志时看不到链重组（没有 Removed = true 的日志），需要时可以只处理有足够确认数的区块。
+package subscribe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// DefaultPollInterval 轮询间隔
+	DefaultPollInterval = 3 * time.Second
+	// DefaultMaxRange 按区块范围轮询日志时每次 eth_getLogs 的最大区块数
+	DefaultMaxRange = 1000
+
+	// sentBlocks 轮询新区块时记住最近多少个区块内输出过的区块哈希
+	sentBlocks = 64
+)
+
+// Subscriber 订阅新区块头和日志；同时实现 FilterLogs / BlockNumber，可以直接作为 logwatch.Client 使用
+type Subscriber struct {
+	client       *ethclient.Client
+	PollInterval time.Duration // 为 0 时使用 DefaultPollInterval
+	MaxRange     uint64        // 为 0 时使用 DefaultMaxRange
+	Polling      bool          // 为 true 时不尝试 eth_subscribe，直接轮询
+	Out          io.Writer     // 输出切换到轮询等提示，为 nil 时不输出
+}
+
+// New 创建 Subscriber
+func New(client *ethclient.Client) *Subscriber {
+	return &Subscriber{client: client, PollInterval: DefaultPollInterval, MaxRange: DefaultMaxRange}
+}
+
+// FilterLogs 直接调用 eth_getLogs
+func (s *Subscriber) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return s.client.FilterLogs(ctx, q)
+}
+
+// BlockNumber 当前区块号
+func (s *Subscriber) BlockNumber(ctx context.Context) (uint64, error) {
+	return s.client.BlockNumber(ctx)
+}
+
+// SubscribeNewHead 订阅新区块头，不支持订阅时轮询
+func (s *Subscriber) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	if !s.Polling {
+		sub, err := s.client.SubscribeNewHead(ctx, ch)
+		if err == nil || !Unsupported(err) {
+			return sub, err
+		}
+		s.logf("eth_subscribe is not available (%v), polling for new blocks every %s\n", err, s.interval())
+	}
+	// 先安装过滤器再读区块号：两次调用之间出块时由过滤器报告，不会落在两者之间的空隙里
+	var filterID string
+	if err := s.client.Client().CallContext(ctx, &filterID, "eth_newBlockFilter"); err != nil {
+		s.logf("eth_newBlockFilter is not available (%v), polling eth_blockNumber\n", err)
+		filterID = ""
+	}
+	last, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		s.uninstall(filterID)
+		return nil, fmt.Errorf("failed to get latest block number: %w", err)
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ctx, cancel := quitContext(quit)
+		defer cancel()
+		defer s.uninstall(filterID)
+		return s.pollHeads(ctx, filterID, last, ch)
+	}), nil
+}
+
+// pollHeads 轮询新区块；last 为已经处理过的区块号
+func (s *Subscriber) pollHeads(ctx context.Context, filterID string, last uint64, ch chan<- *types.Header) error {
+	ticker := time.NewTicker(s.interval())
+	defer ticker.Stop()
+	// sent 记录最近输出过的区块哈希，按区块号补齐之后过滤器再报告同一个区块时不重复输出
+	sent := map[common.Hash]uint64{}
+	emit := func(h *types.Header) bool {
+		if _, ok := sent[h.Hash()]; ok {
+			return true
+		}
+		if !send(ctx, ch, h) {
+			return false
+		}
+		n := h.Number.Uint64()
+		sent[h.Hash()] = n
+		last = max(last, n)
+		for hash, m := range sent {
+			if m+sentBlocks < last {
+				delete(sent, hash)
+			}
+		}
+		return true
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if filterID != "" {
+			var hashes []common.Hash
+			err := s.client.Client().CallContext(ctx, &hashes, "eth_getFilterChanges", filterID)
+			if err == nil {
+				caughtUp := true
+				for _, hash := range hashes {
+					h, err := s.client.HeaderByHash(ctx, hash)
+					if err != nil {
+						if ctx.Err() != nil {
+							return nil
+						}
+						// 区块可能已经被重组掉；跳过它，本轮剩下的区块按区块号补齐
+						s.logf("Failed to get block %s (%v), polling eth_blockNumber from block %d\n", hash.Hex(), err, last+1)
+						caughtUp = false
+						break
+					}
+					if !emit(h) {
+						return nil
+					}
+				}
+				if caughtUp {
+					continue
+				}
+			} else {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if !FilterGone(err) {
+					return fmt.Errorf("failed to poll block filter: %w", err)
+				}
+				s.logf("Block filter expired (%v), polling eth_blockNumber from block %d\n", err, last+1)
+				filterID = ""
+			}
+		}
+		head, err := s.client.BlockNumber(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to get latest block number: %w", err)
+		}
+		for n := last + 1; n <= head; n++ {
+			h, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("failed to get block %d: %w", n, err)
+			}
+			if !emit(h) {
+				return nil
+			}
+			last = n
+		}
+	}
+}
+
+// SubscribeFilterLogs 订阅日志，不支持订阅时轮询；q.FromBlock / q.ToBlock 被忽略，只推送新区块中的日志
+func (s *Subscriber) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if !s.Polling {
+		sub, err := s.client.SubscribeFilterLogs(ctx, q, ch)
+		if err == nil || !Unsupported(err) {
+			return sub, err
+		}
+		s.logf("eth_subscribe is not available (%v), polling for logs every %s\n", err, s.interval())
+	}
+	q.FromBlock, q.ToBlock = nil, nil
+	// 先安装过滤器再读区块号：covered 之后的区块都在过滤器安装之后产生，过滤器过期前不会漏掉
+	var filterID string
+	if err := s.client.Client().CallContext(ctx, &filterID, "eth_newFilter", filterArg(q)); err != nil {
+		s.logf("eth_newFilter is not available (%v), polling eth_getLogs\n", err)
+		filterID = ""
+	}
+	covered, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		s.uninstall(filterID)
+		return nil, fmt.Errorf("failed to get latest block number: %w", err)
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ctx, cancel := quitContext(quit)
+		defer cancel()
+		defer s.uninstall(filterID)
+		return s.pollLogs(ctx, filterID, q, covered, ch)
+	}), nil
+}
+
+// pollLogs 轮询日志；covered 为已经处理完的区块号
+func (s *Subscriber) pollLogs(ctx context.Context, filterID string, q ethereum.FilterQuery, covered uint64, ch chan<- types.Log) error {
+	ticker := time.NewTicker(s.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		// 先读区块号再取变化：这次取到的变化至少覆盖到 head，过滤器过期时从 head 之后按范围补齐
+		head, err := s.client.BlockNumber(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to get latest block number: %w", err)
+		}
+		if filterID != "" {
+			var logs []types.Log
+			err := s.client.Client().CallContext(ctx, &logs, "eth_getFilterChanges", filterID)
+			if err == nil {
+				for _, l := range logs {
+					if !send(ctx, ch, l) {
+						return nil
+					}
+					covered = max(covered, l.BlockNumber)
+				}
+				covered = max(covered, head)
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !FilterGone(err) {
+				return fmt.Errorf("failed to poll log filter: %w", err)
+			}
+			s.logf("Log filter expired (%v), polling eth_getLogs from block %d\n", err, covered+1)
+			filterID = ""
+		}
+		for covered < head {
+			from, to := covered+1, min(head, covered+s.maxRange())
+			q.FromBlock, q.ToBlock = new(big.Int).SetUint64(from), new(big.Int).SetUint64(to)
+			logs, err := s.client.FilterLogs(ctx, q)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("failed to get logs for blocks %d-%d: %w", from, to, err)
+			}
+			for _, l := range logs {
+				if !send(ctx, ch, l) {
+					return nil
+				}
+			}
+			covered = to
+		}
+	}
+}
+
+// uninstall 删除节点上的过滤器（忽略错误，过滤器过期后节点也会自动删除）
+func (s *Subscriber) uninstall(filterID string) {
+	if filterID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var ok bool
+	_ = s.client.Client().CallContext(ctx, &ok, "eth_uninstallFilter", filterID)
+}
+
+func (s *Subscriber) interval() time.Duration {
+	if s.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return s.PollInterval
+}
+
+func (s *Subscriber) maxRange() uint64 {
+	if s.MaxRange == 0 {
+		return DefaultMaxRange
+	}
+	return s.MaxRange
+}
+
+func (s *Subscriber) logf(format string, args ...interface{}) {
+	if s.Out != nil {
+		fmt.Fprintf(s.Out, format, args...)
+	}
+}
+
+// Unsupported 判断 eth_subscribe 的错误是否表示连接或节点不支持订阅（HTTP 连接、方法不存在）
+func Unsupported(err error) bool {
+	if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not supported") || strings.Contains(msg, "does not exist") || strings.Contains(msg, "method not found")
+}
+
+// FilterGone 判断 eth_getFilterChanges 的错误是否表示过滤器已经不存在（过期或节点重启）
+func FilterGone(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "filter not found") || strings.Contains(msg, "filter does not exist") || strings.Contains(msg, "unknown filter")
+}
+
+// filterArg 把 FilterQuery 转换为 eth_newFilter 的参数（不带区块范围，从最新区块开始）
+func filterArg(q ethereum.FilterQuery) map[string]interface{} {
+	arg := map[string]interface{}{}
+	if len(q.Addresses) > 0 {
+		arg["address"] = q.Addresses
+	}
+	if len(q.Topics) > 0 {
+		arg["topics"] = q.Topics
+	}
+	return arg
+}
+
+// quitContext 返回在 quit 关闭时取消的 context
+func quitContext(quit <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// send 发送到通道，ctx 取消时返回 false
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
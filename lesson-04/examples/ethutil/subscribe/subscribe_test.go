@@ -0,0 +1,339 @@
+package subscribe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeNode 只支持 HTTP 的 JSON-RPC 节点：一条链 + 一个过滤器（日志或区块）
+type fakeNode struct {
+	mu      sync.Mutex
+	head    uint64
+	headers map[uint64]*types.Header
+	logs    []types.Log
+	calls   []string
+
+	filter  string        // 已安装的过滤器类型："logs" / "blocks"，为空时没有过滤器
+	changes []interface{} // 过滤器尚未取走的变化
+	expired bool          // 为 true 时 eth_getFilterChanges 返回 filter not found
+
+	badHash     map[common.Hash]bool // eth_getBlockByHash 对这些哈希返回错误
+	onBadHash   func()               // 返回错误之前调用（持有锁）
+	uninstalled bool
+}
+
+func newFakeNode(head uint64) *fakeNode {
+	n := &fakeNode{head: head, headers: map[uint64]*types.Header{}, badHash: map[common.Hash]bool{}}
+	for i := uint64(0); i <= head; i++ {
+		n.headers[i] = mkHeader(i)
+	}
+	return n
+}
+
+func mkHeader(n uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(n), Difficulty: big.NewInt(0)}
+}
+
+// mine 产生下一个区块，每个区块带一条日志，返回区块头
+func (n *fakeNode) mine() *types.Header {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.mineLocked()
+}
+
+func (n *fakeNode) mineLocked() *types.Header {
+	n.head++
+	h := mkHeader(n.head)
+	n.headers[n.head] = h
+	l := types.Log{Topics: []common.Hash{}, BlockNumber: n.head, BlockHash: h.Hash()}
+	n.logs = append(n.logs, l)
+	switch n.filter {
+	case "logs":
+		n.changes = append(n.changes, l)
+	case "blocks":
+		n.changes = append(n.changes, h.Hash())
+	}
+	return h
+}
+
+// expire 让过滤器过期，之后产生的变化都不再记录
+func (n *fakeNode) expire() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.expired, n.filter, n.changes = true, "", nil
+}
+
+func (n *fakeNode) methods() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.calls...)
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := n.call(req.Method, req.Params)
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if err != nil {
+		resp["error"] = map[string]interface{}{"code": -32000, "message": err.Error()}
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (n *fakeNode) call(method string, params []json.RawMessage) (interface{}, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls = append(n.calls, method)
+	switch method {
+	case "eth_blockNumber":
+		return hexutil.Uint64(n.head), nil
+	case "eth_newFilter":
+		n.filter, n.changes, n.expired = "logs", nil, false
+		return "0x1", nil
+	case "eth_newBlockFilter":
+		n.filter, n.changes, n.expired = "blocks", nil, false
+		return "0x1", nil
+	case "eth_getFilterChanges":
+		if n.expired || n.filter == "" {
+			return nil, errors.New("filter not found")
+		}
+		changes := n.changes
+		n.changes = nil
+		if changes == nil {
+			changes = []interface{}{}
+		}
+		return changes, nil
+	case "eth_uninstallFilter":
+		n.uninstalled = true
+		return true, nil
+	case "eth_getLogs":
+		var arg struct {
+			FromBlock hexutil.Uint64 `json:"fromBlock"`
+			ToBlock   hexutil.Uint64 `json:"toBlock"`
+		}
+		if err := json.Unmarshal(params[0], &arg); err != nil {
+			return nil, err
+		}
+		logs := []types.Log{}
+		for _, l := range n.logs {
+			if l.BlockNumber >= uint64(arg.FromBlock) && l.BlockNumber <= uint64(arg.ToBlock) {
+				logs = append(logs, l)
+			}
+		}
+		return logs, nil
+	case "eth_getBlockByNumber":
+		var num hexutil.Uint64
+		if err := json.Unmarshal(params[0], &num); err != nil {
+			return nil, err
+		}
+		if h, ok := n.headers[uint64(num)]; ok {
+			return h, nil
+		}
+		return nil, nil
+	case "eth_getBlockByHash":
+		var hash common.Hash
+		if err := json.Unmarshal(params[0], &hash); err != nil {
+			return nil, err
+		}
+		if n.badHash[hash] {
+			if n.onBadHash != nil {
+				n.onBadHash()
+			}
+			return nil, errors.New("header not found")
+		}
+		for _, h := range n.headers {
+			if h.Hash() == hash {
+				return h, nil
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("the method %s does not exist/is not available", method)
+}
+
+func dialFake(t *testing.T, n *fakeNode) *Subscriber {
+	t.Helper()
+	srv := httptest.NewServer(n)
+	t.Cleanup(srv.Close)
+	client, err := ethclient.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	s := New(client)
+	s.PollInterval = 5 * time.Millisecond
+	return s
+}
+
+// collect 从通道读取 want 个值的区块号，再确认之后没有多余的值
+func collect[T any](t *testing.T, ch <-chan T, sub ethereum.Subscription, number func(T) uint64, want int) []uint64 {
+	t.Helper()
+	var got []uint64
+	timeout := time.After(2 * time.Second)
+	for len(got) < want {
+		select {
+		case v := <-ch:
+			got = append(got, number(v))
+		case err := <-sub.Err():
+			t.Fatalf("subscription ended: %v (got %v)", err, got)
+		case <-timeout:
+			t.Fatalf("timed out, got %v", got)
+		}
+	}
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected extra value at block %d (got %v)", number(v), got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return got
+}
+
+func wantBlocks(t *testing.T, got []uint64, from, to uint64) {
+	t.Helper()
+	var want []uint64
+	for n := from; n <= to; n++ {
+		want = append(want, n)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("blocks = %v, want %v", got, want)
+	}
+}
+
+// TestSubscribeFilterLogs HTTP 连接退回 eth_newFilter 轮询；过滤器过期后改用 eth_getLogs 从上次处理到的区块之后继续，
+// 不遗漏也不重复
+func TestSubscribeFilterLogs(t *testing.T) {
+	node := newFakeNode(10)
+	s := dialFake(t, node)
+	s.MaxRange = 2
+	ch := make(chan types.Log)
+	sub, err := s.SubscribeFilterLogs(context.Background(), ethereum.FilterQuery{}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	// 过滤器必须先于 eth_blockNumber 安装，否则两次调用之间产生的区块既不在过滤器里也不会被补齐
+	if calls := strings.Join(node.methods(), ","); !strings.HasPrefix(calls, "eth_newFilter,eth_blockNumber") {
+		t.Errorf("calls = %s, want eth_newFilter before eth_blockNumber", calls)
+	}
+
+	node.mine()
+	node.mine()
+	got := collect(t, ch, sub, func(l types.Log) uint64 { return l.BlockNumber }, 2)
+	node.expire()
+	for i := 0; i < 5; i++ {
+		node.mine()
+	}
+	got = append(got, collect(t, ch, sub, func(l types.Log) uint64 { return l.BlockNumber }, 5)...)
+	wantBlocks(t, got, 11, 17)
+}
+
+// TestSubscribeNewHead 区块过滤器报告的区块查询失败（例如已经被重组掉）时不结束订阅，
+// 按区块号补齐，之后过滤器再报告已经输出的区块时不重复；过滤器过期后改用 eth_blockNumber
+func TestSubscribeNewHead(t *testing.T) {
+	node := newFakeNode(10)
+	s := dialFake(t, node)
+	ch := make(chan *types.Header)
+	sub, err := s.SubscribeNewHead(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	number := func(h *types.Header) uint64 { return h.Number.Uint64() }
+
+	node.mine()
+	got := collect(t, ch, sub, number, 1)
+
+	// 区块 12 按哈希查询失败；失败时又产生区块 14，按区块号补齐时输出，下一轮过滤器报告它时跳过
+	node.mu.Lock()
+	bad := node.mineLocked()
+	node.mineLocked()
+	node.badHash[bad.Hash()] = true
+	node.onBadHash = func() {
+		node.onBadHash = nil
+		node.mineLocked()
+	}
+	node.mu.Unlock()
+	got = append(got, collect(t, ch, sub, number, 3)...)
+
+	node.expire()
+	node.mine()
+	node.mine()
+	got = append(got, collect(t, ch, sub, number, 2)...)
+	wantBlocks(t, got, 11, 16)
+}
+
+type testRPCError struct {
+	code int
+	msg  string
+}
+
+func (e testRPCError) Error() string  { return e.msg }
+func (e testRPCError) ErrorCode() int { return e.code }
+
+func TestUnsupported(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{rpc.ErrNotificationsUnsupported, true},
+		{fmt.Errorf("failed to subscribe: %w", rpc.ErrNotificationsUnsupported), true},
+		{testRPCError{-32601, "rpc method eth_subscribe not found"}, true},
+		{errors.New("the method eth_subscribe does not exist/is not available"), true},
+		{errors.New("notifications not supported"), true},
+		{errors.New("Method not found"), true},
+		{testRPCError{-32000, "execution reverted"}, false},
+		{errors.New("connection refused"), false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		if got := Unsupported(tt.err); got != tt.want {
+			t.Errorf("Unsupported(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFilterGone(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("filter not found"), true},
+		{errors.New("Filter not found"), true},
+		{errors.New("filter does not exist"), true},
+		{fmt.Errorf("poll: %w", errors.New("unknown filter id 0x1")), true},
+		{errors.New("header not found"), false},
+		{errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := FilterGone(tt.err); got != tt.want {
+			t.Errorf("FilterGone(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package tracer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RenderOptions 控制调用树的渲染方式
+type RenderOptions struct {
+	// Method 根据调用的 input 返回可读的函数名（例如通过 ABI 匹配 4 字节选择器），
+	// 无法识别时返回空字符串
+	Method func(input []byte) string
+	// Revert 将回滚数据（调用帧的 output）解码为可读原因，为 nil 时只显示节点给出的错误
+	Revert func(output []byte) string
+}
+
+// Render 以缩进树的形式输出调用树
+//
+//	CALL 0xSender → 0xToken  transfer(address,uint256)  value=0 gasUsed=35012
+//	├─ STATICCALL 0xToken → 0xOracle  latestAnswer()  gasUsed=2300
+//	└─ CALL 0xToken → 0xHook  onTransfer(...)  gasUsed=5000  ✗ execution reverted: not allowed
+func Render(w io.Writer, root *CallFrame, opts RenderOptions) {
+	renderFrame(w, root, "", "", opts)
+}
+
+func renderFrame(w io.Writer, f *CallFrame, prefix, childPrefix string, opts RenderOptions) {
+	fmt.Fprintf(w, "%s%s\n", prefix, describeFrame(f, opts))
+	for i := range f.Calls {
+		if i == len(f.Calls)-1 {
+			renderFrame(w, &f.Calls[i], childPrefix+"└─ ", childPrefix+"   ", opts)
+		} else {
+			renderFrame(w, &f.Calls[i], childPrefix+"├─ ", childPrefix+"│  ", opts)
+		}
+	}
+}
+
+// describeFrame 生成单个调用帧的一行描述
+func describeFrame(f *CallFrame, opts RenderOptions) string {
+	var b strings.Builder
+	to := "(new contract)"
+	if f.To != nil {
+		to = f.To.Hex()
+	}
+	fmt.Fprintf(&b, "%s %s → %s", f.Type, f.From.Hex(), to)
+
+	if name := frameMethod(f, opts); name != "" {
+		fmt.Fprintf(&b, "  %s", name)
+	}
+	if f.Value != nil && f.Value.ToInt().Sign() > 0 {
+		fmt.Fprintf(&b, "  value=%s wei", f.Value.ToInt().String())
+	}
+	fmt.Fprintf(&b, "  gasUsed=%d", uint64(f.GasUsed))
+
+	if f.Error != "" {
+		reason := f.RevertReason
+		if reason == "" && opts.Revert != nil && len(f.Output) > 0 {
+			reason = opts.Revert(f.Output)
+		}
+		if reason != "" {
+			fmt.Fprintf(&b, "  ✗ %s: %s", f.Error, reason)
+		} else {
+			fmt.Fprintf(&b, "  ✗ %s", f.Error)
+		}
+	}
+	return b.String()
+}
+
+// frameMethod 返回调用的函数名；合约创建、纯 ETH 转账没有函数选择器
+func frameMethod(f *CallFrame, opts RenderOptions) string {
+	switch f.Type {
+	case "CREATE", "CREATE2":
+		return "<constructor>"
+	}
+	if len(f.Input) == 0 {
+		if f.Value != nil && f.Value.ToInt().Sign() > 0 {
+			return "<receive>"
+		}
+		return ""
+	}
+	if len(f.Input) < 4 {
+		return "<fallback>"
+	}
+	if opts.Method != nil {
+		if name := opts.Method(f.Input); name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%x", f.Input[:4])
+}
+
+// Stats 统计调用树中的调用总数、最大深度和失败调用数
+func (f *CallFrame) Stats() (calls, depth, failed int) {
+	var walk func(fr *CallFrame, d int)
+	walk = func(fr *CallFrame, d int) {
+		calls++
+		if d > depth {
+			depth = d
+		}
+		if fr.Error != "" {
+			failed++
+		}
+		for i := range fr.Calls {
+			walk(&fr.Calls[i], d+1)
+		}
+	}
+	walk(f, 1)
+	return calls, depth, failed
+}
@@ -0,0 +1,174 @@
+// Package tracer 封装 debug 命名空间下的追踪接口（debug_traceCall 等），
+// 并定义 callTracer / prestateTracer 返回结果对应的 Go 结构体。
+//
+// 注意：debug 命名空间通常只在自建节点（geth --http.api debug）、anvil、hardhat 等环境开放，
+// 大多数公共 RPC 服务商不支持，调用方需要通过 IsUnsupported 判断并优雅降级。
+package tracer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CallFrame 对应 callTracer 输出的一次调用（可嵌套）
+type CallFrame struct {
+	Type         string          `json:"type"` // CALL / STATICCALL / DELEGATECALL / CREATE / CREATE2 ...
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to,omitempty"`
+	Value        *hexutil.Big    `json:"value,omitempty"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Calls        []CallFrame     `json:"calls,omitempty"`
+	Logs         []CallLog       `json:"logs,omitempty"`
+}
+
+// CallLog 对应 callTracer 在 withLog=true 时附带的日志
+// 被回滚的调用中产生的日志不会出现在结果里
+type CallLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// AccountState 对应 prestateTracer 输出中单个账户的状态
+type AccountState struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// PrestateDiff 对应 prestateTracer 在 diffMode=true 时的输出
+// Pre 是执行前被修改账户的状态，Post 只包含执行后发生变化的字段
+type PrestateDiff struct {
+	Pre  map[common.Address]*AccountState `json:"pre"`
+	Post map[common.Address]*AccountState `json:"post"`
+}
+
+// ToCallArg 将 ethereum.CallMsg 转换为 JSON-RPC 的调用参数（与 ethclient 内部实现保持一致）
+func ToCallArg(msg ethereum.CallMsg) map[string]interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	return arg
+}
+
+// TraceCallTree 使用 callTracer（带日志）模拟执行一次调用，返回完整的调用树
+// block 为区块标签（如 "pending"、"latest"）或十六进制区块号
+func TraceCallTree(ctx context.Context, c *rpc.Client, msg ethereum.CallMsg, block string) (*CallFrame, error) {
+	var frame CallFrame
+	config := map[string]interface{}{
+		"tracer":       "callTracer",
+		"tracerConfig": map[string]interface{}{"withLog": true},
+	}
+	if err := c.CallContext(ctx, &frame, "debug_traceCall", ToCallArg(msg), block, config); err != nil {
+		return nil, err
+	}
+	return &frame, nil
+}
+
+// TraceTransaction 使用 callTracer 重新执行一笔已上链的交易，返回完整的调用树
+// withLog 为 true 时每个调用帧会附带该调用产生的日志
+func TraceTransaction(ctx context.Context, c *rpc.Client, txHash common.Hash, withLog bool) (*CallFrame, error) {
+	var frame CallFrame
+	config := map[string]interface{}{
+		"tracer":       "callTracer",
+		"tracerConfig": map[string]interface{}{"withLog": withLog},
+		// 复杂交易重新执行可能较慢，默认 5s 超时不够用
+		"timeout": "60s",
+	}
+	if err := c.CallContext(ctx, &frame, "debug_traceTransaction", txHash, config); err != nil {
+		return nil, err
+	}
+	return &frame, nil
+}
+
+// TraceCallPrestateDiff 使用 prestateTracer（diffMode）模拟执行一次调用，返回执行前后的账户状态差异
+func TraceCallPrestateDiff(ctx context.Context, c *rpc.Client, msg ethereum.CallMsg, block string) (*PrestateDiff, error) {
+	var diff PrestateDiff
+	config := map[string]interface{}{
+		"tracer":       "prestateTracer",
+		"tracerConfig": map[string]interface{}{"diffMode": true},
+	}
+	if err := c.CallContext(ctx, &diff, "debug_traceCall", ToCallArg(msg), block, config); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// BalanceDeltas 计算 diff 中每个账户的 ETH 余额变化（post - pre），未变化的账户不返回
+// diffMode 的 post 只包含变化了的字段：post 中有账户但没有余额表示余额未变化；
+// pre 中有、post 中完全没有的账户已被删除（selfdestruct / EIP-161 清理空账户），按余额为 0 计算
+func (d *PrestateDiff) BalanceDeltas() map[common.Address]*big.Int {
+	deltas := make(map[common.Address]*big.Int)
+	for addr, post := range d.Post {
+		if post == nil || post.Balance == nil {
+			continue
+		}
+		before := new(big.Int)
+		if pre, ok := d.Pre[addr]; ok && pre != nil && pre.Balance != nil {
+			before = pre.Balance.ToInt()
+		}
+		delta := new(big.Int).Sub(post.Balance.ToInt(), before)
+		if delta.Sign() != 0 {
+			deltas[addr] = delta
+		}
+	}
+	for addr, pre := range d.Pre {
+		if _, ok := d.Post[addr]; ok || pre == nil || pre.Balance == nil {
+			continue
+		}
+		if pre.Balance.ToInt().Sign() != 0 {
+			deltas[addr] = new(big.Int).Neg(pre.Balance.ToInt())
+		}
+	}
+	return deltas
+}
+
+// IsUnsupported 判断错误是否表示节点未开放 debug 命名空间或不支持指定的 tracer
+func IsUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"method not found", "does not exist", "not available", "not supported", "unsupported", "unknown tracer"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package tracer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBalanceDeltas(t *testing.T) {
+	a, b := common.HexToAddress("0xaa"), common.HexToAddress("0xbb")
+	// 用例中的 "0xaa" 等账户展开为完整地址
+	expand := strings.NewReplacer(`"0xaa"`, `"`+a.Hex()+`"`, `"0xbb"`, `"`+b.Hex()+`"`, `"0xcc"`, `"`+common.HexToAddress("0xcc").Hex()+`"`)
+	tests := []struct {
+		name string
+		diff string
+		want map[common.Address]int64
+	}{
+		{
+			"transfer",
+			`{"pre":{"0xaa":{"balance":"0x64","nonce":1},"0xbb":{"balance":"0xa"}},
+			  "post":{"0xaa":{"balance":"0x50","nonce":2},"0xbb":{"balance":"0x1e"}}}`,
+			map[common.Address]int64{a: -20, b: 20},
+		},
+		{
+			"new account",
+			`{"pre":{"0xaa":{"balance":"0x64"}},"post":{"0xaa":{"balance":"0x5a"},"0xbb":{"balance":"0xa"}}}`,
+			map[common.Address]int64{a: -10, b: 10},
+		},
+		{
+			"post without balance is unchanged",
+			`{"pre":{"0xaa":{"balance":"0x64","nonce":1}},"post":{"0xaa":{"nonce":2}}}`,
+			map[common.Address]int64{},
+		},
+		{
+			// selfdestruct 把余额转给 b 后账户被删除，只出现在 pre 中；余额为 0 的空账户被清理时没有变化
+			"deleted account",
+			`{"pre":{"0xaa":{"balance":"0x64"},"0xbb":{"balance":"0x1"},"0xcc":{"balance":"0x0","nonce":1}},
+			  "post":{"0xbb":{"balance":"0x65"}}}`,
+			map[common.Address]int64{a: -100, b: 100},
+		},
+		{
+			"balance back to original",
+			`{"pre":{"0xcc":{"balance":"0x64"}},"post":{"0xcc":{"balance":"0x64","nonce":3}}}`,
+			map[common.Address]int64{},
+		},
+	}
+	for _, tt := range tests {
+		var diff PrestateDiff
+		if err := json.Unmarshal([]byte(expand.Replace(tt.diff)), &diff); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := diff.BalanceDeltas()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: BalanceDeltas() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for addr, want := range tt.want {
+			if d, ok := got[addr]; !ok || d.Int64() != want {
+				t.Errorf("%s: delta of %s = %v, want %d", tt.name, addr.Hex(), d, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package txsim 在签名前对交易做一次预执行（dry run）：
+//  1. 在 pending 状态上执行 eth_call，判断交易是否会回滚并解码回滚原因（见 revert 包）
+//  2. 如果节点支持 debug_traceCall，则使用 prestateTracer(diffMode) 计算 ETH 余额变化，
+//     并使用 callTracer(withLog) 汇总 ERC-20 Transfer 日志得到代币余额变化
+package txsim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethutil/revert"
+	"ethutil/tracer"
+	"ethutil/units"
+)
+
+// simulateBlock 预执行使用的区块标签：pending 包含内存池中已排队的交易（如同一账户更早的 nonce）
+const simulateBlock = "pending"
+
+// transferTopic 是 ERC-20 Transfer(address,address,uint256) 事件签名哈希
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// TokenChange 单个地址在某个代币上的余额变化（原始最小单位）
+type TokenChange struct {
+	Token  common.Address
+	Holder common.Address
+	Delta  *big.Int
+}
+
+// Result 预执行结果
+type Result struct {
+	ReturnData []byte
+	// Revert 非 nil 表示预执行回滚
+	Revert *revert.Reason
+
+	// ETHChanges / TokenChanges 仅在节点支持 debug_traceCall 时填充
+	ETHChanges   map[common.Address]*big.Int
+	TokenChanges []TokenChange
+	// TraceErr 记录余额预览不可用的原因（例如节点未开放 debug 命名空间）
+	TraceErr error
+}
+
+// Simulate 对 msg 执行预执行。只有网络等非回滚错误才会返回 error，
+// 回滚结果通过 Result.Revert 返回；decoder 用于解码自定义错误，可以为 nil
+func Simulate(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg, decoder *revert.Decoder) (*Result, error) {
+	res := &Result{}
+
+	output, err := client.PendingCallContract(ctx, msg)
+	if err != nil {
+		res.Revert = decoder.FromError(err)
+		if res.Revert == nil {
+			return nil, fmt.Errorf("eth_call failed: %w", err)
+		}
+		// 回滚的交易不会产生任何状态变化，无需再做余额预览
+		return res, nil
+	}
+	res.ReturnData = output
+
+	rpcClient := client.Client()
+	diff, err := tracer.TraceCallPrestateDiff(ctx, rpcClient, msg, simulateBlock)
+	if err != nil {
+		res.TraceErr = err
+		return res, nil
+	}
+	res.ETHChanges = diff.BalanceDeltas()
+
+	frame, err := tracer.TraceCallTree(ctx, rpcClient, msg, simulateBlock)
+	if err != nil {
+		res.TraceErr = err
+		return res, nil
+	}
+	res.TokenChanges = tokenChanges(frame)
+
+	return res, nil
+}
+
+// tokenChanges 遍历调用树中的 ERC-20 Transfer 日志，汇总每个地址在每个代币上的余额变化
+func tokenChanges(frame *tracer.CallFrame) []TokenChange {
+	type key struct{ token, holder common.Address }
+	deltas := make(map[key]*big.Int)
+	add := func(token, holder common.Address, v *big.Int) {
+		k := key{token, holder}
+		if deltas[k] == nil {
+			deltas[k] = new(big.Int)
+		}
+		deltas[k].Add(deltas[k], v)
+	}
+
+	var walk func(f *tracer.CallFrame)
+	walk = func(f *tracer.CallFrame) {
+		for _, l := range f.Logs {
+			// ERC-20 Transfer: 3 个 topics + 32 字节 data；ERC-721 的 tokenId 是 indexed，有 4 个 topics
+			if len(l.Topics) != 3 || l.Topics[0] != transferTopic || len(l.Data) != 32 {
+				continue
+			}
+			from := common.BytesToAddress(l.Topics[1].Bytes())
+			to := common.BytesToAddress(l.Topics[2].Bytes())
+			value := new(big.Int).SetBytes(l.Data)
+			add(l.Address, from, new(big.Int).Neg(value))
+			add(l.Address, to, value)
+		}
+		for i := range f.Calls {
+			walk(&f.Calls[i])
+		}
+	}
+	walk(frame)
+
+	var out []TokenChange
+	for k, d := range deltas {
+		if d.Sign() == 0 {
+			continue
+		}
+		out = append(out, TokenChange{Token: k.token, Holder: k.holder, Delta: d})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if c := bytes.Compare(out[i].Token[:], out[j].Token[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(out[i].Holder[:], out[j].Holder[:]) < 0
+	})
+	return out
+}
+
+// Print 以可读格式输出预执行结果
+func (r *Result) Print(w io.Writer) {
+	fmt.Fprintf(w, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	fmt.Fprintf(w, "Pre-flight Simulation (eth_call @ %s)\n", simulateBlock)
+	fmt.Fprintf(w, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	if r.Revert != nil {
+		fmt.Fprintf(w, "Result        : REVERTED\n")
+		fmt.Fprintf(w, "Revert Reason : %s\n", r.Revert)
+		if len(r.Revert.Data) > 0 {
+			fmt.Fprintf(w, "Revert Data   : 0x%x\n", r.Revert.Data)
+		}
+		fmt.Fprintf(w, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		return
+	}
+
+	fmt.Fprintf(w, "Result        : success\n")
+	if len(r.ReturnData) > 0 {
+		fmt.Fprintf(w, "Return Data   : 0x%x\n", r.ReturnData)
+	}
+
+	fmt.Fprintf(w, "\nExpected Balance Changes:\n")
+	fmt.Fprintf(w, "────────────────────────────────────────────────────────\n")
+	if r.TraceErr != nil {
+		if tracer.IsUnsupported(r.TraceErr) {
+			fmt.Fprintf(w, "  (unavailable: node does not support debug_traceCall)\n")
+		} else {
+			fmt.Fprintf(w, "  (unavailable: %v)\n", r.TraceErr)
+		}
+	} else if len(r.ETHChanges) == 0 && len(r.TokenChanges) == 0 {
+		fmt.Fprintf(w, "  (no balance changes)\n")
+	} else {
+		addrs := make([]common.Address, 0, len(r.ETHChanges))
+		for addr := range r.ETHChanges {
+			addrs = append(addrs, addr)
+		}
+		sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+		for _, addr := range addrs {
+			fmt.Fprintf(w, "  ETH    %s  %s ETH\n", addr.Hex(), units.FormatSigned(r.ETHChanges[addr], units.EtherDecimals))
+		}
+		for _, c := range r.TokenChanges {
+			fmt.Fprintf(w, "  Token  %s  %s raw (token %s)\n", c.Holder.Hex(), units.FormatSigned(c.Delta, units.WeiDecimals), c.Token.Hex())
+		}
+	}
+	fmt.Fprintf(w, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+}
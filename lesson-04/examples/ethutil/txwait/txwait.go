@@ -0,0 +1,233 @@
+// Package txwait 等待交易达到指定的确认深度（或被 safe / finalized 区块覆盖），并感知链重组：
+//   - 收到回执后会核对回执中的区块哈希是否仍是该高度的规范区块，被重组掉的回执不计入确认
+//   - 已经得到的回执消失或换了区块时记为一次重组，继续等待交易重新被打包
+//   - 节点支持订阅（WebSocket / IPC）时通过 newHeads 在每个新区块到来时检查，否则按固定间隔轮询；
+//     订阅中途断开也会自动退回轮询
+package txwait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultPollInterval 不支持订阅时的轮询间隔
+const DefaultPollInterval = 3 * time.Second
+
+// Target 等待目标：Confirmations 个确认，或 Tag 指定的 safe / finalized 区块覆盖交易所在区块
+type Target struct {
+	Confirmations uint64
+	Tag           string // "safe" / "finalized"，为空时使用 Confirmations
+}
+
+// ParseTarget 解析等待目标："3" 表示 3 个确认（包含交易所在区块），"safe"、"finalized" 表示等待对应的区块标签
+func ParseTarget(s string) (Target, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "safe", "finalized":
+		return Target{Tag: s}, nil
+	case "":
+		return Target{Confirmations: 1}, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 {
+		return Target{}, fmt.Errorf("invalid wait target %q: use a confirmation count >= 1, safe or finalized", s)
+	}
+	return Target{Confirmations: n}, nil
+}
+
+func (t Target) String() string {
+	if t.Tag != "" {
+		return t.Tag
+	}
+	return fmt.Sprintf("%d confirmation(s)", t.Confirmations)
+}
+
+// Client Wait 需要的节点接口，*ethclient.Client 满足该接口
+type Client interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// Options 等待参数
+type Options struct {
+	Target       Target
+	PollInterval time.Duration // 为 0 时使用 DefaultPollInterval
+	// Out 输出等待进度（订阅/轮询模式、打包、确认数、重组），为 nil 时不输出
+	Out io.Writer
+}
+
+// Result 等待结果
+type Result struct {
+	Receipt       *types.Receipt
+	Confirmations uint64 // 达到目标时的确认数（包含交易所在区块）
+	Reorgs        int    // 等待期间检测到的重组次数
+}
+
+// ErrReorged 交易所在区块被重组掉、且直到超时都没有被重新打包
+var ErrReorged = errors.New("transaction was reorged out and not re-included")
+
+// Wait 等待交易达到 opts.Target；ctx 控制最长等待时间，超时返回 ctx.Err()（若期间发生过重组则返回 ErrReorged）
+func Wait(ctx context.Context, client Client, txHash common.Hash, opts Options) (*Result, error) {
+	if opts.Target.Tag == "" && opts.Target.Confirmations == 0 {
+		opts.Target.Confirmations = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	w := &waiter{client: client, txHash: txHash, opts: opts}
+
+	heads := make(chan *types.Header, 16)
+	sub, err := client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		// HTTP 连接不支持订阅（rpc.ErrNotificationsUnsupported），使用轮询
+		w.logf("Waiting for %s (polling every %s)...\n", opts.Target, opts.PollInterval)
+		sub = nil
+	} else {
+		w.logf("Waiting for %s (subscribed to new heads)...\n", opts.Target)
+		defer sub.Unsubscribe()
+	}
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	var subErr <-chan error
+	if sub != nil {
+		subErr = sub.Err()
+		// 订阅模式下只在新区块到来时检查；保留一个较慢的兜底轮询，防止漏掉通知
+		ticker.Reset(opts.PollInterval * 10)
+	}
+
+	for {
+		res, err := w.check(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.logf("Warning: %v\n", err)
+		}
+		if res != nil {
+			return res, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if w.reorgs > 0 && w.receipt == nil {
+				return nil, ErrReorged
+			}
+			return nil, ctx.Err()
+		case <-heads:
+		case err := <-subErr:
+			w.logf("Subscription dropped (%v), falling back to polling\n", err)
+			subErr = nil
+			ticker.Reset(opts.PollInterval)
+		case <-ticker.C:
+		}
+	}
+}
+
+// waiter 保存等待过程中的状态
+type waiter struct {
+	client  Client
+	txHash  common.Hash
+	opts    Options
+	receipt *types.Receipt // 最近一次看到的规范回执
+	lastLog uint64         // 上一次输出的确认数，避免重复输出
+	reorgs  int
+}
+
+func (w *waiter) logf(format string, args ...interface{}) {
+	if w.opts.Out != nil {
+		fmt.Fprintf(w.opts.Out, format, args...)
+	}
+}
+
+// check 检查一次交易状态，达到目标时返回结果
+func (w *waiter) check(ctx context.Context) (*Result, error) {
+	receipt, err := w.client.TransactionReceipt(ctx, w.txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		if w.receipt != nil {
+			w.reorgs++
+			w.logf("⚠️  Reorg detected: block %d (%s) no longer contains the transaction, waiting for re-inclusion\n",
+				w.receipt.BlockNumber.Uint64(), w.receipt.BlockHash.Hex())
+			w.receipt, w.lastLog = nil, 0
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	// 回执所在区块必须仍是该高度的规范区块（部分节点在重组后短时间内仍会返回旧回执）
+	header, err := w.client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d: %w", receipt.BlockNumber.Uint64(), err)
+	}
+	if header.Hash() != receipt.BlockHash {
+		return nil, nil
+	}
+
+	if w.receipt == nil {
+		w.logf("Included in block %d (%s)\n", receipt.BlockNumber.Uint64(), receipt.BlockHash.Hex())
+	} else if w.receipt.BlockHash != receipt.BlockHash {
+		w.reorgs++
+		w.logf("⚠️  Reorg detected: transaction moved from block %d (%s) to block %d (%s)\n",
+			w.receipt.BlockNumber.Uint64(), w.receipt.BlockHash.Hex(), receipt.BlockNumber.Uint64(), receipt.BlockHash.Hex())
+		w.lastLog = 0
+	}
+	w.receipt = receipt
+
+	// 当前链头高度，用于计算确认数
+	head, err := w.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	mined := receipt.BlockNumber.Uint64()
+	confirmations := uint64(0)
+	if head.Number.Uint64() >= mined {
+		confirmations = head.Number.Uint64() - mined + 1
+	}
+
+	done := false
+	if w.opts.Target.Tag != "" {
+		tagged, err := w.tagNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tagged >= mined {
+			done = true
+		} else if confirmations != w.lastLog {
+			w.logf("Confirmations: %d (%s block: %d, need >= %d)\n", confirmations, w.opts.Target.Tag, tagged, mined)
+		}
+	} else {
+		if confirmations >= w.opts.Target.Confirmations {
+			done = true
+		} else if confirmations != w.lastLog {
+			w.logf("Confirmations: %d/%d\n", confirmations, w.opts.Target.Confirmations)
+		}
+	}
+	w.lastLog = confirmations
+	if !done {
+		return nil, nil
+	}
+	return &Result{Receipt: receipt, Confirmations: confirmations, Reorgs: w.reorgs}, nil
+}
+
+// tagNumber 查询 safe / finalized 区块高度
+func (w *waiter) tagNumber(ctx context.Context) (uint64, error) {
+	tag := rpc.SafeBlockNumber
+	if w.opts.Target.Tag == "finalized" {
+		tag = rpc.FinalizedBlockNumber
+	}
+	header, err := w.client.HeaderByNumber(ctx, big.NewInt(int64(tag)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s block (node may not support it): %w", w.opts.Target.Tag, err)
+	}
+	return header.Number.Uint64(), nil
+}
@@ -0,0 +1,160 @@
+// Package units 在十进制字符串与最小单位整数之间做精确转换（不经过 float64 / big.Float），
+// 适用于 ETH（18 位）、gwei（9 位）以及任意 decimals 的代币数量。
+//
+//	units.Parse("1.5", 6)           // 1500000
+//	units.ParseEther("1.5")         // 1500000000000000000（默认单位 ether）
+//	units.ParseEther("30gwei")      // 30000000000
+//	units.ParseEther("100 wei")     // 100
+//	units.Parse("0.0000001", 6)     // error: 超过 6 位小数
+//	units.Format(big.NewInt(1500000), 6) // "1.5"
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// 常用单位的小数位数
+const (
+	WeiDecimals   = 0
+	GweiDecimals  = 9
+	EtherDecimals = 18
+)
+
+// suffixes 支持的单位后缀（不区分大小写），按长度从长到短匹配
+var suffixes = []struct {
+	name     string
+	decimals int
+}{
+	{"ether", EtherDecimals},
+	{"gwei", GweiDecimals},
+	{"eth", EtherDecimals},
+	{"wei", WeiDecimals},
+}
+
+// ErrTooManyDecimals 小数位数超过单位精度（例如 wei 不能有小数）
+var ErrTooManyDecimals = errors.New("too many decimal places")
+
+// Parse 将十进制字符串（如 "1.5"、".5"、"1000"）按 decimals 精确转换为最小单位。
+// 只接受非负的普通十进制写法，不接受符号、千分位和科学计数法；小数位数超过 decimals 时返回 ErrTooManyDecimals，
+// 结果超过 uint256 时返回错误
+func Parse(s string, decimals int) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("empty amount")
+	}
+	if decimals < 0 {
+		return nil, fmt.Errorf("invalid decimals %d", decimals)
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if (intPart == "" && frac == "") || !isDigits(intPart) || !isDigits(frac) {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	// 末尾多余的 0 不算有效小数位："1.500000" 对 6 位精度的代币是合法的
+	trimmed := strings.TrimRight(frac, "0")
+	if len(trimmed) > decimals {
+		return nil, fmt.Errorf("%w: %q has %d, at most %d allowed", ErrTooManyDecimals, s, len(trimmed), decimals)
+	}
+	digits := intPart + trimmed + strings.Repeat("0", decimals-len(trimmed))
+	v, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	// 链上金额（ETH 余额、代币数量）都是 uint256，超出时在这里报错，而不是在编码交易时失败
+	if v.BitLen() > 256 {
+		return nil, fmt.Errorf("amount %q exceeds uint256", s)
+	}
+	return v, nil
+}
+
+// ParseWithUnit 解析带可选单位后缀的金额（如 "1.5ether"、"30 gwei"、"100wei"），
+// 没有后缀时使用 defaultDecimals
+func ParseWithUnit(s string, defaultDecimals int) (*big.Int, error) {
+	num, decimals := splitUnit(s, defaultDecimals)
+	return Parse(num, decimals)
+}
+
+// ParseEther 解析 ETH 金额，返回 wei；默认单位为 ether，可以使用 ether / eth / gwei / wei 后缀
+func ParseEther(s string) (*big.Int, error) {
+	return ParseWithUnit(s, EtherDecimals)
+}
+
+// ParseGwei 解析 Gas 价格，返回 wei；默认单位为 gwei，可以使用后缀
+func ParseGwei(s string) (*big.Int, error) {
+	return ParseWithUnit(s, GweiDecimals)
+}
+
+// splitUnit 拆分数字和单位后缀
+func splitUnit(s string, defaultDecimals int) (string, int) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+	for _, u := range suffixes {
+		if strings.HasSuffix(lower, u.name) {
+			return strings.TrimSpace(s[:len(s)-len(u.name)]), u.decimals
+		}
+	}
+	return s, defaultDecimals
+}
+
+// Format 将最小单位格式化为十进制字符串，去掉小数部分末尾的 0（如 1500000, 6 → "1.5"）
+func Format(v *big.Int, decimals int) string {
+	if v == nil {
+		return "0"
+	}
+	neg := v.Sign() < 0
+	s := new(big.Int).Abs(v).String()
+	if decimals > 0 {
+		if len(s) <= decimals {
+			s = strings.Repeat("0", decimals-len(s)+1) + s
+		}
+		s = strings.TrimRight(s[:len(s)-decimals]+"."+s[len(s)-decimals:], "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// FormatFixed 与 Format 相同，但固定保留 places 位小数（截断而非四舍五入，避免显示出并不存在的余额）
+func FormatFixed(v *big.Int, decimals, places int) string {
+	s := Format(v, decimals)
+	intPart, frac, _ := strings.Cut(s, ".")
+	if places <= 0 {
+		return intPart
+	}
+	if len(frac) > places {
+		frac = frac[:places]
+	}
+	return intPart + "." + frac + strings.Repeat("0", places-len(frac))
+}
+
+// FormatSigned 与 Format 相同，正数带 "+" 前缀，用于显示余额变化
+func FormatSigned(v *big.Int, decimals int) string {
+	s := Format(v, decimals)
+	if v != nil && v.Sign() > 0 {
+		return "+" + s
+	}
+	return s
+}
+
+// FormatEther 将 wei 格式化为 ETH
+func FormatEther(wei *big.Int) string {
+	return Format(wei, EtherDecimals)
+}
+
+// FormatGwei 将 wei 格式化为 gwei
+func FormatGwei(wei *big.Int) string {
+	return Format(wei, GweiDecimals)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package units
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// maxUint256 2^256 - 1
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+func mustInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid integer " + s)
+	}
+	return v
+}
+
+func TestParse(t *testing.T) {
+	max := maxUint256.String()
+	tests := []struct {
+		s        string
+		decimals int
+		want     string // 为空时期望返回错误
+		tooMany  bool   // 期望错误为 ErrTooManyDecimals
+	}{
+		{"1.5", 6, "1500000", false},
+		{".5", 18, "500000000000000000", false},
+		{"1.", 0, "1", false},
+		{"1.500000", 6, "1500000", false},
+		{" 42 ", 0, "42", false},
+		{"0", 18, "0", false},
+		{"0.000000000000000001", 18, "1", false},
+		{"007", 2, "700", false},
+		{max, 0, max, false},
+		{max[:len(max)-18] + "." + max[len(max)-18:], 18, max, false},
+
+		// 小数位数超过精度
+		{"0.0000001", 6, "", true},
+		{"1.5", 0, "", true},
+		{"0.0000000000000000001", 18, "", true},
+
+		// 负数和其他写法
+		{"-1", 18, "", false},
+		{"-0.5", 18, "", false},
+		{"+1", 18, "", false},
+		{"1e18", 0, "", false},
+		{"1,000", 0, "", false},
+		{"1.2.3", 6, "", false},
+		{"", 18, "", false},
+		{".", 18, "", false},
+		{"abc", 18, "", false},
+		{"1", -1, "", false},
+
+		// 超过 uint256
+		{new(big.Int).Add(maxUint256, big.NewInt(1)).String(), 0, "", false},
+		{max, 1, "", false},
+		{"1" + strings.Repeat("0", 80), 0, "", false},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.s, tt.decimals)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Parse(%q, %d) = %s, want error", tt.s, tt.decimals, got)
+			} else if errors.Is(err, ErrTooManyDecimals) != tt.tooMany {
+				t.Errorf("Parse(%q, %d) error = %v, ErrTooManyDecimals %v", tt.s, tt.decimals, err, tt.tooMany)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q, %d): %v", tt.s, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q, %d) = %s, want %s", tt.s, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestParseWithUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(string) (*big.Int, error)
+		s, want string // want 为空时期望返回错误
+	}{
+		{"ether", ParseEther, "1.5", "1500000000000000000"},
+		{"ether", ParseEther, "1.5ether", "1500000000000000000"},
+		{"ether", ParseEther, "1.5 ETH", "1500000000000000000"},
+		{"ether", ParseEther, "30gwei", "30000000000"},
+		{"ether", ParseEther, "30 GWei", "30000000000"},
+		{"ether", ParseEther, "100wei", "100"},
+		{"ether", ParseEther, "100 wei", "100"},
+		{"ether", ParseEther, "0.5wei", ""},
+		{"ether", ParseEther, "1.5 gwei", "1500000000"},
+		{"ether", ParseEther, "gwei", ""},
+		{"ether", ParseEther, "-1ether", ""},
+		{"ether", ParseEther, "1 btc", ""},
+		{"gwei", ParseGwei, "1.5", "1500000000"},
+		{"gwei", ParseGwei, "2 ether", "2000000000000000000"},
+		{"gwei", ParseGwei, "0.0000000001", ""},
+	}
+	for _, tt := range tests {
+		got, err := tt.parse(tt.s)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Parse%s(%q) = %s, want error", tt.name, tt.s, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse%s(%q): %v", tt.name, tt.s, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse%s(%q) = %s, want %s", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		v        *big.Int
+		decimals int
+		want     string
+	}{
+		{big.NewInt(1500000), 6, "1.5"},
+		{big.NewInt(0), 18, "0"},
+		{nil, 18, "0"},
+		{big.NewInt(1), 18, "0.000000000000000001"},
+		{mustInt("1000000000000000000"), 18, "1"},
+		{big.NewInt(100), 0, "100"},
+		{big.NewInt(-1500000), 6, "-1.5"},
+		{big.NewInt(-1), 2, "-0.01"},
+		{maxUint256, 18, "115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.v, tt.decimals); got != tt.want {
+			t.Errorf("Format(%v, %d) = %q, want %q", tt.v, tt.decimals, got, tt.want)
+		}
+	}
+
+	// Format 的结果可以被 Parse 还原
+	for _, v := range []*big.Int{big.NewInt(1), big.NewInt(1500000), mustInt("123456789012345678901234567890"), maxUint256} {
+		for _, decimals := range []int{0, 6, 18} {
+			got, err := Parse(Format(v, decimals), decimals)
+			if err != nil || got.Cmp(v) != 0 {
+				t.Errorf("Parse(Format(%s, %d)) = %v, %v", v, decimals, got, err)
+			}
+		}
+	}
+}
+
+func TestFormatFixed(t *testing.T) {
+	tests := []struct {
+		v                *big.Int
+		decimals, places int
+		want             string
+	}{
+		{big.NewInt(1234567), 6, 2, "1.23"},
+		{big.NewInt(1999999), 6, 2, "1.99"},
+		{big.NewInt(1000000), 6, 2, "1.00"},
+		{big.NewInt(-1500000), 6, 0, "-1"},
+		{big.NewInt(5), 0, 3, "5.000"},
+		{nil, 18, 4, "0.0000"},
+	}
+	for _, tt := range tests {
+		if got := FormatFixed(tt.v, tt.decimals, tt.places); got != tt.want {
+			t.Errorf("FormatFixed(%v, %d, %d) = %q, want %q", tt.v, tt.decimals, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSigned(t *testing.T) {
+	tests := []struct {
+		v    *big.Int
+		want string
+	}{
+		{big.NewInt(1500000), "+1.5"},
+		{big.NewInt(-1500000), "-1.5"},
+		{big.NewInt(0), "0"},
+		{nil, "0"},
+	}
+	for _, tt := range tests {
+		if got := FormatSigned(tt.v, 6); got != tt.want {
+			t.Errorf("FormatSigned(%v, 6) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Package watchcfg 加载日志监听配置（YAML 或 JSON）：一次订阅同时监听多个合约，每个合约有自己的 ABI、事件子集和标签。
+//
+//	contracts:
+//	  - label: USDT
+//	    address: "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+//	    events: [Transfer]          # 只输出这些事件（名称或签名），省略表示全部
+//	  - label: my-vault
+//	    address: "0x..."
+//	    abi: abis/vault.json        # 相对路径相对于配置文件所在目录
+//
+// 每个合约的事件注册表 = 内置常用事件 + 该合约的 ABI；所有合约合并为一个 FilterQuery，
+// 每个合约都指定了事件子集时，Topics[0] 也会限制为这些事件的签名哈希。
+package watchcfg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gopkg.in/yaml.v3"
+
+	"ethutil/abifile"
+	"ethutil/events"
+)
+
+// Config 配置文件内容
+type Config struct {
+	Contracts []ContractConfig `yaml:"contracts" json:"contracts"`
+}
+
+// ContractConfig 一个被监听的合约
+type ContractConfig struct {
+	Label   string   `yaml:"label" json:"label"`
+	Address string   `yaml:"address" json:"address"`
+	ABI     string   `yaml:"abi,omitempty" json:"abi,omitempty"`
+	Events  []string `yaml:"events,omitempty" json:"events,omitempty"`
+}
+
+// Contract 加载后的合约
+type Contract struct {
+	Label    string
+	Address  common.Address
+	Registry *events.Registry
+	Events   []string             // 配置的事件子集，为空表示全部
+	events   map[common.Hash]bool // 事件子集的 Topics[0]，nil 表示全部
+}
+
+// Wants 日志是否属于该合约要输出的事件
+func (c *Contract) Wants(log *types.Log) bool {
+	if c.events == nil {
+		return true
+	}
+	return len(log.Topics) > 0 && c.events[log.Topics[0]]
+}
+
+// Watch 加载后的监听配置
+type Watch struct {
+	Path      string
+	Contracts []*Contract
+	byAddress map[common.Address]*Contract
+}
+
+// Load 读取配置文件：.json 按 JSON 解析，其余按 YAML 解析；未知字段直接报错，避免拼错的配置静默失效
+func Load(path string) (*Watch, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch config: %w", err)
+	}
+	// JSON 是 YAML 的子集，两种格式都用 YAML 解码器解析
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse watch config %s: %w", path, err)
+	}
+	w, err := New(cfg, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid watch config %s: %w", path, err)
+	}
+	w.Path = path
+	return w, nil
+}
+
+// New 根据配置创建 Watch，baseDir 用于解析 ABI 文件的相对路径
+func New(cfg Config, baseDir string) (*Watch, error) {
+	if len(cfg.Contracts) == 0 {
+		return nil, fmt.Errorf("no contracts configured")
+	}
+	w := &Watch{byAddress: make(map[common.Address]*Contract)}
+	for i, cc := range cfg.Contracts {
+		c, err := newContract(cc, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("contract #%d: %w", i+1, err)
+		}
+		if prev, ok := w.byAddress[c.Address]; ok {
+			return nil, fmt.Errorf("contract #%d: address %s is already configured as %q", i+1, c.Address.Hex(), prev.Label)
+		}
+		w.byAddress[c.Address] = c
+		w.Contracts = append(w.Contracts, c)
+	}
+	return w, nil
+}
+
+func newContract(cc ContractConfig, baseDir string) (*Contract, error) {
+	if !common.IsHexAddress(cc.Address) {
+		return nil, fmt.Errorf("invalid address %q", cc.Address)
+	}
+	c := &Contract{
+		Label:    strings.TrimSpace(cc.Label),
+		Address:  common.HexToAddress(cc.Address),
+		Registry: events.Bundled(),
+		Events:   cc.Events,
+	}
+	if c.Label == "" {
+		c.Label = c.Address.Hex()
+	}
+	var contractABI *abi.ABI
+	if cc.ABI != "" {
+		path := cc.ABI
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		parsed, err := abifile.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		contractABI = parsed
+		c.Registry.AddABI(parsed, c.Label)
+	}
+	if len(cc.Events) > 0 {
+		c.events = make(map[common.Hash]bool)
+		for _, name := range cc.Events {
+			ids := findEvent(contractABI, c.Registry, strings.TrimSpace(name))
+			if len(ids) == 0 {
+				return nil, fmt.Errorf("%s: unknown event %q", c.Label, name)
+			}
+			for _, id := range ids {
+				c.events[id] = true
+			}
+		}
+	}
+	return c, nil
+}
+
+// findEvent 按名称或签名查找事件的 Topics[0]：有 ABI 时只在 ABI 中查找，否则在内置事件中查找（同名的全部包含）
+func findEvent(contractABI *abi.ABI, reg *events.Registry, name string) []common.Hash {
+	var ids []common.Hash
+	if contractABI != nil {
+		for _, ev := range contractABI.Events {
+			if ev.Name == name || ev.Sig == name {
+				ids = append(ids, ev.ID)
+			}
+		}
+		return ids
+	}
+	for _, e := range reg.Find(name) {
+		ids = append(ids, e.Event.ID)
+	}
+	return ids
+}
+
+// Contract 返回地址对应的合约，不在配置中时返回 nil
+func (w *Watch) Contract(addr common.Address) *Contract {
+	return w.byAddress[addr]
+}
+
+// Query 合并所有合约的过滤条件：Addresses 为全部合约地址；每个合约都指定了事件子集时，
+// Topics[0] 为这些事件签名哈希的并集（OR），否则不限制
+func (w *Watch) Query() ethereum.FilterQuery {
+	var q ethereum.FilterQuery
+	topics := make(map[common.Hash]bool)
+	all := false
+	for _, c := range w.Contracts {
+		q.Addresses = append(q.Addresses, c.Address)
+		if c.events == nil {
+			all = true
+		}
+		for id := range c.events {
+			topics[id] = true
+		}
+	}
+	sort.Slice(q.Addresses, func(i, j int) bool { return bytes.Compare(q.Addresses[i][:], q.Addresses[j][:]) < 0 })
+	if !all {
+		ids := make([]common.Hash, 0, len(topics))
+		for id := range topics {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+		q.Topics = [][]common.Hash{ids}
+	}
+	return q
+}
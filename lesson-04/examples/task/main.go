@@ -2,20 +2,22 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
-	"strconv"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
+
+	"ethutil/policy"
+	"ethutil/prompt"
+	"ethutil/signer"
+	"ethutil/units"
 )
 
 func main() {
@@ -111,11 +113,11 @@ func sendTransaction() {
 	}
 	fmt.Printf("infuraURL: %s\n", infuraURL)
 
-	//获取私钥
-	privKeyHex := os.Getenv("SENDER_PRIVATE_KEY")
-	//	fmt.Printf("私钥原始值: %s\n", privKeyHex)
-	if privKeyHex == "" {
-		log.Fatal("SENDER_PRIVATE_KEY is not set (required for send mode)")
+	//打开签名器：SIGNER_KEYSTORE / SIGNER_MNEMONIC_FILE / SIGNER_CLEF_URL，都未配置时兼容 SENDER_PRIVATE_KEY
+	//私钥材料只保存在签名器内部，不会被打印
+	txSigner, err := signer.ConfigFromEnv("SENDER_PRIVATE_KEY").Open()
+	if err != nil {
+		log.Fatalf("failed to open signer: %v", err)
 	}
 	//context.WithTimeout 创建一个新的上下文，会在指定时间后自动取消,30 秒超时：防止网络连接问题导致程序无限等待
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -127,21 +129,9 @@ func sendTransaction() {
 	}
 	defer client.Close() // defer 确保函数退出时关闭连接
 
-	// 解析私钥， trim0x 移除十六进制字符串前缀 "0x"
-	privKey, err := crypto.HexToECDSA(trim0x(privKeyHex))
-	if err != nil {
-		log.Fatalf("invalid private key: %v", err)
-	}
-
-	// 获取发送方地址
-	publicKey := privKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("error casting public key to ECDSA")
-	}
 	//获取转账账户
-	fromAddr := crypto.PubkeyToAddress(*publicKeyECDSA)
-	fmt.Printf("获取转账账户fromAddr: %s\n", fromAddr)
+	fromAddr := txSigner.Address()
+	fmt.Printf("获取转账账户fromAddr: %s (signer: %s)\n", fromAddr, txSigner.Kind())
 
 	//获取转账接收账户
 	toAddrHex := os.Getenv("TO_ADDRESS")
@@ -192,22 +182,15 @@ func sendTransaction() {
 	// 估算 Gas Limit（普通转账固定为 21000）
 	gasLimit := uint64(21000)
 
-	// 转换 ETH 金额为 Wei
-	// amountEth * 1e18
+	// 转换 ETH 金额为 Wei：按十进制字符串精确换算（不经过 float64），支持 1.5ether、30gwei、100wei 等后缀
 	amountStr := os.Getenv("AMOUNT")
 	if amountStr == "" {
 		log.Fatal("AMOUNT environment variable is not set")
 	}
-	// 2. 转换为 float64
-	amountEth, err := strconv.ParseFloat(amountStr, 64)
+	valueWei, err := units.ParseEther(amountStr)
 	if err != nil {
 		log.Fatalf("金额格式错误 '%s': %v", amountStr, err)
 	}
-	amountWei := new(big.Float).Mul(
-		big.NewFloat(amountEth),
-		big.NewFloat(1e18),
-	)
-	valueWei, _ := amountWei.Int(nil)
 
 	// 检查余额是否足够
 	balance, err := client.BalanceAt(ctx, fromAddr, nil)
@@ -226,6 +209,35 @@ func sendTransaction() {
 		log.Fatalf("insufficient balance: have %s wei, need %s wei", balance.String(), totalCost.String())
 	}
 
+	// 交易策略检查（TX_POLICY_FILE 未配置时不启用）
+	pol, err := policy.Load("")
+	if err != nil {
+		log.Fatalf("failed to load policy: %v", err)
+	}
+	policyTx := &policy.Tx{
+		Kind:      policy.KindETHTransfer,
+		ChainID:   chainID,
+		From:      fromAddr,
+		To:        &toAddr,
+		Value:     valueWei,
+		GasLimit:  gasLimit,
+		GasFeeCap: gasFeeCap,
+		GasTipCap: gasTipCap,
+	}
+	decision, err := pol.Check(policyTx)
+	if err != nil {
+		policy.PrintRejection(os.Stdout, err)
+		log.Fatalf("transaction rejected by policy: %v", err)
+	}
+	// 本程序默认不询问确认，只有策略要求时才交互确认
+	ok, err := decision.Confirm(true, "Sign and broadcast this transaction?", prompt.Confirm)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !ok {
+		log.Fatal("aborted, transaction not sent")
+	}
+
 	// 构造交易（EIP-1559 动态费用交易）
 	txData := &types.DynamicFeeTx{
 		ChainID:   chainID,   //链 ID
@@ -240,8 +252,7 @@ func sendTransaction() {
 	tx := types.NewTx(txData)
 
 	// 签名交易
-	signer := types.NewLondonSigner(chainID)
-	signedTx, err := types.SignTx(tx, signer, privKey)
+	signedTx, err := txSigner.SignTx(tx, chainID)
 	if err != nil {
 		log.Fatalf("failed to sign transaction: %v", err)
 	}
@@ -250,12 +261,16 @@ func sendTransaction() {
 	if err := client.SendTransaction(ctx, signedTx); err != nil {
 		log.Fatalf("failed to send transaction: %v", err)
 	}
+	// 记入每日额度账本（交易已广播，记账失败只提示不退出）
+	if err := pol.Record(policyTx, signedTx.Hash()); err != nil {
+		fmt.Printf("Warning: failed to record spend in policy ledger: %v\n", err)
+	}
 
 	// 输出交易信息
 	fmt.Println("=== Transaction Sent ===")
 	fmt.Printf("From       : %s\n", fromAddr.Hex())
 	fmt.Printf("To         : %s\n", toAddr.Hex())
-	fmt.Printf("Value      : %s ETH (%s Wei)\n", fmt.Sprintf("%.6f", amountEth), valueWei.String())
+	fmt.Printf("Value      : %s ETH (%s Wei)\n", units.FormatEther(valueWei), valueWei.String())
 	fmt.Printf("Gas Limit  : %d\n", gasLimit)
 	fmt.Printf("Gas Tip Cap: %s Wei\n", gasTipCap.String())
 	fmt.Printf("Gas Fee Cap: %s Wei\n", gasFeeCap.String())
@@ -265,11 +280,3 @@ func sendTransaction() {
 	fmt.Printf("  go run main.go --tx %s\n", signedTx.Hash().Hex())
 	//Tx Hash    : 0x2fc001fd55bca7af3de17c642a2648d67df3e8e2456ea8e5d2f8f9c2a3daf096
 }
-
-// trim0x 移除十六进制字符串前缀 "0x"
-func trim0x(s string) string {
-	if len(s) >= 2 && s[:2] == "0x" {
-		return s[2:]
-	}
-	return s
-}
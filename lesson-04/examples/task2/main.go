@@ -2,22 +2,62 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
+	"strings"
+	"time"
 
 	"task2/contract" // 导入生成的绑定包
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv" // 用于加载 .env 文件
+
+	"ethutil/abiargs"
+	"ethutil/abifile"
+	"ethutil/policy"
+	"ethutil/prompt"
+	"ethutil/signer"
+	"ethutil/txwait"
+	"ethutil/units"
 )
 
+// 用法：
+//
+//	# 部署 Counter（或加载 CONTRACT_ADDRESS 指定的已有合约），读取计数并调用 increment
+//	go run main.go
+//
+//	# 从 solc 构建产物部署任意合约，按 ABI 中的构造函数参数类型解析 --args（多个参数用逗号分隔）
+//	go run main.go deploy --abi build/Counter_sol_Counter.abi --bin build/Counter_sol_Counter.bin --args 100
+//
+//	# CREATE2 确定性部署：同一工厂、salt 和 init code 在每条链上得到相同的合约地址
+//	go run main.go create2 --abi build/Counter_sol_Counter.abi --bin build/Counter_sol_Counter.bin --args 100 --salt counter-v1
+//
+//	# 只预测地址，不部署
+//	go run main.go create2 --abi build/Counter_sol_Counter.abi --bin build/Counter_sol_Counter.bin --args 100 --salt counter-v1 --predict-only
+//
+// 连接和签名器配置都来自 .env，见下方 main 中的说明
 func main() {
+	// create2 先解析参数并输出预测地址：--predict-only 只是本地计算，不需要 .env、节点连接和签名器
+	var create2 *create2Plan
+	if len(os.Args) > 1 && os.Args[1] == "create2" {
+		var predictOnly bool
+		create2, predictOnly = parseCreate2(os.Args[2:])
+		create2.print()
+		if predictOnly {
+			return
+		}
+	}
+
 	// 1. 加载 .env 文件
 	err := godotenv.Load()
 	if err != nil {
@@ -25,11 +65,9 @@ func main() {
 	}
 
 	rpcURL := os.Getenv("SEPOLIA_RPC_URL")
-	privateKeyHex := os.Getenv("PRIVATE_KEY")
 	contractAddressHex := os.Getenv("CONTRACT_ADDRESS")
 
 	fmt.Printf("rpcURL: %s\n", rpcURL)
-	fmt.Printf("privateKeyHex: %s\n", privateKeyHex)
 	fmt.Printf("contractAddressHex: %s\n", contractAddressHex)
 
 	// 2. 连接 Sepolia 网络
@@ -39,19 +77,14 @@ func main() {
 	}
 	fmt.Println("Connected to Sepolia network")
 
-	// 3. 从私钥加载账户
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	// 3. 打开签名器：SIGNER_KEYSTORE / SIGNER_MNEMONIC_FILE / SIGNER_CLEF_URL，都未配置时兼容 PRIVATE_KEY
+	//    私钥材料只保存在签名器内部，不会被打印
+	txSigner, err := signer.ConfigFromEnv("PRIVATE_KEY").Open()
 	if err != nil {
-		log.Fatalf("Failed to load private key: %v", err)
+		log.Fatalf("Failed to open signer: %v", err)
 	}
-
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("Failed to cast public key to ECDSA")
-	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-	fmt.Printf("Using account: %s\n", fromAddress.Hex())
+	fromAddress := txSigner.Address()
+	fmt.Printf("Using account: %s (signer: %s)\n", fromAddress.Hex(), txSigner.Kind())
 
 	// 4. 获取链 ID（Sepolia 链 ID 是 11155111）
 	chainID, err := client.NetworkID(context.Background())
@@ -61,16 +94,34 @@ func main() {
 	fmt.Printf("Chain ID: %s\n", chainID.String())
 
 	// 5. 创建交易授权对象（Auth）
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
-	if err != nil {
-		log.Fatalf("Failed to create authorized transactor: %v", err)
-	}
+	auth := signer.TransactOpts(txSigner, chainID)
 	// 设置 Gas 价格（可选，建议动态获取）
 	gasPrice, err := client.SuggestGasPrice(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
 	auth.GasPrice = gasPrice
+	// 只签名不广播：先按交易策略（TX_POLICY_FILE）检查已签名的交易，通过后再由 checkAndSend 广播
+	auth.NoSend = true
+	pol, err := policy.Load("")
+	if err != nil {
+		log.Fatalf("Failed to load policy: %v", err)
+	}
+	// 确认目标：WAIT_FOR=3 表示 3 个确认，也可以是 safe / finalized，默认 1 个确认
+	target, err := txwait.ParseTarget(os.Getenv("WAIT_FOR"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// deploy / create2 子命令：从构建产物部署任意合约
+	if len(os.Args) > 1 && os.Args[1] == "deploy" {
+		deployArtifact(client, auth, pol, chainID, fromAddress, target, os.Args[2:])
+		return
+	}
+	if create2 != nil {
+		deployCreate2(client, auth, pol, chainID, fromAddress, target, create2)
+		return
+	}
 
 	var counterInstance *contract.Contract
 
@@ -90,14 +141,15 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to deploy contract: %v", err)
 		}
+		checkAndSend(client, pol, policy.KindDeploy, chainID, fromAddress, tx)
 
 		fmt.Printf("Contract deployed! Address: %s\n", address.Hex())
 		fmt.Printf("Transaction hash: %s\n", tx.Hash().Hex())
 
 		counterInstance = instance
 
-		// 等待交易被确认（可选）
-		// bind.WaitMined(context.Background(), client, tx)
+		// 等待部署交易被确认，之后才能读取合约状态
+		waitMined(client, tx, target)
 	} else {
 		// 6b. 加载已有合约
 		contractAddress := common.HexToAddress(contractAddressHex)
@@ -122,23 +174,299 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to increment: %v", err)
 	}
+	checkAndSend(client, pol, policy.KindContractCall, chainID, fromAddress, tx)
 	fmt.Printf("Increment transaction sent! Hash: %s\n", tx.Hash().Hex())
 
-	// 9. 等待交易确认并读取新值（可选）
-	// 生产环境建议等待确认
-	// receipt, err := bind.WaitMined(context.Background(), client, tx)
-	// if err != nil {
-	//     log.Fatalf("Failed to wait for mining: %v", err)
-	// }
-	// fmt.Printf("Transaction mined! Block: %d\n", receipt.BlockNumber)
-
-	// 10. 再次读取计数值（注意：因为状态未更新，需要等待区块确认）
-	// 为了演示，这里添加短暂延迟并重新读取
+	// 9. 等待交易确认（期间检测链重组）
 	fmt.Println("Waiting for transaction to be mined...")
-	// 在实际代码中应该使用 WaitMined，这里简化处理
+	waitMined(client, tx, target)
+
+	// 10. 交易确认后再次读取计数值
 	newCount, err := counterInstance.GetCount(&bind.CallOpts{})
 	if err != nil {
 		log.Fatalf("Failed to get new count: %v", err)
 	}
 	fmt.Printf("New count after increment: %s\n", newCount.String())
 }
+
+// checkAndSend 按交易策略检查已签名（未广播）的交易，通过后广播并记入每日额度账本
+func checkAndSend(client *ethclient.Client, pol *policy.Policy, kind string, chainID *big.Int, from common.Address, tx *types.Transaction) {
+	policyTx := &policy.Tx{
+		Kind:      kind,
+		ChainID:   chainID,
+		From:      from,
+		To:        tx.To(),
+		Value:     tx.Value(),
+		GasLimit:  tx.Gas(),
+		GasFeeCap: tx.GasFeeCap(),
+		GasTipCap: tx.GasTipCap(),
+	}
+	decision, err := pol.Check(policyTx)
+	if err != nil {
+		policy.PrintRejection(os.Stdout, err)
+		log.Fatalf("Transaction rejected by policy: %v", err)
+	}
+	// 本程序默认不询问确认，只有策略要求时才交互确认
+	ok, err := decision.Confirm(true, "Broadcast this transaction?", prompt.Confirm)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !ok {
+		log.Fatal("Aborted, transaction not sent")
+	}
+
+	if err := client.SendTransaction(context.Background(), tx); err != nil {
+		log.Fatalf("Failed to send transaction: %v", err)
+	}
+	if err := pol.Record(policyTx, tx.Hash()); err != nil {
+		fmt.Printf("Warning: failed to record spend in policy ledger: %v\n", err)
+	}
+}
+
+// deployArtifact 从 ABI 和字节码文件部署合约：按构造函数的参数类型解析 --args，估算 Gas 并签名，
+// 经交易策略检查后广播，等待回执并确认合约地址上已有运行时代码
+func deployArtifact(client *ethclient.Client, auth *bind.TransactOpts, pol *policy.Policy, chainID *big.Int, from common.Address, target txwait.Target, args []string) {
+	fs := flag.NewFlagSet("deploy", flag.ExitOnError)
+	artifact := registerArtifactFlags(fs)
+	fs.Parse(args)
+	parsedABI, bytecode, params, value := artifact.load()
+	auth.Value = value
+
+	// bind.DeployContract 打包构造函数参数并通过 eth_estimateGas 估算 Gas Limit；auth.NoSend 为 true，这里只签名
+	fmt.Printf("Deploying %s with constructor args %v...\n", *artifact.binPath, params)
+	address, tx, _, err := bind.DeployContract(auth, *parsedABI, bytecode, client, params...)
+	if err != nil {
+		log.Fatalf("Failed to deploy contract: %v", err)
+	}
+	fmt.Printf("Contract address: %s\n", address.Hex())
+	fmt.Printf("Gas limit (estimated): %d\n", tx.Gas())
+	fmt.Printf("Max cost: %s ETH\n", units.FormatEther(tx.Cost()))
+	checkAndSend(client, pol, policy.KindDeploy, chainID, from, tx)
+	fmt.Printf("Transaction hash: %s\n", tx.Hash().Hex())
+
+	receipt := waitMined(client, tx, target)
+	verifyDeployedCode(client, address, receipt.BlockNumber)
+}
+
+// defaultCreate2Factory 确定性部署代理（Arachnid deterministic-deployment-proxy），已部署在绝大多数 EVM 链的同一地址上。
+// 调用数据为 salt(32 字节) ‖ init code，代理以 CREATE2 部署并返回新合约地址（20 字节）
+const defaultCreate2Factory = "0x4e59b44847b379578588920cA78FbF26c0B4956C"
+
+// create2Plan 一次 CREATE2 部署：地址 = keccak256(0xff ‖ factory ‖ salt ‖ keccak256(initCode))[12:]，与部署账户和 nonce 无关
+type create2Plan struct {
+	factory   common.Address
+	salt      [32]byte
+	initCode  []byte   // 创建字节码 ‖ ABI 编码的构造函数参数
+	value     *big.Int // 随部署发送的 ETH
+	predicted common.Address
+}
+
+// parseCreate2 解析 create2 子命令的参数并计算预测地址，出错直接退出
+func parseCreate2(args []string) (*create2Plan, bool) {
+	fs := flag.NewFlagSet("create2", flag.ExitOnError)
+	artifact := registerArtifactFlags(fs)
+	saltStr := fs.String("salt", "", "32-byte hex salt (0x..., left padded), or any text that is hashed with keccak256")
+	factoryHex := fs.String("factory", defaultCreate2Factory, "CREATE2 factory taking salt ++ init code as calldata")
+	predictOnly := fs.Bool("predict-only", false, "only print the predicted address")
+	fs.Parse(args)
+
+	if !common.IsHexAddress(*factoryHex) {
+		log.Fatalf("Invalid --factory address: %s", *factoryHex)
+	}
+	salt, err := parseSalt(*saltStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parsedABI, bytecode, params, value := artifact.load()
+	packedArgs, err := parsedABI.Pack("", params...)
+	if err != nil {
+		log.Fatalf("Failed to pack constructor arguments: %v", err)
+	}
+	// init code = 创建字节码 ‖ ABI 编码的构造函数参数，参数不同得到的地址也不同
+	initCode := append(append([]byte{}, bytecode...), packedArgs...)
+	return newCreate2Plan(common.HexToAddress(*factoryHex), salt, initCode, value), *predictOnly
+}
+
+// newCreate2Plan 计算预测地址
+func newCreate2Plan(factory common.Address, salt [32]byte, initCode []byte, value *big.Int) *create2Plan {
+	return &create2Plan{
+		factory:   factory,
+		salt:      salt,
+		initCode:  initCode,
+		value:     value,
+		predicted: crypto.CreateAddress2(factory, salt, crypto.Keccak256(initCode)),
+	}
+}
+
+func (p *create2Plan) print() {
+	fmt.Printf("Factory       : %s\n", p.factory.Hex())
+	fmt.Printf("Salt          : %s\n", common.Hash(p.salt).Hex())
+	fmt.Printf("Init Code Hash: %s\n", crypto.Keccak256Hash(p.initCode).Hex())
+	fmt.Printf("Predicted     : %s\n", p.predicted.Hex())
+}
+
+// prepare 检查预测地址和工厂合约，模拟调用工厂确认返回地址与预测一致，再构造部署交易（auth.NoSend 为 true 时只签名）。
+// 预测地址上已有代码时返回 nil 交易，表示跳过部署
+func (p *create2Plan) prepare(ctx context.Context, backend bind.ContractBackend, auth *bind.TransactOpts) (*types.Transaction, error) {
+	existing, err := backend.CodeAt(ctx, p.predicted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code at predicted address: %w", err)
+	}
+	if len(existing) > 0 {
+		fmt.Printf("Contract already deployed at %s (%d bytes), skipping\n", p.predicted.Hex(), len(existing))
+		return nil, nil
+	}
+	factoryCode, err := backend.CodeAt(ctx, p.factory, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get factory code: %w", err)
+	}
+	if len(factoryCode) == 0 {
+		return nil, fmt.Errorf("no CREATE2 factory deployed at %s, use --factory", p.factory.Hex())
+	}
+
+	// 先模拟调用工厂，确认返回的地址与预测一致（不一致说明工厂的调用约定不同）
+	calldata := append(p.salt[:], p.initCode...)
+	out, err := backend.CallContract(ctx, ethereum.CallMsg{From: auth.From, To: &p.factory, Value: p.value, Data: calldata}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("factory call reverted: %w", err)
+	}
+	if len(out) < common.AddressLength || common.BytesToAddress(out[len(out)-common.AddressLength:]) != p.predicted {
+		return nil, fmt.Errorf("factory returned 0x%x, expected %s", out, p.predicted.Hex())
+	}
+
+	auth.Value = p.value
+	tx, err := bind.NewBoundContract(p.factory, abi.ABI{}, backend, backend, backend).RawTransact(auth, calldata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deployment transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// deployCreate2 通过 CREATE2 工厂确定性部署合约，预测地址上已有代码时跳过部署
+func deployCreate2(client *ethclient.Client, auth *bind.TransactOpts, pol *policy.Policy, chainID *big.Int, from common.Address, target txwait.Target, plan *create2Plan) {
+	tx, err := plan.prepare(context.Background(), client, auth)
+	if err != nil {
+		log.Fatalf("CREATE2 deployment on chain %s failed: %v", chainID.String(), err)
+	}
+	if tx == nil {
+		return
+	}
+	fmt.Printf("Gas limit (estimated): %d\n", tx.Gas())
+	fmt.Printf("Max cost: %s ETH\n", units.FormatEther(tx.Cost()))
+	checkAndSend(client, pol, policy.KindDeploy, chainID, from, tx)
+	fmt.Printf("Transaction hash: %s\n", tx.Hash().Hex())
+
+	receipt := waitMined(client, tx, target)
+	verifyDeployedCode(client, plan.predicted, receipt.BlockNumber)
+}
+
+// parseSalt 解析 salt：0x 开头按十六进制解析并左侧补零到 32 字节，其它文本取 keccak256
+func parseSalt(s string) ([32]byte, error) {
+	var salt [32]byte
+	if s == "" {
+		return salt, fmt.Errorf("create2 requires --salt")
+	}
+	if strings.HasPrefix(s, "0x") {
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) > 32 {
+			return salt, fmt.Errorf("invalid --salt %q: expected at most 32 bytes hex", s)
+		}
+		copy(salt[32-len(b):], b)
+		return salt, nil
+	}
+	return crypto.Keccak256Hash([]byte(s)), nil
+}
+
+// verifyDeployedCode 确认合约地址上已有运行时代码。构造函数执行成功但返回空代码时
+// （例如字节码只是运行时代码、或构造函数里 selfdestruct），合约地址上没有代码
+func verifyDeployedCode(client *ethclient.Client, address common.Address, blockNumber *big.Int) {
+	code, err := client.CodeAt(context.Background(), address, blockNumber)
+	if err != nil {
+		log.Fatalf("Failed to get deployed code: %v", err)
+	}
+	if len(code) == 0 {
+		log.Fatalf("Deployment of %s produced no runtime code", address.Hex())
+	}
+	fmt.Printf("Contract deployed! Address: %s, runtime code: %d bytes\n", address.Hex(), len(code))
+}
+
+// artifactFlags deploy 和 create2 共用的构建产物参数
+type artifactFlags struct {
+	abiPath  *string
+	binPath  *string
+	ctorArgs *string
+	value    *string
+}
+
+func registerArtifactFlags(fs *flag.FlagSet) *artifactFlags {
+	return &artifactFlags{
+		abiPath:  fs.String("abi", "", "contract ABI file (solc .abi, or a Foundry / Hardhat artifact)"),
+		binPath:  fs.String("bin", "", "contract creation bytecode file (hex, with or without 0x)"),
+		ctorArgs: fs.String("args", "", "comma separated constructor arguments, e.g. 100 or 0xabc...,\"My Token\",[1,2]"),
+		value:    fs.String("value", "0", "ETH sent to a payable constructor, e.g. 0.1 or 100gwei"),
+	}
+}
+
+// load 加载 ABI 和字节码，按构造函数的参数类型解析 --args，出错直接退出
+func (a *artifactFlags) load() (*abi.ABI, []byte, []interface{}, *big.Int) {
+	if *a.abiPath == "" || *a.binPath == "" {
+		log.Fatal("--abi and --bin are required")
+	}
+	parsedABI, err := abifile.Load(*a.abiPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bytecode, err := loadBytecode(*a.binPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 构造函数参数：没有 constructor 的合约 Inputs 为空，只能不传参数
+	params, err := abiargs.ParseList(parsedABI.Constructor.Inputs, *a.ctorArgs)
+	if err != nil {
+		log.Fatalf("Invalid constructor arguments: %v", err)
+	}
+	value, err := units.ParseEther(*a.value)
+	if err != nil {
+		log.Fatalf("Invalid --value: %v", err)
+	}
+	if value.Sign() > 0 && !parsedABI.Constructor.Payable {
+		log.Fatal("Constructor is not payable, --value must be 0")
+	}
+	return parsedABI, bytecode, params, value
+}
+
+// loadBytecode 读取十六进制字节码文件（solc --bin 输出），可带 0x 前缀和换行
+func loadBytecode(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bytecode file: %w", err)
+	}
+	hexStr := strings.TrimPrefix(strings.TrimSpace(string(raw)), "0x")
+	// 未链接库的字节码中包含 __$...$__ 占位符，无法直接部署
+	if strings.Contains(hexStr, "__") {
+		return nil, fmt.Errorf("bytecode %s contains unlinked library placeholders", path)
+	}
+	bytecode := common.FromHex(hexStr)
+	if len(bytecode) == 0 {
+		return nil, fmt.Errorf("bytecode file %s is empty or not hex", path)
+	}
+	return bytecode, nil
+}
+
+// waitMined 等待交易达到确认目标并返回回执，交易执行失败或等待超时直接退出
+func waitMined(client *ethclient.Client, tx *types.Transaction, target txwait.Target) *types.Receipt {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	result, err := txwait.Wait(ctx, client, tx.Hash(), txwait.Options{Target: target, Out: os.Stdout})
+	if err != nil {
+		log.Fatalf("Failed to wait for transaction %s: %v", tx.Hash().Hex(), err)
+	}
+	receipt := result.Receipt
+	fmt.Printf("Transaction mined! Block: %d, Gas used: %d / %d, Confirmations: %d\n",
+		receipt.BlockNumber.Uint64(), receipt.GasUsed, tx.Gas(), result.Confirmations)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Fatalf("Transaction %s failed", tx.Hash().Hex())
+	}
+	return receipt
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"task2/contract"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// arachnidFactoryInitCode 确定性部署代理的创建字节码：构造函数返回其后 0x45 字节的运行时代码
+const arachnidFactoryInitCode = "0x604580600e600039806000f350fe7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf3"
+
+// TestCreate2 在模拟链上部署 CREATE2 工厂，确认 Counter 部署到预测地址，重复部署时跳过
+func TestCreate2(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		from: {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))},
+	})
+	defer backend.Close()
+	client := backend.Client()
+	ctx := context.Background()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 部署工厂
+	factory, tx, _, err := bind.DeployContract(auth, abi.ABI{}, common.FromHex(arachnidFactoryInitCode), client)
+	if err != nil {
+		t.Fatalf("failed to deploy factory: %v", err)
+	}
+	backend.Commit()
+	mustSucceed(t, client, tx)
+
+	counterABI, err := contract.ContractMetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := counterABI.Pack("", big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initCode := append(common.FromHex(contract.ContractMetaData.Bin), args...)
+	plan := newCreate2Plan(factory, crypto.Keccak256Hash([]byte("counter-v1")), initCode, big.NewInt(0))
+
+	// 与 CREATE2 公式一致，与部署账户无关
+	want := common.BytesToAddress(crypto.Keccak256(
+		[]byte{0xff}, factory.Bytes(), plan.salt[:], crypto.Keccak256(initCode))[12:])
+	if plan.predicted != want {
+		t.Fatalf("predicted %s, want %s", plan.predicted.Hex(), want.Hex())
+	}
+
+	tx, err = plan.prepare(ctx, client, auth)
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if tx == nil {
+		t.Fatal("prepare skipped deployment on an empty address")
+	}
+	backend.Commit()
+	mustSucceed(t, client, tx)
+
+	counter, err := contract.NewContract(plan.predicted, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := counter.GetCount(&bind.CallOpts{})
+	if err != nil {
+		t.Fatalf("no Counter at predicted address %s: %v", plan.predicted.Hex(), err)
+	}
+	if count.Int64() != 100 {
+		t.Fatalf("count = %s, want 100", count)
+	}
+
+	// 预测地址上已有代码：跳过部署
+	tx, err = plan.prepare(ctx, client, auth)
+	if err != nil {
+		t.Fatalf("prepare on deployed address: %v", err)
+	}
+	if tx != nil {
+		t.Fatalf("prepare returned transaction %s for an already deployed contract", tx.Hash().Hex())
+	}
+}
+
+// TestCreate2NoFactory 工厂地址上没有代码时返回错误
+func TestCreate2NoFactory(t *testing.T) {
+	backend := simulated.NewBackend(types.GenesisAlloc{})
+	defer backend.Close()
+
+	key, _ := crypto.GenerateKey()
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := newCreate2Plan(common.HexToAddress(defaultCreate2Factory), [32]byte{}, []byte{0x00}, big.NewInt(0))
+	_, err = plan.prepare(context.Background(), backend.Client(), auth)
+	if err == nil || !strings.Contains(err.Error(), "no CREATE2 factory") {
+		t.Fatalf("got %v, want missing factory error", err)
+	}
+}
+
+// TestParseSalt 十六进制 salt 左侧补零，其它文本取 keccak256
+func TestParseSalt(t *testing.T) {
+	salt, err := parseSalt("0x01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if salt != common.BigToHash(big.NewInt(1)) {
+		t.Fatalf("got %x", salt)
+	}
+	salt, err = parseSalt("counter-v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if salt != crypto.Keccak256Hash([]byte("counter-v1")) {
+		t.Fatalf("got %x", salt)
+	}
+	for _, bad := range []string{"", "0xzz", "0x" + strings.Repeat("00", 33)} {
+		if _, err := parseSalt(bad); err == nil {
+			t.Errorf("parseSalt(%q) succeeded", bad)
+		}
+	}
+}
+
+func mustSucceed(t *testing.T, client simulated.Client, tx *types.Transaction) {
+	t.Helper()
+	receipt, err := client.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("no receipt for %s: %v", tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction %s failed", tx.Hash().Hex())
+	}
+}